@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
-	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/bootstrap"
+	"github.com/manattan/clove/internal/config"
 	"github.com/manattan/clove/internal/worktree"
 	"github.com/spf13/cobra"
 )
@@ -14,52 +16,201 @@ var addCmd = &cobra.Command{
 	Long: `現在いるリポジトリの「隣」に worktree 用ディレクトリを作成します。
 例: ~/hogehoge で実行 → ~/hogehoge-<branch> が作られる
 
+作成先は --layout / layout.path のテンプレートで変えられます。
+変数: {repo} {repoParent} {branch} {sanitized} {user} {date} {remote} {prefix} {suffix}
+例: '~/worktrees/{repo}/{branch}'、'{repoParent}/{repo}.worktrees/{branch}'、
+    '.worktrees/{branch}'（リポジトリ内。.git/info/exclude に自動で追加します）
+
 例:
   clove add feature/update
   clove add -open code feature/update
-  clove add -base origin/develop feature/update`,
+  clove add -base origin/develop feature/update
+  clove add --remote upstream feature/update
+  clove add --push-upstream feature/update
+  clove add --bootstrap node,python feature/update
+  clove add --no-bootstrap feature/update
+
+base ref の検出:
+  --base / add.base がなければ、<リモート>/HEAD、git ls-remote --symref（結果は
+  <リモート>/HEAD に保存）、init.defaultBranch、main / master / develop / trunk の
+  順に、実在する ref を探します。どれも見つからなければエラーになります。
+
+bootstrap:
+  作成後に依存ディレクトリ（node_modules, .venv, vendor/bundle, target など）を
+  元のリポジトリから用意します。戦略は設定ファイルで bootstrap.<名前>.strategy に
+  auto / copy / hardlink / symlink / reflink / install / none のいずれかを指定できます。
+  node / pnpm / yarn 以外は、戦略かコマンドを設定するか --bootstrap で指定したときだけ実行します。`,
 	Args: cobra.ExactArgs(1),
 	RunE: runAdd,
 }
 
 var (
 	addBaseRef   string
+	addRemote    string
+	addLayout    string
 	addPrefix    string
 	addSuffix    string
 	addOpenCmd   string
 	addDryRun    bool
 	addForceName string
 	addNoFetch   bool
+
+	addTrack        bool
+	addNoTrack      bool
+	addPushUpstream bool
+
+	addCopy     []string
+	addCopyMode string
+
+	addBootstrap   []string
+	addNoBootstrap bool
 )
 
 func init() {
-	addCmd.Flags().StringVar(&addBaseRef, "base", "", "起点にするref（省略時: origin/HEAD を試し、ダメなら origin/main）")
+	addCmd.Flags().StringVar(&addBaseRef, "base", "", "起点にするref（省略時: リモートの既定ブランチを検出）")
+	addCmd.Flags().StringVar(&addRemote, "remote", "", "ブランチを探し、既定の起点を決めるリモート（省略時: すべてのリモートから探す）")
+	addCmd.Flags().StringVar(&addLayout, "layout", "", "作成先のパスのテンプレート（例: '~/worktrees/{repo}/{branch}'、省略時: 設定 layout.path）")
 	addCmd.Flags().StringVar(&addPrefix, "prefix", "", "作成するディレクトリ名の接頭辞（省略時: リポジトリ名）")
 	addCmd.Flags().StringVar(&addSuffix, "suffix", "", "作成するディレクトリ名の接尾辞（任意）")
 	addCmd.Flags().StringVar(&addOpenCmd, "open", "", "作成後にディレクトリを開くコマンド（例: code / cursor / open）")
 	addCmd.Flags().BoolVar(&addDryRun, "dry-run", false, "実行せず、実行内容だけ表示します")
-	addCmd.Flags().StringVar(&addForceName, "dir", "", "ディレクトリ名を明示します（repoの親ディレクトリ配下に作る）")
+	addCmd.Flags().StringVar(&addForceName, "dir", "", "ディレクトリ名を明示します（レイアウトのルート、既定では repo の親ディレクトリ配下に作る）")
 	addCmd.Flags().BoolVar(&addNoFetch, "no-fetch", false, "git fetch origin をスキップします")
+	addCmd.Flags().BoolVar(&addTrack, "track", false, "新しいブランチの upstream をリモートの同名ブランチにします")
+	addCmd.Flags().BoolVar(&addNoTrack, "no-track", false, "新しいブランチに upstream を設定しません")
+	addCmd.Flags().BoolVar(&addPushUpstream, "push-upstream", false, "新しいブランチをすぐにリモートへ push し、upstream に設定します")
+	addCmd.MarkFlagsMutuallyExclusive("track", "no-track")
+	addCmd.Flags().StringSliceVar(&addCopy, "copy", nil, "新しい worktree にコピーする未追跡ファイルの glob パターン（例: '.env*'）")
+	addCmd.Flags().StringVar(&addCopyMode, "copy-mode", "copy", "--copy のファイルの持ち込み方（copy / symlink）")
+	addCmd.Flags().StringSliceVar(&addBootstrap, "bootstrap", nil, "実行する bootstrap をカンマ区切りで指定します（"+strings.Join(bootstrap.Names(), ", ")+"）")
+	addCmd.Flags().BoolVar(&addNoBootstrap, "no-bootstrap", false, "依存ディレクトリの bootstrap をスキップします")
 }
 
 func runAdd(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	branch := args[0]
 
-	repoRoot, err := git.GetRepoRoot()
+	repo, err := openRepository(ctx, "")
+	if err != nil {
+		return err
+	}
+	repoRoot := repo.Main
+
+	cfg, err := loadConfig(repoRoot)
+	if err != nil {
+		return err
+	}
+
+	opts, err := addOptions(cmd, cfg, repoRoot, branch)
+	if err != nil {
+		return err
+	}
+	if repo.Bare {
+		// .bare のコンテナにはチェックアウトがないので、いまいる worktree から持ち込む
+		opts.Source = repo.Current
+	}
+
+	res, err := worktree.Add(ctx, repoRoot, opts)
+	return finish("add", res, err)
+}
+
+// addOptions builds AddOptions from add.* config keys and any add flags defined on cmd
+func addOptions(cmd *cobra.Command, cfg *config.Config, repoRoot, branch string) (worktree.AddOptions, error) {
+	hooks, err := hookConfig(cfg, repoRoot)
+	if err != nil {
+		return worktree.AddOptions{}, err
+	}
+
+	copyMode, err := worktree.ParseCopyMode(stringOption(cmd, "copy-mode", cfg, "add.copy-mode"))
+	if err != nil {
+		return worktree.AddOptions{}, fmt.Errorf("clove: %w", err)
+	}
+
+	bs, err := bootstrapOptions(cmd, cfg)
+	if err != nil {
+		return worktree.AddOptions{}, err
+	}
+
+	track, err := trackingOption(cmd, cfg)
+	if err != nil {
+		return worktree.AddOptions{}, err
+	}
+
+	layout, err := layoutOption(cmd, cfg)
 	if err != nil {
-		return fmt.Errorf("clove: %w", err)
+		return worktree.AddOptions{}, err
+	}
+
+	return worktree.AddOptions{
+		Branch:       branch,
+		BaseRef:      stringOption(cmd, "base", cfg, "add.base"),
+		Remote:       stringOption(cmd, "remote", cfg, "remote.default"),
+		Layout:       layout,
+		ForceName:    stringOption(cmd, "dir", cfg, "add.dir"),
+		OpenCmd:      stringOption(cmd, "open", cfg, "add.open"),
+		DryRun:       boolOption(cmd, "dry-run", cfg, "add.dry-run"),
+		NoFetch:      boolOption(cmd, "no-fetch", cfg, "add.no-fetch"),
+		Track:        track,
+		PushUpstream: boolOption(cmd, "push-upstream", cfg, "add.push-upstream"),
+		Copy:         listOption(cmd, "copy", cfg, "add.copy"),
+		CopyMode:     copyMode,
+		Bootstrap:    bs,
+		Hooks:        hooks,
+	}, nil
+}
+
+// trackingOption resolves --track / --no-track, falling back to add.track
+func trackingOption(cmd *cobra.Command, cfg *config.Config) (worktree.Tracking, error) {
+	if cmd.Flags().Changed("track") {
+		if v, _ := cmd.Flags().GetBool("track"); !v {
+			return worktree.TrackNone, nil
+		}
+		return worktree.TrackRemote, nil
+	}
+	if v, _ := cmd.Flags().GetBool("no-track"); v {
+		return worktree.TrackNone, nil
+	}
+	track, err := worktree.ParseTracking(cfg.String("add.track"))
+	if err != nil {
+		return "", fmt.Errorf("clove: %w", err)
+	}
+	return track, nil
+}
+
+// bootstrapOptions builds bootstrap options from flags and bootstrap.<name>.* config keys
+func bootstrapOptions(cmd *cobra.Command, cfg *config.Config) (bootstrap.Options, error) {
+	opts := bootstrap.Options{
+		Disabled:   boolOption(cmd, "no-bootstrap", cfg, "add.no-bootstrap"),
+		Only:       listOption(cmd, "bootstrap", cfg, "add.bootstrap"),
+		Strategies: map[string]bootstrap.Strategy{},
+		Commands:   map[string]string{},
 	}
 
-	opts := worktree.AddOptions{
-		Branch:    branch,
-		BaseRef:   addBaseRef,
-		Prefix:    addPrefix,
-		Suffix:    addSuffix,
-		ForceName: addForceName,
-		OpenCmd:   addOpenCmd,
-		DryRun:    addDryRun,
-		NoFetch:   addNoFetch,
+	for _, key := range cfg.Keys() {
+		rest, ok := strings.CutPrefix(key, "bootstrap.")
+		if !ok {
+			continue
+		}
+		name, field, ok := strings.Cut(rest, ".")
+		if !ok {
+			return opts, fmt.Errorf("clove: 設定キーは bootstrap.<名前>.strategy の形式で指定してください: %s", key)
+		}
+		if _, ok := bootstrap.Lookup(name); !ok {
+			return opts, fmt.Errorf("clove: %s: 未知の bootstrap です: %s", key, name)
+		}
+		switch field {
+		case "strategy":
+			st, err := bootstrap.ParseStrategy(cfg.String(key))
+			if err != nil {
+				return opts, fmt.Errorf("clove: %s: %w", key, err)
+			}
+			opts.Strategies[name] = st
+		case "command":
+			opts.Commands[name] = cfg.String(key)
+		default:
+			return opts, fmt.Errorf("clove: 未知の設定キーです: %s", key)
+		}
 	}
 
-	return worktree.Add(repoRoot, opts)
+	return opts, nil
 }
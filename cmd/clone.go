@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"github.com/manattan/clove/internal/bare"
+	"github.com/manattan/clove/internal/util"
+	"github.com/manattan/clove/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone [オプション] <URL|パス> [ディレクトリ]",
+	Short: "bare リポジトリ + worktree の構成でクローンします",
+	Long: `リポジトリを bare リポジトリとしてクローンし、ブランチごとの worktree を
+同じディレクトリに並べる構成を作ります。
+
+  <ディレクトリ>/.bare    bare リポジトリ
+  <ディレクトリ>/.git     "gitdir: ./.bare"（ここから git / clove を使えるようにする）
+  <ディレクトリ>/main     既定ブランチの worktree
+
+リモート追跡ブランチ（origin/*）と origin/HEAD を設定し、既定ブランチ以外の
+ローカルブランチは作りません。<ディレクトリ>/.clove.toml に layout.path = {branch}
+を書き込むので、以降の clove add はこのディレクトリの中に worktree を作ります。
+ディレクトリを省略すると URL のリポジトリ名になります。
+
+例:
+  clove clone https://github.com/manattan/clove.git
+  clove clone git@github.com:manattan/clove.git ~/src/clove
+  clove clone --layout 'worktrees/{branch}' ../upstream`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runClone,
+}
+
+var (
+	cloneRemote      string
+	cloneLayout      string
+	cloneOpenCmd     string
+	cloneDryRun      bool
+	cloneNoBootstrap bool
+)
+
+func init() {
+	cloneCmd.Flags().StringVar(&cloneRemote, "remote", "origin", "リモートの名前")
+	cloneCmd.Flags().StringVar(&cloneLayout, "layout", bare.Layout, "書き込む layout.path（空にすると書き込まない）")
+	cloneCmd.Flags().StringVar(&cloneOpenCmd, "open", "", "作成後に既定ブランチの worktree を開くコマンド（例: code / cursor / open）")
+	cloneCmd.Flags().BoolVar(&cloneDryRun, "dry-run", false, "実行せず、実行内容だけ表示します")
+	cloneCmd.Flags().BoolVar(&cloneNoBootstrap, "no-bootstrap", false, "依存ディレクトリの bootstrap をスキップします")
+}
+
+func runClone(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	opts := bare.CloneOptions{URL: args[0], Remote: cloneRemote, Layout: cloneLayout, DryRun: cloneDryRun}
+	if len(args) > 1 {
+		opts.Dir = args[1]
+	}
+
+	// クローン前はリポジトリがないので、グローバル設定だけを使う
+	if _, err := loadConfig(""); err != nil {
+		return err
+	}
+	res, err := bare.Clone(ctx, opts)
+	if err != nil || opts.DryRun || res.DefaultBranch == "" {
+		return finish("clone", res, err)
+	}
+
+	cfg, err := loadConfig(res.Dir)
+	if err != nil {
+		return finish("clone", res, err)
+	}
+	addOpts, err := addOptions(cmd, cfg, res.Dir, res.DefaultBranch)
+	if err != nil {
+		return finish("clone", res, err)
+	}
+	addOpts.Remote = res.Remote
+	addOpts.NoFetch = true
+
+	util.Printf("\n")
+	res.Worktree, err = worktree.Add(ctx, res.Dir, addOpts)
+	return finish("clone", res, err)
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/manattan/clove/internal/config"
+	"github.com/manattan/clove/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config <get|set|list>",
+	Short: "設定値を表示・変更します",
+	Long: `clove の設定値を表示・変更します。
+設定は以下の順に読み込まれ、後のものが優先されます:
+  1. 組み込みのデフォルト値
+  2. グローバル設定 (~/.config/clove/config)
+  3. リポジトリ設定 (<repo>/.clove.toml または .clove.yaml)
+  4. コマンドラインフラグ
+
+例:
+  clove config list
+  clove config get add.base
+  clove config set add.base origin/develop
+  clove config set --global add.open cursor`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <キー>",
+	Short: "設定値を表示します",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <キー> <値>",
+	Short: "設定値を書き込みます（リスト値はカンマ区切り）",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runConfigSet,
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "すべての設定値と取得元を表示します",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigList,
+}
+
+var (
+	configRepo   string
+	configGlobal bool
+)
+
+// configEntry is a resolved config value in structured output
+type configEntry struct {
+	Key    string   `json:"key"`
+	Value  string   `json:"value"`
+	Items  []string `json:"items,omitempty"`
+	Source string   `json:"source"`
+	Path   string   `json:"path,omitempty"`
+}
+
+// newConfigEntry converts a resolved value for structured output
+func newConfigEntry(key string, v config.Value) configEntry {
+	e := configEntry{Key: key, Value: v.String(), Source: string(v.Source), Path: v.Path}
+	if v.List {
+		e.Items = v.Items
+	}
+	return e
+}
+
+func init() {
+	configCmd.PersistentFlags().StringVar(&configRepo, "repo", "", "対象リポジトリのパス（省略時: カレントから判定）")
+	configSetCmd.Flags().BoolVar(&configGlobal, "global", false, "グローバル設定に書き込みます")
+
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configListCmd)
+}
+
+// configRepoRoot resolves the repository for config commands.
+// Outside a repository only the global layer is used.
+func configRepoRoot(ctx context.Context) string {
+	root, err := repoRootOf(ctx, configRepo)
+	if err != nil {
+		return configRepo
+	}
+	return root
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(configRepoRoot(cmd.Context()))
+	if err != nil {
+		return err
+	}
+	v, ok := cfg.Lookup(args[0])
+	if !ok {
+		return fmt.Errorf("clove: 設定されていないキーです: %s", args[0])
+	}
+	if output.IsStructured() {
+		return finish("config", newConfigEntry(args[0], v), nil)
+	}
+	fmt.Println(v.String())
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	var path string
+	if configGlobal {
+		path = config.FindGlobalFile()
+		if path == "" {
+			dir, err := config.GlobalDir()
+			if err != nil {
+				return fmt.Errorf("clove: %w", err)
+			}
+			path = filepath.Join(dir, "config")
+		}
+	} else {
+		repoRoot := configRepoRoot(cmd.Context())
+		if repoRoot == "" {
+			return fmt.Errorf("clove: gitリポジトリではありません（--global を指定してください）")
+		}
+		path = config.FindRepoFile(repoRoot)
+		if path == "" {
+			path = filepath.Join(repoRoot, config.RepoFileNames[0])
+		}
+	}
+
+	if err := config.Set(path, args[0], args[1]); err != nil {
+		return fmt.Errorf("clove: %w", err)
+	}
+	fmt.Printf("%s = %s (%s)\n", args[0], args[1], path)
+	return nil
+}
+
+func runConfigList(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(configRepoRoot(cmd.Context()))
+	if err != nil {
+		return err
+	}
+	if output.IsStructured() {
+		entries := []configEntry{}
+		for _, key := range cfg.Keys() {
+			v, _ := cfg.Lookup(key)
+			entries = append(entries, newConfigEntry(key, v))
+		}
+		return finish("config", entries, nil)
+	}
+
+	for _, key := range cfg.Keys() {
+		v, _ := cfg.Lookup(key)
+		source := string(v.Source)
+		if v.Path != "" {
+			source += ": " + v.Path
+		}
+		fmt.Printf("%s = %s\t(%s)\n", key, v.String(), source)
+	}
+	return nil
+}
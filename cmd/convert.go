@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"github.com/manattan/clove/internal/bare"
+	"github.com/spf13/cobra"
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert [オプション]",
+	Short: "既存のクローンを bare リポジトリ + worktree の構成に変換します",
+	Long: `クローンし直さずに、既存のリポジトリを clove clone と同じ構成に変換します。
+
+  <repo>/.git        -> <repo>/.bare（bare リポジトリ）
+  <repo>/<ファイル>  -> <repo>/<ブランチ>/<ファイル>（メインの worktree）
+  <repo>/.git        "gitdir: ./.bare"
+
+未コミットの変更・未追跡ファイル・stash・hook・設定はそのまま引き継ぎ、
+linked worktree の参照も .bare に付け替えます。変換後に git status と stash が
+変わっていないことを確認し、途中で失敗したり中断したりした場合は元に戻します。
+変換前に .git を <repo>.git-backup-<日時> にバックアップします。
+
+clove convert --undo で元の構成に戻せます（変換後のコミットや変更は残ります）。
+
+例:
+  clove convert --dry-run
+  clove convert
+  clove convert --layout 'worktrees/{branch}'
+  clove convert --undo`,
+	Args: cobra.NoArgs,
+	RunE: runConvert,
+}
+
+var (
+	convertRepo     string
+	convertLayout   string
+	convertDryRun   bool
+	convertNoBackup bool
+	convertUndo     bool
+)
+
+func init() {
+	convertCmd.Flags().StringVar(&convertRepo, "repo", "", "対象リポジトリのパス（省略時: カレントから判定）")
+	convertCmd.Flags().StringVar(&convertLayout, "layout", bare.Layout, "メインの worktree の配置と、書き込む layout.path（空にすると書き込まない）")
+	convertCmd.Flags().BoolVar(&convertDryRun, "dry-run", false, "実行せず、変換の手順だけ表示します")
+	convertCmd.Flags().BoolVar(&convertNoBackup, "no-backup", false, ".git のバックアップを作りません")
+	convertCmd.Flags().BoolVar(&convertUndo, "undo", false, "clove convert で変換したリポジトリを元の構成に戻します")
+	convertCmd.MarkFlagsMutuallyExclusive("undo", "layout")
+	convertCmd.MarkFlagsMutuallyExclusive("undo", "no-backup")
+}
+
+func runConvert(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	repoRoot, err := repoRootOf(ctx, convertRepo)
+	if err != nil {
+		return err
+	}
+	if _, err := loadConfig(repoRoot); err != nil {
+		return err
+	}
+
+	if convertUndo {
+		res, err := bare.Revert(ctx, repoRoot, convertDryRun)
+		return finish("convert", res, err)
+	}
+	res, err := bare.Convert(ctx, repoRoot, bare.ConvertOptions{
+		Layout:   convertLayout,
+		DryRun:   convertDryRun,
+		NoBackup: convertNoBackup,
+	})
+	return finish("convert", res, err)
+}
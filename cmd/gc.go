@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/manattan/clove/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc [オプション]",
+	Short: "マージ済み・放置された worktree をまとめて削除します",
+	Long: `次のいずれかに当てはまる worktree を探し、削除計画を表示してから削除します。
+  merged         ブランチが base ref に取り込まれている（squash マージも検出）
+  upstream-gone  upstream のブランチがリモートから削除されている
+  stale          最後のコミットから --stale-days 日以上経っている
+
+未コミットの変更がある worktree は --force を付けない限り削除しません。
+未プッシュのコミットや stash、大きな ignore 対象のファイルがある worktree は
+--i-know を付けない限り削除しません（マージ済みのブランチのコミットは対象外）。
+--delete-branch を付けると、マージ済みのローカルブランチも削除します。
+
+例:
+  clove gc --dry-run
+  clove gc --delete-branch
+  clove gc --stale-days 30 --yes`,
+	Args: cobra.NoArgs,
+	RunE: runGC,
+}
+
+var (
+	gcRepo         string
+	gcBase         string
+	gcRemote       string
+	gcMerged       bool
+	gcGone         bool
+	gcStaleDays    int
+	gcDeleteBranch bool
+	gcForce        bool
+	gcDryRun       bool
+	gcYes          bool
+	gcIKnow        bool
+)
+
+func init() {
+	gcCmd.Flags().StringVar(&gcRepo, "repo", "", "対象リポジトリのパス（省略時: カレントから判定）")
+	gcCmd.Flags().StringVar(&gcBase, "base", "", "マージ判定に使う base ref（省略時: リモートの既定ブランチを検出）")
+	gcCmd.Flags().StringVar(&gcRemote, "remote", "", "既定の base ref を決めるリモート（省略時: remote.default / origin）")
+	gcCmd.Flags().BoolVar(&gcMerged, "merged", true, "base にマージ済みのブランチを対象にします")
+	gcCmd.Flags().BoolVar(&gcGone, "gone", true, "upstream が削除されたブランチを対象にします")
+	gcCmd.Flags().IntVar(&gcStaleDays, "stale-days", 0, "最後のコミットからこの日数が経った worktree を対象にします（0 で無効）")
+	gcCmd.Flags().BoolVar(&gcDeleteBranch, "delete-branch", false, "マージ済みのローカルブランチも削除します")
+	gcCmd.Flags().BoolVar(&gcForce, "force", false, "未コミットの変更がある worktree も削除します")
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "削除計画を表示するだけで削除しません")
+	gcCmd.Flags().BoolVar(&gcIKnow, "i-know", false, "未プッシュのコミットや stash などが失われても削除します")
+	gcCmd.Flags().BoolVarP(&gcYes, "yes", "y", false, "確認せずに削除します")
+}
+
+func runGC(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	repoRoot, err := repoRootOf(ctx, gcRepo)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(repoRoot)
+	if err != nil {
+		return err
+	}
+
+	layout, err := layoutOption(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	hooks, err := hookConfig(cfg, repoRoot)
+	if err != nil {
+		return err
+	}
+
+	staleDays := gcStaleDays
+	if !cmd.Flags().Changed("stale-days") {
+		staleDays, err = strconv.Atoi(cfg.String("gc.stale-days"))
+		if err != nil || staleDays < 0 {
+			return fmt.Errorf("clove: gc.stale-days は 0 以上の整数で指定してください: %s", cfg.String("gc.stale-days"))
+		}
+	}
+
+	safety, err := safetyOptions(cfg)
+	if err != nil {
+		return err
+	}
+
+	opts := worktree.GCOptions{
+		BaseRef:      stringOption(cmd, "base", cfg, "gc.base"),
+		Remote:       stringOption(cmd, "remote", cfg, "remote.default"),
+		Merged:       boolOption(cmd, "merged", cfg, "gc.merged"),
+		Gone:         boolOption(cmd, "gone", cfg, "gc.gone"),
+		StaleDays:    staleDays,
+		DeleteBranch: boolOption(cmd, "delete-branch", cfg, "gc.delete-branch"),
+		Force:        boolOption(cmd, "force", cfg, "gc.force"),
+		DryRun:       boolOption(cmd, "dry-run", cfg, "gc.dry-run"),
+		IKnow:        gcIKnow,
+		Safety:       safety,
+		Hooks:        hooks,
+		Layout:       layout,
+	}
+
+	res, err := worktree.PlanGC(ctx, repoRoot, opts)
+	if err != nil {
+		return finish("gc", res, fmt.Errorf("clove: %w", err))
+	}
+	res.PrintPlan()
+	if res.Removable() == 0 {
+		return finish("gc", res, nil)
+	}
+
+	if !opts.DryRun && !gcYes {
+		ok, err := confirm(fmt.Sprintf("%d 件の worktree を削除します。よろしいですか？", res.Removable()))
+		if err != nil {
+			return finish("gc", res, err)
+		}
+		if !ok {
+			return finish("gc", res, fmt.Errorf("clove: 中止しました"))
+		}
+	}
+
+	err = worktree.RunGC(ctx, repoRoot, res, opts)
+	if err != nil {
+		err = fmt.Errorf("clove: %w", err)
+	}
+	return finish("gc", res, err)
+}
@@ -1,9 +1,10 @@
 package cmd
 
 import (
-	"fmt"
+	"os"
+	"strings"
 
-	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/output"
 	"github.com/manattan/clove/internal/worktree"
 	"github.com/spf13/cobra"
 )
@@ -11,33 +12,67 @@ import (
 var listCmd = &cobra.Command{
 	Use:   "list [オプション]",
 	Short: "worktree の一覧を表示します",
-	Args:  cobra.NoArgs,
-	RunE:  runList,
+	Long: `worktree の一覧を、ブランチ・upstream / base との差分・変更ファイル数などの状態付きで表示します。
+
+例:
+  clove list
+  clove list --columns branch,dirty,size,path
+  clove list --base origin/develop
+  clove list --porcelain`,
+	Args: cobra.NoArgs,
+	RunE: runList,
 }
 
 var (
 	listRepo      string
 	listPorcelain bool
+	listColumns   []string
+	listBase      string
+	listRemote    string
 )
 
 func init() {
 	listCmd.Flags().StringVar(&listRepo, "repo", "", "対象リポジトリのパス（省略時: カレントから判定）")
 	listCmd.Flags().BoolVar(&listPorcelain, "porcelain", false, "機械処理しやすい形式（--porcelain）で表示します")
+	listCmd.Flags().StringSliceVar(&listColumns, "columns", nil, "表示する列をカンマ区切りで指定します（"+strings.Join(worktree.ColumnNames(), ", ")+"）")
+	listCmd.Flags().StringVar(&listBase, "base", "", "ahead/behind の比較に使う base ref（省略時: リモートの既定ブランチを検出）")
+	listCmd.Flags().StringVar(&listRemote, "remote", "", "既定の base ref を決めるリモート（省略時: remote.default / origin）")
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	repoRoot := listRepo
-	if repoRoot == "" {
-		var err error
-		repoRoot, err = git.GetRepoRoot()
-		if err != nil {
-			return fmt.Errorf("clove: %w", err)
-		}
+	ctx := cmd.Context()
+	repo, err := openRepository(ctx, listRepo)
+	if err != nil {
+		return err
+	}
+	repoRoot := repo.Main
+
+	cfg, err := loadConfig(repoRoot)
+	if err != nil {
+		return err
+	}
+
+	layout, err := layoutOption(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	if boolOption(cmd, "porcelain", cfg, "list.porcelain") && !output.IsStructured() {
+		return worktree.ListPorcelain(ctx, repoRoot)
 	}
 
 	opts := worktree.ListOptions{
-		Porcelain: listPorcelain,
+		Columns: listOption(cmd, "columns", cfg, "list.columns"),
+		BaseRef: stringOption(cmd, "base", cfg, "list.base"),
+		Remote:  stringOption(cmd, "remote", cfg, "remote.default"),
+		Layout:  layout,
+		Current: repo.Current,
 	}
 
-	return worktree.List(repoRoot, opts)
+	res, err := worktree.List(ctx, repoRoot, opts)
+	if err != nil || output.IsStructured() {
+		return finish("list", res, err)
+	}
+	res.Render(os.Stdout)
+	return nil
 }
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/util"
+	"github.com/manattan/clove/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var openCmd = &cobra.Command{
+	Use:   "open [オプション] [ブランチ名|検索語]",
+	Short: "worktree をエディタなどで開きます",
+	Long: `clove switch と同じ方法で worktree を探し、指定したコマンドで開きます。
+コマンドは --with、設定 open.command、add.open の順に決まります。
+端末で引数を省略した場合は、対話的に worktree を選択できます。
+
+例:
+  clove open feature/update
+  clove open --with cursor upd
+  clove open               # 一覧から選択`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runOpen,
+}
+
+var (
+	openRepo string
+	openWith string
+)
+
+// openResult describes the worktree opened by `clove open`
+type openResult struct {
+	Repo    string `json:"repo"`
+	Path    string `json:"path"`
+	Branch  string `json:"branch,omitempty"`
+	Command string `json:"command"`
+}
+
+func init() {
+	openCmd.Flags().StringVar(&openRepo, "repo", "", "対象リポジトリのパス（省略時: カレントから判定）")
+	openCmd.Flags().StringVar(&openWith, "with", "", "worktree を開くコマンド（例: code / cursor / open）")
+}
+
+func runOpen(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	repoRoot, err := repoRootOf(ctx, openRepo)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(repoRoot)
+	if err != nil {
+		return err
+	}
+
+	layout, err := layoutOption(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	command := stringOption(cmd, "with", cfg, "open.command")
+	if command == "" {
+		command = cfg.String("add.open")
+	}
+	if command == "" {
+		return fmt.Errorf("clove: 開くコマンドが決まっていません（--with または clove config set open.command <コマンド>）")
+	}
+
+	var wt worktree.WorktreeInfo
+	if len(args) == 0 {
+		picked, err := pickWorktrees(ctx, repoRoot, "open>", false, true)
+		if err != nil {
+			return err
+		}
+		wt = picked[0].WorktreeInfo
+	} else {
+		wt, err = worktree.Resolve(ctx, repoRoot, args[0], layout)
+		if errors.Is(err, worktree.ErrNotFound) {
+			return fmt.Errorf("clove: worktree が見つかりません: %s", args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("clove: %w", err)
+		}
+	}
+
+	res := &openResult{Repo: repoRoot, Path: wt.Path, Branch: wt.Branch, Command: command}
+	util.Verbose("[verbose] %s で開きます: %s", command, wt.Path)
+	if err := git.Run(ctx, command, wt.Path); err != nil {
+		return finish("open", res, fmt.Errorf("clove: %w", err))
+	}
+	return finish("open", res, nil)
+}
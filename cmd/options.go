@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/manattan/clove/internal/clone"
+	"github.com/manattan/clove/internal/config"
+	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/hook"
+	"github.com/manattan/clove/internal/output"
+	"github.com/manattan/clove/internal/util"
+	"github.com/manattan/clove/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+// openRepository finds the repository containing dir ("" for the working directory)
+func openRepository(ctx context.Context, dir string) (*git.Repository, error) {
+	repo, err := git.OpenRepository(ctx, dir)
+	if err != nil {
+		return nil, fmt.Errorf("clove: %w", err)
+	}
+	return repo, nil
+}
+
+// repoRootOf returns the main worktree of the repository containing dir ("" for
+// the working directory), so every command behaves the same in any worktree
+func repoRootOf(ctx context.Context, dir string) (string, error) {
+	repo, err := openRepository(ctx, dir)
+	if err != nil {
+		return "", err
+	}
+	return repo.Main, nil
+}
+
+// loadConfig loads the layered config for repoRoot
+func loadConfig(repoRoot string) (*config.Config, error) {
+	cfg, err := config.Load(repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("clove: 設定ファイルの読み込みに失敗しました: %w", err)
+	}
+	if p := config.FindGlobalFile(); p != "" {
+		util.Verbose("[verbose] グローバル設定を読み込みました: %s", p)
+	}
+	if p := config.FindRepoFile(repoRoot); p != "" {
+		util.Verbose("[verbose] リポジトリ設定を読み込みました: %s", p)
+	}
+	if err := git.UseBackend(cfg.String("git.backend")); err != nil {
+		return nil, fmt.Errorf("clove: git.backend: %w", err)
+	}
+	util.Verbose("[verbose] git backend: %s", git.CurrentBackend().Name())
+
+	timeout := cfg.String("git.timeout")
+	if rootCmd.PersistentFlags().Changed("timeout") {
+		timeout = globalTimeout
+	}
+	d, err := time.ParseDuration(timeout)
+	if err != nil {
+		return nil, fmt.Errorf("clove: git.timeout を解釈できません: %w", err)
+	}
+	git.SetTimeout(d)
+	return cfg, nil
+}
+
+// stringOption returns the flag value if it was given explicitly, otherwise the config value
+func stringOption(cmd *cobra.Command, flag string, cfg *config.Config, key string) string {
+	if cmd.Flags().Changed(flag) {
+		v, _ := cmd.Flags().GetString(flag)
+		return v
+	}
+	return cfg.String(key)
+}
+
+// boolOption returns the flag value if it was given explicitly, otherwise the config value
+func boolOption(cmd *cobra.Command, flag string, cfg *config.Config, key string) bool {
+	if cmd.Flags().Changed(flag) {
+		v, _ := cmd.Flags().GetBool(flag)
+		return v
+	}
+	return cfg.Bool(key)
+}
+
+// listOption returns the flag value if it was given explicitly, otherwise the config value
+func listOption(cmd *cobra.Command, flag string, cfg *config.Config, key string) []string {
+	if cmd.Flags().Changed(flag) {
+		v, _ := cmd.Flags().GetStringSlice(flag)
+		return v
+	}
+	return cfg.List(key)
+}
+
+// hookConfig builds the hook settings from hooks.* config keys and --no-hooks
+func hookConfig(cfg *config.Config, repoRoot string) (hook.Config, error) {
+	h := hook.Config{
+		Disabled: globalNoHooks,
+		Commands: map[hook.Event]string{},
+		Dir:      filepath.Join(repoRoot, hook.DirName),
+	}
+	for _, ev := range hook.Events {
+		h.Commands[ev] = cfg.String("hooks." + string(ev))
+	}
+
+	timeout, err := time.ParseDuration(cfg.String("hooks.timeout"))
+	if err != nil {
+		return h, fmt.Errorf("clove: hooks.timeout を解釈できません: %w", err)
+	}
+	h.Timeout = timeout
+
+	policy, err := hook.ParsePolicy(cfg.String("hooks.on-error"))
+	if err != nil {
+		return h, fmt.Errorf("clove: %w", err)
+	}
+	h.OnError = policy
+
+	return h, nil
+}
+
+// layoutOption builds the worktree layout from layout.path, add.prefix and
+// add.suffix, preferring the --layout / --prefix / --suffix flags when cmd defines them
+func layoutOption(cmd *cobra.Command, cfg *config.Config) (worktree.Layout, error) {
+	l := worktree.Layout{
+		Template: stringOption(cmd, "layout", cfg, "layout.path"),
+		Prefix:   stringOption(cmd, "prefix", cfg, "add.prefix"),
+		Suffix:   stringOption(cmd, "suffix", cfg, "add.suffix"),
+		Unicode:  cfg.Bool("layout.unicode"),
+	}
+	if err := worktree.ValidateLayout(l.Template); err != nil {
+		return l, fmt.Errorf("clove: layout.path: %w", err)
+	}
+	n, err := strconv.Atoi(cfg.String("layout.max-length"))
+	if err != nil || n < 0 {
+		return l, fmt.Errorf("clove: layout.max-length: 0 以上の整数を指定してください: %q", cfg.String("layout.max-length"))
+	}
+	l.MaxLength = n
+	return l, nil
+}
+
+// safetyOptions builds the pre-removal safety checks from remove.* config keys
+func safetyOptions(cfg *config.Config) (worktree.SafetyOptions, error) {
+	threshold, err := clone.ParseBytes(cfg.String("remove.ignored-threshold"))
+	if err != nil {
+		return worktree.SafetyOptions{}, fmt.Errorf("clove: remove.ignored-threshold: %w", err)
+	}
+	return worktree.SafetyOptions{IgnoredThreshold: threshold}, nil
+}
+
+// finish emits the structured result document when --output is json or yaml
+func finish(name string, result interface{}, err error) error {
+	if output.IsStructured() {
+		return output.Emit(name, result, err)
+	}
+	return err
+}
+
+// confirm asks a yes/no question on the terminal.
+// Without a terminal the caller must pass --yes instead.
+func confirm(question string) (bool, error) {
+	if !util.IsTerminal(os.Stdin) {
+		return false, fmt.Errorf("clove: 確認が必要です（端末以外から実行する場合は --yes を指定してください）")
+	}
+	util.Printf("%s [y/N]: ", question)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/manattan/clove/internal/picker"
+	"github.com/manattan/clove/internal/worktree"
+)
+
+// pickWorktrees lets the user choose worktrees with the built-in fuzzy picker.
+// The main worktree (the first entry) is offered only when withMain is set.
+func pickWorktrees(ctx context.Context, repoRoot, prompt string, multi, withMain bool) ([]worktree.Status, error) {
+	if !picker.Available() {
+		return nil, fmt.Errorf("clove: 引数を指定してください（対話的な選択は端末でのみ使えます）")
+	}
+
+	res, err := worktree.List(ctx, repoRoot, worktree.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("clove: %w", err)
+	}
+
+	var candidates []worktree.Status
+	for i, st := range res.Worktrees {
+		if st.Bare || (i == 0 && !withMain) {
+			continue
+		}
+		candidates = append(candidates, st)
+	}
+
+	now := time.Now()
+	width := 0
+	for _, st := range candidates {
+		if n := len(branchLabel(st)); n > width {
+			width = n
+		}
+	}
+	items := make([]picker.Item, len(candidates))
+	for i, st := range candidates {
+		label := branchLabel(st)
+		items[i] = picker.Item{
+			Label:   label + strings.Repeat(" ", width-len(label)+2) + filepath.Base(st.Path),
+			Preview: worktree.Preview(st, now),
+		}
+	}
+
+	picked, err := picker.Run(items, picker.Options{Prompt: prompt, Multi: multi})
+	if err != nil {
+		return nil, fmt.Errorf("clove: %w", err)
+	}
+	selected := make([]worktree.Status, len(picked))
+	for i, idx := range picked {
+		selected[i] = candidates[idx]
+	}
+	return selected, nil
+}
+
+// branchLabel returns the short branch name of st for display
+func branchLabel(st worktree.Status) string {
+	switch {
+	case st.Branch != "":
+		return strings.TrimPrefix(st.Branch, "refs/heads/")
+	case st.Detached:
+		return "(detached " + shortHead(st.Head) + ")"
+	}
+	return "-"
+}
+
+// shortHead abbreviates a commit hash
+func shortHead(h string) string {
+	if len(h) > 7 {
+		return h[:7]
+	}
+	return h
+}
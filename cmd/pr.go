@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var prCmd = &cobra.Command{
+	Use:   "pr [オプション] <番号>",
+	Short: "プルリクエストの head を取得して worktree を作成します",
+	Long: `リモートからプルリクエスト（GitHub の pull/<N>/head、GitLab の
+merge-requests/<N>/head）を取得し、ローカルブランチ pr/<N> として
+~/hogehoge-pr-<N> のような worktree を作成します。
+PR 番号はブランチの設定に記録され、clove list に表示されます。
+
+再実行するとブランチ pr/<N> を PR の最新の head で更新します
+（その worktree が既にある場合はエラーになります）。
+
+例:
+  clove pr 123
+  clove pr --remote upstream 123
+  clove pr --provider gitlab 45
+  clove pr --refspec 'refs/changes/{number}/head' 7`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPR,
+}
+
+var (
+	prRemote      string
+	prProvider    string
+	prRefspec     string
+	prOpenCmd     string
+	prDryRun      bool
+	prNoBootstrap bool
+)
+
+func init() {
+	prCmd.Flags().StringVar(&prRemote, "remote", "", "PR を取得するリモート（省略時: remote.default / origin）")
+	prCmd.Flags().StringVar(&prProvider, "provider", "", "ホスティングサービス（github / gitlab、省略時: リモート URL から判定）")
+	prCmd.Flags().StringVar(&prRefspec, "refspec", "", "PR の head の ref（{number} が PR 番号に置き換わる）")
+	prCmd.Flags().StringVar(&prOpenCmd, "open", "", "作成後にディレクトリを開くコマンド（例: code / cursor / open）")
+	prCmd.Flags().BoolVar(&prDryRun, "dry-run", false, "実行せず、実行内容だけ表示します")
+	prCmd.Flags().BoolVar(&prNoBootstrap, "no-bootstrap", false, "依存ディレクトリの bootstrap をスキップします")
+}
+
+func runPR(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	n, err := worktree.ParsePRNumber(args[0])
+	if err != nil {
+		return fmt.Errorf("clove: %w", err)
+	}
+
+	repoRoot, err := git.GetRepoRoot(ctx)
+	if err != nil {
+		return fmt.Errorf("clove: %w", err)
+	}
+
+	cfg, err := loadConfig(repoRoot)
+	if err != nil {
+		return err
+	}
+
+	remote := stringOption(cmd, "remote", cfg, "pr.remote")
+	if remote == "" {
+		remote = cfg.String("remote.default")
+	}
+	remote = git.DefaultRemote(ctx, repoRoot, remote)
+	ref, err := worktree.PRRef(ctx, repoRoot, remote, stringOption(cmd, "provider", cfg, "pr.provider"), stringOption(cmd, "refspec", cfg, "pr.refspec"), n)
+	if err != nil {
+		return fmt.Errorf("clove: %w", err)
+	}
+
+	opts, err := addOptions(cmd, cfg, repoRoot, worktree.PRBranch(n))
+	if err != nil {
+		return err
+	}
+	opts.Remote = remote
+	opts.Fetch = &worktree.FetchSpec{Remote: remote, Ref: ref, PR: n}
+
+	res, err := worktree.Add(ctx, repoRoot, opts)
+	return finish("pr", res, err)
+}
@@ -1,9 +1,6 @@
 package cmd
 
 import (
-	"fmt"
-
-	"github.com/manattan/clove/internal/git"
 	"github.com/manattan/clove/internal/worktree"
 	"github.com/spf13/cobra"
 )
@@ -28,19 +25,28 @@ func init() {
 }
 
 func runPrune(cmd *cobra.Command, args []string) error {
-	repoRoot := pruneRepo
-	if repoRoot == "" {
-		var err error
-		repoRoot, err = git.GetRepoRoot()
-		if err != nil {
-			return fmt.Errorf("clove: %w", err)
-		}
+	ctx := cmd.Context()
+	repoRoot, err := repoRootOf(ctx, pruneRepo)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(repoRoot)
+	if err != nil {
+		return err
+	}
+
+	hooks, err := hookConfig(cfg, repoRoot)
+	if err != nil {
+		return err
 	}
 
 	opts := worktree.PruneOptions{
-		DryRun:  pruneDryRun,
-		Verbose: pruneVerbose,
+		DryRun:  boolOption(cmd, "dry-run", cfg, "prune.dry-run"),
+		Verbose: boolOption(cmd, "verbose", cfg, "prune.verbose"),
+		Hooks:   hooks,
 	}
 
-	return worktree.Prune(repoRoot, opts)
+	res, err := worktree.Prune(ctx, repoRoot, opts)
+	return finish("prune", res, err)
 }
@@ -3,22 +3,33 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/util"
 	"github.com/manattan/clove/internal/worktree"
 	"github.com/spf13/cobra"
 )
 
 var removeCmd = &cobra.Command{
-	Use:     "remove [オプション] <パス|ブランチ名>",
+	Use:     "remove [オプション] [パス|ブランチ名|パターン]...",
 	Aliases: []string{"rm"},
 	Short:   "worktree を削除します（パス指定 or ブランチ名指定）",
 	Long: `引数が存在するパスならその worktree を削除します。
 パスとして存在しない場合はブランチ名として解釈し、worktree 一覧から紐づくパスを探して削除します。
+どのリモートにもないコミット、その worktree で作った stash、大きな ignore 対象のファイルが
+あると削除を中止します（--force を付けても同様。内容を確認したうえで --i-know で削除できます）。
+複数の引数や glob パターン（ブランチ名・ディレクトリ名・パスに一致）を指定すると、
+対象を一覧表示して確認したうえで並列に削除し、1 件ずつ結果を表示します。
+端末で引数を省略した場合は、対話的に worktree を選択できます（Tab で複数選択）。
 
 例:
   clove rm ../hogehoge-feature-update
-  clove rm feature/update`,
-	Args: cobra.ExactArgs(1),
+  clove rm feature/update
+  clove rm --delete-branch feature/update        # ブランチも削除（git branch -d）
+  clove rm --delete-branch=force feature/update  # 未マージでも削除（git branch -D）
+  clove rm --delete-branch --delete-remote feature/update
+  clove rm 'feature/JIRA-12*'
+  clove rm --all-except main --all-except develop
+  clove rm                 # 一覧から選択`,
+	Args: cobra.ArbitraryArgs,
 	RunE: runRemove,
 }
 
@@ -26,31 +37,126 @@ var (
 	removeRepo   string
 	removeForce  bool
 	removeDryRun bool
+
+	removeDeleteBranch string
+	removeDeleteRemote bool
+	removeIKnow        bool
+
+	removeAllExcept []string
+	removeYes       bool
+	removeJobs      int
 )
 
 func init() {
 	removeCmd.Flags().StringVar(&removeRepo, "repo", "", "対象リポジトリのパス（省略時: カレントから判定）")
 	removeCmd.Flags().BoolVar(&removeForce, "force", false, "強制削除（git worktree remove --force）")
 	removeCmd.Flags().BoolVar(&removeDryRun, "dry-run", false, "実行せず、実行内容だけ表示します")
+	removeCmd.Flags().StringVar(&removeDeleteBranch, "delete-branch", "", "ローカルブランチも削除します（値なし/safe: git branch -d, force: git branch -D）")
+	removeCmd.Flags().Lookup("delete-branch").NoOptDefVal = string(worktree.DeleteBranchSafe)
+	removeCmd.Flags().BoolVar(&removeDeleteRemote, "delete-remote", false, "リモートブランチも削除します（git push <remote> --delete）")
+	removeCmd.Flags().BoolVar(&removeIKnow, "i-know", false, "未プッシュのコミットや stash などが失われても削除します")
+	removeCmd.Flags().StringSliceVar(&removeAllExcept, "all-except", nil, "指定したもの以外のすべての worktree を削除します（ブランチ名・パス・パターン）")
+	removeCmd.Flags().BoolVarP(&removeYes, "yes", "y", false, "複数削除の確認をスキップします")
+	removeCmd.Flags().IntVarP(&removeJobs, "jobs", "j", 4, "並列に削除する数")
 }
 
 func runRemove(cmd *cobra.Command, args []string) error {
-	pathOrBranch := args[0]
+	ctx := cmd.Context()
+	repoRoot, err := repoRootOf(ctx, removeRepo)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(repoRoot)
+	if err != nil {
+		return err
+	}
+
+	layout, err := layoutOption(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	hooks, err := hookConfig(cfg, repoRoot)
+	if err != nil {
+		return err
+	}
+
+	deleteBranch, err := worktree.ParseBranchDeletion(stringOption(cmd, "delete-branch", cfg, "remove.delete-branch"))
+	if err != nil {
+		return fmt.Errorf("clove: %w", err)
+	}
+
+	safety, err := safetyOptions(cfg)
+	if err != nil {
+		return err
+	}
+
+	opts := worktree.RemoveOptions{
+		Force:        boolOption(cmd, "force", cfg, "remove.force"),
+		DryRun:       boolOption(cmd, "dry-run", cfg, "remove.dry-run"),
+		DeleteBranch: deleteBranch,
+		DeleteRemote: boolOption(cmd, "delete-remote", cfg, "remove.delete-remote"),
+		IKnow:        removeIKnow,
+		Safety:       safety,
+		Hooks:        hooks,
+		Layout:       layout,
+	}
 
-	repoRoot := removeRepo
-	if repoRoot == "" {
-		var err error
-		repoRoot, err = git.GetRepoRoot()
+	var allExcept []string
+	if cmd.Flags().Changed("all-except") {
+		if len(args) > 0 {
+			return fmt.Errorf("clove: --all-except と削除対象は同時に指定できません")
+		}
+		allExcept = append([]string{}, removeAllExcept...)
+	}
+
+	// 1 件だけの指定は従来どおり直接削除する
+	if len(args) == 1 && !worktree.IsPattern(args[0]) {
+		opts.PathOrBranch = args[0]
+		res, err := worktree.Remove(ctx, repoRoot, opts)
+		return finish("remove", res, err)
+	}
+
+	var targets []worktree.WorktreeInfo
+	picked := len(args) == 0 && allExcept == nil
+	if picked {
+		statuses, err := pickWorktrees(ctx, repoRoot, "rm>", true, false)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			targets = append(targets, st.WorktreeInfo)
+		}
+	} else {
+		targets, err = worktree.ResolveTargets(ctx, repoRoot, args, allExcept)
 		if err != nil {
-			return fmt.Errorf("clove: %w", err)
+			return finish("remove", nil, fmt.Errorf("clove: %w", err))
 		}
 	}
 
-	opts := worktree.RemoveOptions{
-		PathOrBranch: pathOrBranch,
-		Force:        removeForce,
-		DryRun:       removeDryRun,
+	results := []*worktree.RemoveResult{}
+	if len(targets) == 0 {
+		util.Info("削除対象の worktree はありません")
+		return finish("remove", results, nil)
 	}
 
-	return worktree.Remove(repoRoot, opts)
+	util.Info("削除対象の worktree (%d 件):", len(targets))
+	worktree.PrintTargets(targets)
+	if !picked && !opts.DryRun && !removeYes {
+		ok, err := confirm(fmt.Sprintf("%d 件の worktree を削除します。よろしいですか？", len(targets)))
+		if err != nil {
+			return finish("remove", results, err)
+		}
+		if !ok {
+			return finish("remove", results, fmt.Errorf("clove: 中止しました"))
+		}
+	}
+
+	results, err = worktree.RemoveMany(ctx, repoRoot, targets, opts, removeJobs)
+	worktree.PrintRemoveReport(results)
+	if err != nil {
+		err = fmt.Errorf("clove: %w", err)
+	}
+	return finish("remove", results, err)
 }
@@ -1,12 +1,21 @@
 package cmd
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/manattan/clove/internal/output"
 	"github.com/manattan/clove/internal/util"
 	"github.com/spf13/cobra"
 )
 
 var (
 	globalVerbose bool
+	globalNoHooks bool
+	globalOutput  string
+	globalTimeout string
 )
 
 var rootCmd = &cobra.Command{
@@ -19,9 +28,17 @@ var rootCmd = &cobra.Command{
 
 サブコマンド:
   add <ブランチ名>     worktree を作成し、指定ブランチをチェックアウトします
+  pr <番号>           プルリクエストを取得して worktree を作成します
+  clone <URL>         bare リポジトリ + worktree の構成でクローンします
+  convert             既存のクローンを bare リポジトリ + worktree の構成に変換します
   list                worktree の一覧を表示します
   prune               削除済み worktree の参照等を掃除します
+  gc                  マージ済み・放置された worktree をまとめて削除します
   rm <パス|ブランチ>  worktree を削除します（パス指定 or ブランチ名指定）
+  switch <ブランチ>   worktree へ移動します（要 shell-init）
+  shell-init <シェル> clove switch 用のシェル関数を出力します
+  open <ブランチ>     worktree をエディタなどで開きます
+  config              設定値を表示・変更します
   help                このヘルプを表示します
 
 例:
@@ -33,29 +50,73 @@ var rootCmd = &cobra.Command{
   clove prune --dry-run
   clove rm ../hogehoge-feature-update
   clove rm feature/update
+  clove switch feature/update
+  clove pr 123
 
 各サブコマンドの詳細:
   clove add   -h
+  clove pr    -h
+  clove clone -h
+  clove convert -h
   clove list  -h
   clove prune -h
-  clove rm    -h`,
+  clove gc    -h
+  clove rm    -h
+  clove switch -h
+  clove open  -h
+  clove config -h`,
 	SilenceUsage:  true,
 	SilenceErrors: true,
 }
 
-// Execute runs the root command
+// Execute runs the root command.
+// With --output json/yaml, errors are also written as a document.
+// The first Ctrl-C (or SIGTERM) cancels the running command so it can clean up;
+// a second one terminates clove immediately.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	c, err := rootCmd.ExecuteContextC(ctx)
+	if err != nil && output.IsStructured() && !output.IsReported(err) {
+		return output.Emit(c.Name(), nil, err)
+	}
+	return err
 }
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&globalVerbose, "verbose", "v", true, "詳細なログを出力します")
-	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+	rootCmd.PersistentFlags().BoolVar(&globalNoHooks, "no-hooks", false, "post-add / pre-remove などの hook を実行しません")
+	rootCmd.PersistentFlags().StringVarP(&globalOutput, "output", "o", "text", "出力形式（text / json / yaml）")
+	rootCmd.PersistentFlags().StringVar(&globalTimeout, "timeout", "", "git コマンド1つあたりのタイムアウト（例: 30s、0 で無制限。既定は設定 git.timeout）")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
 		util.SetVerbose(globalVerbose)
+		f, err := output.ParseFormat(globalOutput)
+		if err != nil {
+			return err
+		}
+		output.SetFormat(f)
+		if output.IsStructured() {
+			// 標準出力は構造化出力専用にし、人間向けのメッセージは標準エラー出力に回す
+			util.SetOutput(os.Stderr)
+		}
+		return nil
 	}
 
 	rootCmd.AddCommand(addCmd)
+	rootCmd.AddCommand(prCmd)
+	rootCmd.AddCommand(cloneCmd)
+	rootCmd.AddCommand(convertCmd)
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(pruneCmd)
+	rootCmd.AddCommand(gcCmd)
 	rootCmd.AddCommand(removeCmd)
+	rootCmd.AddCommand(switchCmd)
+	rootCmd.AddCommand(shellInitCmd)
+	rootCmd.AddCommand(openCmd)
+	rootCmd.AddCommand(configCmd)
 }
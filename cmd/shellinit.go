@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/manattan/clove/internal/shell"
+	"github.com/spf13/cobra"
+)
+
+var shellInitCmd = &cobra.Command{
+	Use:       "shell-init <" + strings.Join(shell.Names(), "|") + ">",
+	Short:     "clove switch でディレクトリを移動するためのシェル関数を出力します",
+	ValidArgs: shell.Names(),
+	Long: `clove switch で親シェルのカレントディレクトリを移動できるようにする
+シェル関数を出力します。シェルの設定ファイルに次の行を追加してください:
+
+  # ~/.bashrc
+  eval "$(clove shell-init bash)"
+
+  # ~/.zshrc
+  eval "$(clove shell-init zsh)"
+
+  # ~/.config/fish/config.fish
+  clove shell-init fish | source`,
+	Args: cobra.ExactArgs(1),
+	RunE: runShellInit,
+}
+
+func runShellInit(cmd *cobra.Command, args []string) error {
+	script, err := shell.Init(args[0])
+	if err != nil {
+		return fmt.Errorf("clove: %w", err)
+	}
+	fmt.Print(script)
+	return nil
+}
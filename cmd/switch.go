@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/manattan/clove/internal/output"
+	"github.com/manattan/clove/internal/shell"
+	"github.com/manattan/clove/internal/util"
+	"github.com/manattan/clove/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var switchCmd = &cobra.Command{
+	Use:     "switch [オプション] [ブランチ名|検索語]",
+	Aliases: []string{"cd"},
+	Short:   "worktree のパスを解決し、シェル連携があればそこへ移動します",
+	Long: `ブランチ名（完全一致）、worktree のパス、またはブランチ名・ディレクトリ名の
+あいまい検索で worktree を探し、そのパスを標準出力に出力します。
+端末で引数を省略した場合は、対話的に worktree を選択できます。
+
+親シェルのカレントディレクトリを変えるには、シェルの設定ファイルで
+clove shell-init の出力を読み込んでください:
+  eval "$(clove shell-init bash)"   # ~/.bashrc
+  eval "$(clove shell-init zsh)"    # ~/.zshrc
+  clove shell-init fish | source    # ~/.config/fish/config.fish
+
+例:
+  clove switch feature/update
+  clove switch upd        # feature/update にあいまい一致
+  clove switch            # 一覧から選択
+  clove switch -c feature/new   # なければ worktree を作成してから移動`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSwitch,
+}
+
+var (
+	switchRepo    string
+	switchCreate  bool
+	switchBase    string
+	switchRemote  string
+	switchNoFetch bool
+)
+
+func init() {
+	switchCmd.Flags().StringVar(&switchRepo, "repo", "", "対象リポジトリのパス（省略時: カレントから判定）")
+	switchCmd.Flags().BoolVarP(&switchCreate, "create", "c", false, "ブランチ名かパスが完全に一致する worktree がなければ clove add と同じ設定で作成します（あいまい検索はしません）")
+	switchCmd.Flags().StringVar(&switchBase, "base", "", "--create で作成するときの起点 ref")
+	switchCmd.Flags().StringVar(&switchRemote, "remote", "", "--create で作成するときにブランチを探すリモート")
+	switchCmd.Flags().BoolVar(&switchNoFetch, "no-fetch", false, "--create で作成するときに git fetch origin をスキップします")
+}
+
+func runSwitch(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	// 標準出力は移動先のパス専用にする
+	util.SetOutput(os.Stderr)
+
+	repoRoot, err := repoRootOf(ctx, switchRepo)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(repoRoot)
+	if err != nil {
+		return err
+	}
+
+	layout, err := layoutOption(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		picked, err := pickWorktrees(ctx, repoRoot, "switch>", false, true)
+		if err != nil {
+			return err
+		}
+		args = []string{picked[0].Path}
+	}
+	query := args[0]
+
+	res := &worktree.SwitchResult{Repo: repoRoot, Query: query}
+	create := boolOption(cmd, "create", cfg, "switch.create")
+	var wt worktree.WorktreeInfo
+	if create {
+		// 作成するときは曖昧一致で別の worktree に移動しないよう、ブランチ名かパスの完全一致だけを見る
+		wt, err = worktree.ResolveExact(ctx, repoRoot, query)
+	} else {
+		wt, err = worktree.Resolve(ctx, repoRoot, query, layout)
+	}
+	switch {
+	case err == nil:
+		res.Path = wt.Path
+		res.Branch = wt.Branch
+	case errors.Is(err, worktree.ErrNotFound) && create:
+		util.Verbose("[verbose] %s の worktree が見つからないため作成します", query)
+		opts, err := addOptions(cmd, cfg, repoRoot, query)
+		if err != nil {
+			return err
+		}
+		added, err := worktree.Add(ctx, repoRoot, opts)
+		if err != nil {
+			return finish("switch", res, fmt.Errorf("clove: %w", err))
+		}
+		res.Path = added.Path
+		res.Branch = "refs/heads/" + added.Branch
+		res.Created = !added.DryRun
+	case errors.Is(err, worktree.ErrNotFound):
+		return finish("switch", res, fmt.Errorf("clove: worktree が見つかりません: %s（--create で作成できます）", query))
+	default:
+		return finish("switch", res, fmt.Errorf("clove: %w", err))
+	}
+
+	if output.IsStructured() {
+		return finish("switch", res, nil)
+	}
+
+	fmt.Println(res.Path)
+	if os.Getenv(shell.IntegrationEnv) == "" && util.IsTerminal(os.Stdout) {
+		util.Info("ヒント: clove shell-init を読み込むと clove switch でディレクトリを移動できます（clove shell-init -h）")
+	}
+	return nil
+}
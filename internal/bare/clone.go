@@ -0,0 +1,226 @@
+// Package bare sets up the "bare repository + worktrees" layout:
+//
+//	<dir>/.bare      the bare repository
+//	<dir>/.git       a file containing "gitdir: ./.bare"
+//	<dir>/<branch>   one worktree per branch
+package bare
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/manattan/clove/internal/config"
+	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/util"
+	"github.com/manattan/clove/internal/worktree"
+)
+
+// Dir is the name of the bare repository inside the container directory
+const Dir = ".bare"
+
+// Layout is the layout.path written to new containers: worktrees go directly in the container
+const Layout = "{branch}"
+
+// gitFile is the content of <dir>/.git
+const gitFile = "gitdir: ./" + Dir + "\n"
+
+// CloneOptions contains options for Clone
+type CloneOptions struct {
+	URL string
+	// Dir is the container directory; "" uses the repository name of URL
+	Dir string
+	// Remote names the remote (default: origin)
+	Remote string
+	// Layout is written to the container's .clove.toml as layout.path; "" leaves it unset
+	Layout string
+	DryRun bool
+}
+
+// CloneResult describes the outcome of Clone
+type CloneResult struct {
+	URL    string `json:"url"`
+	Dir    string `json:"dir"`
+	Bare   string `json:"bare"`
+	Remote string `json:"remote"`
+	// DefaultBranch is the branch HEAD of the remote points to; empty for an empty repository
+	DefaultBranch string                   `json:"defaultBranch"`
+	DryRun        bool                     `json:"dryRun"`
+	Commands      []worktree.CommandResult `json:"commands"`
+	// Worktree is the worktree of the default branch, added by the caller
+	Worktree *worktree.AddResult `json:"worktree,omitempty"`
+	Warnings []string            `json:"warnings,omitempty"`
+}
+
+func (r *CloneResult) warn(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	util.Printf("警告: %s\n", msg)
+	r.Warnings = append(r.Warnings, msg)
+}
+
+// run executes a git command and records it
+func (r *CloneResult) run(ctx context.Context, args ...string) error {
+	cr := worktree.CommandResult{Args: args}
+	util.Verbose("[verbose] 実行中: %s", util.ShellJoin(args))
+	err := git.Run(ctx, args[0], args[1:]...)
+	if err != nil {
+		cr.Error = err.Error()
+	}
+	r.Commands = append(r.Commands, cr)
+	return err
+}
+
+// RepoName derives a directory name from a clone URL the way git clone does,
+// e.g. "https://example.com/org/app.git" -> "app"
+func RepoName(url string) string {
+	s := strings.TrimRight(url, "/\\")
+	s = strings.TrimSuffix(s, "/.git")
+	if i := strings.LastIndexAny(s, "/\\:"); i >= 0 {
+		s = s[i+1:]
+	}
+	s = strings.TrimSuffix(s, ".git")
+	if s == "" {
+		return "repo"
+	}
+	return s
+}
+
+// Clone clones URL as a bare repository into <dir>/.bare, points <dir>/.git at it and
+// configures remote-tracking branches, so that worktrees can be added from <dir>.
+// Only the default branch is kept as a local branch.
+func Clone(ctx context.Context, opts CloneOptions) (*CloneResult, error) {
+	remote := opts.Remote
+	if remote == "" {
+		remote = "origin"
+	}
+	dir := opts.Dir
+	if dir == "" {
+		dir = RepoName(opts.URL)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	bareDir := filepath.Join(dir, Dir)
+	res := &CloneResult{URL: opts.URL, Dir: dir, Bare: bareDir, Remote: remote, DryRun: opts.DryRun, Commands: []worktree.CommandResult{}}
+
+	entries, err := os.ReadDir(dir)
+	existed := err == nil
+	if existed && len(entries) > 0 {
+		return res, fmt.Errorf("作成先ディレクトリが空ではありません: %s", dir)
+	}
+
+	actions := [][]string{
+		{"git", "clone", "--bare", "--origin", remote, opts.URL, bareDir},
+		// bare クローンは fetch の refspec を持たないため、リモート追跡ブランチを取得するよう設定する
+		{"git", "-C", bareDir, "config", "remote." + remote + ".fetch", "+refs/heads/*:refs/remotes/" + remote + "/*"},
+		// bare リポジトリでは既定で reflog を残さないため、worktree のブランチの reflog を有効にする
+		{"git", "-C", bareDir, "config", "core.logAllRefUpdates", "true"},
+		{"git", "-C", bareDir, "fetch", "--prune", remote},
+	}
+	setHead := []string{"git", "-C", bareDir, "remote", "set-head", remote, "--auto"}
+
+	util.Printf("url:    %s\n", opts.URL)
+	util.Printf("dir:    %s\n", dir)
+
+	if opts.DryRun {
+		res.DefaultBranch = git.LsRemoteHead(ctx, "", opts.URL)
+		util.Printf("branch: %s\n", orDash(res.DefaultBranch))
+		util.Printf("\n(dry-run) 実行予定コマンド:\n")
+		for _, a := range append(actions, setHead) {
+			util.Printf("  %s\n", util.ShellJoin(a))
+			res.Commands = append(res.Commands, worktree.CommandResult{Args: a})
+		}
+		util.Printf("\n(dry-run) %s に %q を書き込みます\n", filepath.Join(dir, ".git"), strings.TrimSpace(gitFile))
+		if opts.Layout != "" {
+			util.Printf("(dry-run) %s に layout.path = %s を書き込みます\n", filepath.Join(dir, config.RepoFileNames[0]), opts.Layout)
+		}
+		util.Printf("(dry-run) 既定ブランチ以外のローカルブランチを削除し、既定ブランチの worktree を作成します\n")
+		return res, nil
+	}
+
+	// 失敗・中断したら作りかけのディレクトリを片付ける
+	abort := func(err error) (*CloneResult, error) {
+		util.Info("クローンに失敗したため、作成途中のファイルを削除します: %s", dir)
+		if existed {
+			for _, p := range []string{bareDir, filepath.Join(dir, ".git"), filepath.Join(dir, config.RepoFileNames[0])} {
+				if err := os.RemoveAll(p); err != nil {
+					res.warn("%s を削除できませんでした: %v", p, err)
+				}
+			}
+		} else if err := os.RemoveAll(dir); err != nil {
+			res.warn("%s を削除できませんでした: %v", dir, err)
+		}
+		return res, err
+	}
+
+	for _, a := range actions {
+		if err := res.run(ctx, a...); err != nil {
+			return abort(err)
+		}
+	}
+	if err := res.run(ctx, setHead...); err != nil {
+		if ctx.Err() != nil {
+			return abort(err)
+		}
+		// 空のリポジトリでは HEAD の指すブランチがまだない
+		res.warn("%s/HEAD を設定できませんでした: %v", remote, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".git"), []byte(gitFile), 0o644); err != nil {
+		return abort(err)
+	}
+	if opts.Layout != "" {
+		if err := config.Set(filepath.Join(dir, config.RepoFileNames[0]), "layout.path", opts.Layout); err != nil {
+			return abort(err)
+		}
+	}
+
+	if err := pruneLocalBranches(ctx, res); err != nil {
+		return abort(err)
+	}
+	return res, nil
+}
+
+// pruneLocalBranches deletes the local branches copied by the bare clone except the
+// default one, which is set to track the remote. The others stay available as
+// remote-tracking branches, so `clove add` creates them with an upstream.
+func pruneLocalBranches(ctx context.Context, res *CloneResult) error {
+	out, err := git.Git(ctx, res.Bare, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return err
+	}
+	head := strings.TrimSpace(out)
+
+	out, err = git.Git(ctx, res.Bare, "for-each-ref", "--format=%(refname)", "refs/heads")
+	if err != nil {
+		return err
+	}
+	var del strings.Builder
+	for _, ref := range strings.Fields(out) {
+		if ref != "refs/heads/"+head {
+			fmt.Fprintf(&del, "delete %s\n", ref)
+		}
+	}
+	if del.Len() > 0 {
+		util.Verbose("[verbose] ローカルブランチを削除します:\n%s", del.String())
+		if _, err := git.GitInput(ctx, res.Bare, del.String(), "update-ref", "--stdin"); err != nil {
+			return err
+		}
+	}
+
+	if !git.RefExists(ctx, res.Bare, "refs/heads/"+head) {
+		res.warn("リモートが空のため worktree は作成しません（ブランチ %s はまだありません）", head)
+		return nil
+	}
+	res.DefaultBranch = head
+	return res.run(ctx, "git", "-C", res.Bare, "branch", "--set-upstream-to="+res.Remote+"/"+head, head)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
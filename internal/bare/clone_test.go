@@ -0,0 +1,224 @@
+package bare
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/worktree"
+)
+
+// initRemote creates a repository with main, feature/x and dev to clone from
+func initRemote(t *testing.T) string {
+	t.Helper()
+	t.Setenv("GIT_AUTHOR_NAME", "clove")
+	t.Setenv("GIT_AUTHOR_EMAIL", "clove@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "clove")
+	t.Setenv("GIT_COMMITTER_EMAIL", "clove@example.com")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	tmp, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(tmp, "src")
+	ctx := context.Background()
+	if _, err := git.Git(ctx, "", "init", "-q", "-b", "main", src); err != nil {
+		t.Skipf("git init failed: %v", err)
+	}
+	for _, args := range [][]string{
+		{"commit", "-q", "--allow-empty", "-m", "initial"},
+		{"branch", "feature/x"},
+		{"branch", "dev"},
+	} {
+		if _, err := git.Git(ctx, src, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return src
+}
+
+func TestRepoName(t *testing.T) {
+	tests := []struct {
+		url, want string
+	}{
+		{"https://github.com/manattan/clove.git", "clove"},
+		{"https://github.com/manattan/clove", "clove"},
+		{"git@github.com:manattan/clove.git", "clove"},
+		{"file:///tmp/src/", "src"},
+		{"/tmp/src/.git", "src"},
+		{"../app.git", "app"},
+		{"host:app", "app"},
+		{"/", "repo"},
+	}
+	for _, tt := range tests {
+		if got := RepoName(tt.url); got != tt.want {
+			t.Errorf("RepoName(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	src := initRemote(t)
+	ctx := context.Background()
+	dir := filepath.Join(filepath.Dir(src), "app")
+
+	res, err := Clone(ctx, CloneOptions{URL: "file://" + src, Dir: dir, Layout: Layout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.DefaultBranch != "main" || res.Bare != filepath.Join(dir, ".bare") {
+		t.Errorf("result = %+v", res)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, ".git")); err != nil || string(data) != "gitdir: ./.bare\n" {
+		t.Errorf(".git = %q, %v", data, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, ".clove.toml")); err != nil || !strings.Contains(string(data), `path = "{branch}"`) {
+		t.Errorf(".clove.toml = %q, %v", data, err)
+	}
+
+	for args, want := range map[string]string{
+		"config --get remote.origin.fetch":              "+refs/heads/*:refs/remotes/origin/*",
+		"symbolic-ref --short refs/remotes/origin/HEAD": "origin/main",
+		"for-each-ref --format=%(refname) refs/heads":   "refs/heads/main",
+		"rev-parse --abbrev-ref main@{upstream}":        "origin/main",
+		"config --bool core.bare":                       "true",
+		"config --bool core.logAllRefUpdates":           "true",
+	} {
+		out, err := git.Git(ctx, dir, strings.Fields(args)...)
+		if err != nil || strings.TrimSpace(out) != want {
+			t.Errorf("git %s = %q, %v; want %q", args, out, err, want)
+		}
+	}
+	if !git.RefExists(ctx, dir, "refs/remotes/origin/feature/x") {
+		t.Error("origin/feature/x should be fetched")
+	}
+
+	repo, err := git.OpenRepository(ctx, dir)
+	if err != nil || repo.Main != dir || !repo.Bare {
+		t.Fatalf("OpenRepository = %+v, %v", repo, err)
+	}
+
+	// 既定ブランチと、リモートにあるブランチの worktree をコンテナの中に作れる
+	layout := worktree.Layout{Template: Layout}
+	for _, branch := range []string{"main", "feature/x"} {
+		added, err := worktree.Add(ctx, dir, worktree.AddOptions{Branch: branch, NoFetch: true, Layout: layout})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := filepath.Join(dir, branch); added.Path != want {
+			t.Errorf("path = %q, want %q", added.Path, want)
+		}
+	}
+	if out, err := git.Git(ctx, dir, "rev-parse", "--abbrev-ref", "feature/x@{upstream}"); err != nil || strings.TrimSpace(out) != "origin/feature/x" {
+		t.Errorf("upstream of feature/x = %q, %v", out, err)
+	}
+	// worktree のブランチにも reflog が残る
+	if out, err := git.Git(ctx, dir, "reflog", "show", "refs/heads/feature/x", "--"); err != nil || strings.TrimSpace(out) == "" {
+		t.Errorf("feature/x should have a reflog: %q, %v", out, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, ".bare", "info", "exclude")); strings.Contains(string(data), "/main/") {
+		t.Errorf("info/exclude of a bare repository should not be touched: %q", data)
+	}
+
+	// 既定ブランチの worktree は gc の対象にならない
+	plan, err := worktree.PlanGC(ctx, dir, worktree.GCOptions{Merged: true, Gone: true, Layout: layout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range plan.Candidates {
+		if c.Branch == "main" {
+			t.Errorf("gc should keep the default branch: %+v", c)
+		}
+	}
+}
+
+func TestClone_CarriesFromDefaultWorktree(t *testing.T) {
+	src := initRemote(t)
+	ctx := context.Background()
+	dir := filepath.Join(filepath.Dir(src), "app")
+	if _, err := Clone(ctx, CloneOptions{URL: "file://" + src, Dir: dir, Layout: Layout}); err != nil {
+		t.Fatal(err)
+	}
+	layout := worktree.Layout{Template: Layout}
+	if _, err := worktree.Add(ctx, dir, worktree.AddOptions{Branch: "main", NoFetch: true, Layout: layout}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 既定ブランチの worktree にだけ .env と node_modules がある
+	main := filepath.Join(dir, "main")
+	for name, data := range map[string]string{
+		".env":                      "SECRET=1\n",
+		"package.json":              "{}\n",
+		"package-lock.json":         "{}\n",
+		"node_modules/dep/index.js": "module.exports = 1\n",
+	} {
+		p := filepath.Join(main, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := worktree.AddOptions{Branch: "feature/x", NoFetch: true, Layout: layout, Copy: []string{".env"}, CopyMode: worktree.CopyModeCopy}
+	added, err := worktree.Add(ctx, dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{".env", "node_modules/dep/index.js"} {
+		if _, err := os.Stat(filepath.Join(added.Path, name)); err != nil {
+			t.Errorf("%s should be carried from the default branch worktree: %v", name, err)
+		}
+	}
+	if len(added.Bootstrap) != 1 || added.Bootstrap[0].Name != "node" {
+		t.Errorf("bootstrap = %+v, want node", added.Bootstrap)
+	}
+}
+
+func TestClone_Errors(t *testing.T) {
+	src := initRemote(t)
+	ctx := context.Background()
+	tmp := filepath.Dir(src)
+
+	// 空でないディレクトリには作らない
+	if _, err := Clone(ctx, CloneOptions{URL: src, Dir: src}); err == nil {
+		t.Error("Clone into a non-empty directory should fail")
+	}
+
+	// 失敗したら作ったディレクトリを消す
+	dir := filepath.Join(tmp, "missing")
+	if _, err := Clone(ctx, CloneOptions{URL: filepath.Join(tmp, "no-such-repo"), Dir: dir}); err == nil {
+		t.Error("Clone of a missing repository should fail")
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("%s should be removed: %v", dir, err)
+	}
+
+	// 既にある空のディレクトリは残す
+	empty := filepath.Join(tmp, "empty")
+	if err := os.Mkdir(empty, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Clone(ctx, CloneOptions{URL: filepath.Join(tmp, "no-such-repo"), Dir: empty}); err == nil {
+		t.Error("Clone of a missing repository should fail")
+	}
+	if entries, err := os.ReadDir(empty); err != nil || len(entries) != 0 {
+		t.Errorf("%s should be kept empty: %v, %v", empty, entries, err)
+	}
+
+	// dry-run は何も作らない
+	dry := filepath.Join(tmp, "dry")
+	res, err := Clone(ctx, CloneOptions{URL: src, Dir: dry, DryRun: true})
+	if err != nil || res.DefaultBranch != "main" || len(res.Commands) == 0 {
+		t.Errorf("dry-run = %+v, %v", res, err)
+	}
+	if _, err := os.Stat(dry); !os.IsNotExist(err) {
+		t.Errorf("dry-run should not create %s: %v", dry, err)
+	}
+}
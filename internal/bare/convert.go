@@ -0,0 +1,869 @@
+package bare
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/manattan/clove/internal/clone"
+	"github.com/manattan/clove/internal/config"
+	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/util"
+	"github.com/manattan/clove/internal/worktree"
+)
+
+// manifestFile records a conversion in the bare repository so that it can be undone
+const manifestFile = "clove-convert.json"
+
+// stagingDir temporarily holds the files of the main worktree while they are moved
+const stagingDir = ".clove-convert"
+
+// perWorktreeFiles live in the git directory of each worktree rather than the common one
+var perWorktreeFiles = []string{"index", "ORIG_HEAD", "logs/HEAD", "info/sparse-checkout", "config.worktree"}
+
+// inProgress are the files git leaves while an operation waits for the user
+var inProgress = []string{"MERGE_HEAD", "CHERRY_PICK_HEAD", "REVERT_HEAD", "BISECT_LOG", "rebase-merge", "rebase-apply"}
+
+// ConvertOptions contains options for Convert
+type ConvertOptions struct {
+	// Layout places the main worktree and is written to .clove.toml as layout.path;
+	// "" places it with Layout and writes nothing
+	Layout   string
+	DryRun   bool
+	NoBackup bool
+}
+
+// ConvertResult describes the outcome of Convert and Revert
+type ConvertResult struct {
+	Repo string `json:"repo"`
+	Bare string `json:"bare"`
+	// Worktree is where the files of the main worktree are (after Convert) or were (before Revert)
+	Worktree string `json:"worktree"`
+	Branch   string `json:"branch,omitempty"`
+	Backup   string `json:"backup,omitempty"`
+	// Relinked lists the linked worktrees whose git directory was re-pointed
+	Relinked   []string `json:"relinked,omitempty"`
+	DryRun     bool     `json:"dryRun"`
+	Undo       bool     `json:"undo,omitempty"`
+	Steps      []string `json:"steps"`
+	RolledBack bool     `json:"rolledBack,omitempty"`
+	Warnings   []string `json:"warnings,omitempty"`
+}
+
+func (r *ConvertResult) warn(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	util.Printf("警告: %s\n", msg)
+	r.Warnings = append(r.Warnings, msg)
+}
+
+// manifest is stored as <bare>/clove-convert.json
+type manifest struct {
+	Root     string `json:"root"`
+	Worktree string `json:"worktree"`
+	// Admin is the name of the main worktree under <bare>/worktrees
+	Admin  string `json:"admin"`
+	Backup string `json:"backup,omitempty"`
+	// Config is the .clove.toml written by Convert, if any
+	Config string `json:"config,omitempty"`
+	// LogRefs is set when Convert enabled core.logAllRefUpdates
+	LogRefs   bool      `json:"logRefs,omitempty"`
+	Converted time.Time `json:"converted"`
+}
+
+// step is one reversible change
+type step struct {
+	desc string
+	do   func() error
+	undo func() error
+}
+
+// runSteps runs steps in order. When one fails or ctx is cancelled, the steps
+// already done are undone in reverse order.
+func runSteps(ctx context.Context, res *ConvertResult, steps []step) error {
+	for i, st := range steps {
+		err := ctx.Err()
+		if err != nil {
+			err = fmt.Errorf("変換は中断されました: %w", err)
+		} else {
+			util.Verbose("[verbose] %s", st.desc)
+			err = st.do()
+		}
+		if err == nil {
+			res.Steps = append(res.Steps, st.desc)
+			continue
+		}
+		util.Info("失敗したため、ここまでの変更を元に戻します: %v", err)
+		for j := i - 1; j >= 0; j-- {
+			if steps[j].undo == nil {
+				continue
+			}
+			if uerr := steps[j].undo(); uerr != nil {
+				res.warn("%s を元に戻せませんでした: %v", steps[j].desc, uerr)
+			}
+		}
+		res.RolledBack = true
+		return err
+	}
+	return nil
+}
+
+// printPlan shows the steps of a dry run
+func printPlan(res *ConvertResult, steps []step) {
+	util.Printf("\n(dry-run) 実行予定:\n")
+	for _, st := range steps {
+		util.Printf("  %s\n", st.desc)
+		res.Steps = append(res.Steps, st.desc)
+	}
+}
+
+// Convert turns the clone containing dir into the bare repository + worktrees layout
+// in place: .git moves to .bare, the files of the main worktree move into a linked
+// worktree placed by the layout, and linked worktrees are re-pointed to .bare.
+// Uncommitted changes, stashes, hooks and config are kept; the result is checked
+// against `git status` and every change is rolled back on failure. Unless NoBackup
+// is set, .git is first copied next to the repository.
+func Convert(ctx context.Context, dir string, opts ConvertOptions) (*ConvertResult, error) {
+	repo, err := git.OpenRepository(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	root := repo.Main
+	gitDir := filepath.Join(root, ".git")
+	bareDir := filepath.Join(root, Dir)
+	res := &ConvertResult{Repo: root, Bare: bareDir, DryRun: opts.DryRun, Steps: []string{}}
+
+	if repo.Bare {
+		return res, fmt.Errorf("既に bare リポジトリです: %s", root)
+	}
+	if repo.CommonDir != gitDir {
+		return res, fmt.Errorf("%s が .git ディレクトリではないため変換できません: %s", filepath.Join(root, ".git"), repo.CommonDir)
+	}
+	if err := checkConvertible(ctx, root, gitDir); err != nil {
+		return res, err
+	}
+
+	// worktree の配置
+	name := "detached"
+	if out, err := git.Git(ctx, root, "symbolic-ref", "-q", "--short", "HEAD"); err == nil {
+		res.Branch = strings.TrimSpace(out)
+		name = res.Branch
+	}
+	layout := worktree.Layout{Template: opts.Layout}
+	if layout.Template == "" {
+		layout.Template = Layout
+	}
+	wt, err := layout.Path(root, name, git.DefaultRemote(ctx, root, ""), time.Now())
+	if err != nil {
+		return res, err
+	}
+	if rel, ok := within(root, wt); ok {
+		top, _, _ := strings.Cut(rel, string(filepath.Separator))
+		if top == ".git" || top == Dir || top == stagingDir || top == config.RepoFileNames[0] {
+			return res, fmt.Errorf("worktree の作成先がリポジトリの管理ファイルと重なります: %s", wt)
+		}
+	}
+	res.Worktree = wt
+	admin := adminName(gitDir, filepath.Base(wt))
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return res, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Name() != ".git" {
+			names = append(names, e.Name())
+		}
+	}
+
+	links, err := planLinks(gitDir, bareDir, func(p string) string {
+		if rel, ok := within(root, p); ok {
+			return filepath.Join(wt, rel)
+		}
+		return p
+	})
+	if err != nil {
+		return res, err
+	}
+	for _, l := range links {
+		res.Relinked = append(res.Relinked, filepath.Dir(l.gitFile))
+	}
+
+	m := manifest{Root: root, Worktree: wt, Admin: admin, Converted: time.Now()}
+	if !opts.NoBackup {
+		m.Backup = filepath.Join(filepath.Dir(root), filepath.Base(root)+".git-backup-"+m.Converted.Format("20060102-150405"))
+		res.Backup = m.Backup
+	}
+	var before state
+	staging := filepath.Join(root, stagingDir)
+	var parents []string
+	adminDir := filepath.Join(bareDir, "worktrees", admin)
+	steps := []step{
+		{
+			desc: fmt.Sprintf("作業ツリーのファイル（%d 件）を %s に移動", len(names), staging),
+			do:   func() error { return moveEntries(root, staging, names) },
+			undo: func() error {
+				if err := moveEntries(staging, root, names); err != nil {
+					return err
+				}
+				return os.Remove(staging)
+			},
+		},
+		{
+			desc: fmt.Sprintf("%s を %s に移動", gitDir, bareDir),
+			do:   func() error { return os.Rename(gitDir, bareDir) },
+			undo: func() error { return os.Rename(bareDir, gitDir) },
+		},
+		{
+			desc: fmt.Sprintf("%s を %s に移動", staging, wt),
+			do: func() error {
+				// 作業ツリーのファイルを移動した後なので、同じ名前のディレクトリがあっても衝突しない
+				parents = missingParents(filepath.Dir(wt))
+				if err := os.MkdirAll(filepath.Dir(wt), 0o755); err != nil {
+					return err
+				}
+				return os.Rename(staging, wt)
+			},
+			undo: func() error {
+				if err := os.Rename(wt, staging); err != nil {
+					return err
+				}
+				removeDirs(parents)
+				return nil
+			},
+		},
+		{
+			desc: fmt.Sprintf("%s を worktree %s として登録（index と HEAD を引き継ぐ）", wt, admin),
+			do:   func() error { return attachMain(bareDir, adminDir, wt) },
+			undo: func() error { return detachMain(bareDir, adminDir, wt) },
+		},
+		{
+			desc: "core.bare = true に設定",
+			do:   func() error { return setBare(ctx, bareDir, true) },
+			undo: func() error { return setBare(ctx, bareDir, false) },
+		},
+	}
+	// bare リポジトリでは既定で reflog が残らなくなるため、未設定なら有効にしておく
+	if !git.GitOk(ctx, "", "config", "--file", filepath.Join(gitDir, "config"), "core.logAllRefUpdates") {
+		m.LogRefs = true
+		steps = append(steps, step{
+			desc: "core.logAllRefUpdates = true に設定",
+			do:   func() error { return setLogRefs(ctx, bareDir, true) },
+			undo: func() error { return setLogRefs(ctx, bareDir, false) },
+		})
+	}
+	if len(links) > 0 {
+		steps = append(steps, step{
+			desc: fmt.Sprintf("linked worktree %d 件の参照を %s に更新", len(links), bareDir),
+			do:   func() error { return relink(links, false) },
+			undo: func() error { return relink(links, true) },
+		})
+	}
+	steps = append(steps, step{
+		desc: fmt.Sprintf("%s に %q を書き込む", filepath.Join(root, ".git"), strings.TrimSpace(gitFile)),
+		do:   func() error { return os.WriteFile(filepath.Join(root, ".git"), []byte(gitFile), 0o644) },
+		undo: func() error { return os.Remove(filepath.Join(root, ".git")) },
+	})
+	if opts.Layout != "" {
+		p := filepath.Join(root, config.RepoFileNames[0])
+		steps = append(steps, step{
+			desc: fmt.Sprintf("%s に layout.path = %s を書き込む", p, opts.Layout),
+			do: func() error {
+				if err := config.Set(p, "layout.path", opts.Layout); err != nil {
+					return err
+				}
+				data, err := os.ReadFile(p)
+				m.Config = string(data)
+				return err
+			},
+			undo: func() error { return os.Remove(p) },
+		})
+	}
+	steps = append(steps,
+		step{
+			desc: fmt.Sprintf("%s に変換の記録を書き込む（clove convert --undo で元に戻せます）", filepath.Join(bareDir, manifestFile)),
+			do:   func() error { return writeManifest(bareDir, m) },
+			undo: func() error { return os.Remove(filepath.Join(bareDir, manifestFile)) },
+		},
+		step{
+			desc: "git status と stash が変換前と同じことを確認",
+			do:   func() error { return verify(ctx, wt, before) },
+		},
+	)
+
+	util.Printf("repo:     %s\n", root)
+	util.Printf("bare:     %s\n", bareDir)
+	util.Printf("worktree: %s\n", wt)
+	if m.Backup != "" {
+		util.Printf("backup:   %s\n", m.Backup)
+	}
+
+	if opts.DryRun {
+		if m.Backup != "" {
+			util.Printf("\n(dry-run) %s を %s にバックアップします\n", gitDir, m.Backup)
+		}
+		printPlan(res, steps)
+		return res, nil
+	}
+
+	if before, err = snapshot(ctx, root); err != nil {
+		return res, err
+	}
+	if m.Backup != "" {
+		util.Info("%s をバックアップしています: %s", gitDir, m.Backup)
+		if err := backup(ctx, gitDir, m.Backup); err != nil {
+			return res, fmt.Errorf("バックアップに失敗しました: %w", err)
+		}
+	}
+	if err := runSteps(ctx, res, steps); err != nil {
+		return res, err
+	}
+	util.Info("\n変換しました。メインの worktree は %s です（元に戻すには clove convert --undo）", wt)
+	return res, nil
+}
+
+// Revert undoes Convert for the repository containing dir: the files of the
+// converted worktree move back to the repository directory and .bare becomes .git.
+// Changes made since the conversion (commits, stashes, uncommitted work) are kept.
+func Revert(ctx context.Context, dir string, dryRun bool) (*ConvertResult, error) {
+	repo, err := git.OpenRepository(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	root := repo.Main
+	bareDir := repo.CommonDir
+	gitDir := filepath.Join(root, ".git")
+	res := &ConvertResult{Repo: root, Bare: bareDir, DryRun: dryRun, Undo: true, Steps: []string{}}
+
+	data, err := os.ReadFile(filepath.Join(bareDir, manifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return res, fmt.Errorf("clove convert で変換したリポジトリではありません（%s がありません）", filepath.Join(bareDir, manifestFile))
+	}
+	if err != nil {
+		return res, err
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return res, fmt.Errorf("%s を読み込めません: %w", manifestFile, err)
+	}
+	if m.Root != root || bareDir != filepath.Join(root, Dir) {
+		return res, fmt.Errorf("変換した時からリポジトリが移動しています: %s -> %s", m.Root, root)
+	}
+	wt := m.Worktree
+	adminDir := filepath.Join(bareDir, "worktrees", m.Admin)
+	res.Worktree, res.Backup = wt, m.Backup
+	if _, err := os.Stat(adminDir); err != nil {
+		return res, fmt.Errorf("変換した worktree %s が見つかりません: %w", wt, err)
+	}
+	for _, name := range inProgress {
+		if _, err := os.Stat(filepath.Join(adminDir, name)); err == nil {
+			return res, fmt.Errorf("%s で進行中の操作があります（%s）", wt, name)
+		}
+	}
+	if out, err := git.Git(ctx, wt, "symbolic-ref", "-q", "--short", "HEAD"); err == nil {
+		res.Branch = strings.TrimSpace(out)
+	}
+
+	links, err := planLinks(bareDir, gitDir, func(p string) string {
+		if rel, ok := within(wt, p); ok {
+			return filepath.Join(root, rel)
+		}
+		return p
+	}, m.Admin)
+	if err != nil {
+		return res, err
+	}
+	for _, l := range links {
+		if _, ok := within(wt, l.gitFile); !ok {
+			if _, ok := within(root, l.gitFile); ok {
+				return res, fmt.Errorf("%s の中に他の worktree があるため元に戻せません（先に clove rm で削除してください）: %s", root, filepath.Dir(l.gitFile))
+			}
+		}
+		res.Relinked = append(res.Relinked, filepath.Dir(l.gitFile))
+	}
+
+	// コンテナには .bare、.git、変換時に書いた .clove.toml と worktree しか置けない
+	cfgPath := filepath.Join(root, config.RepoFileNames[0])
+	removeCfg := false
+	if cur, err := os.ReadFile(cfgPath); err == nil {
+		if m.Config == "" || string(cur) != m.Config {
+			return res, fmt.Errorf("%s が変換後に作成・変更されています。不要なら削除してから実行してください", cfgPath)
+		}
+		removeCfg = true
+	}
+	rel, _ := within(root, wt)
+	top, _, _ := strings.Cut(rel, string(filepath.Separator))
+	rootEntries, err := os.ReadDir(root)
+	if err != nil {
+		return res, err
+	}
+	for _, e := range rootEntries {
+		switch e.Name() {
+		case Dir, ".git", config.RepoFileNames[0], top:
+		default:
+			return res, fmt.Errorf("%s に %s があるため元に戻せません（移動するか削除してください）", root, e.Name())
+		}
+	}
+	entries, err := os.ReadDir(wt)
+	if err != nil {
+		return res, err
+	}
+	var names []string
+	for _, e := range entries {
+		switch e.Name() {
+		case ".git":
+		case Dir, stagingDir:
+			return res, fmt.Errorf("%s に %s があるため元に戻せません", wt, e.Name())
+		default:
+			names = append(names, e.Name())
+		}
+	}
+
+	before, err := snapshot(ctx, wt)
+	if err != nil {
+		return res, err
+	}
+
+	staging := filepath.Join(root, stagingDir)
+	parents := emptyParents(wt, root)
+	steps := []step{
+		{
+			desc: fmt.Sprintf("%s を削除", filepath.Join(root, ".git")),
+			do: func() error {
+				if removeCfg {
+					if err := os.Remove(cfgPath); err != nil {
+						return err
+					}
+				}
+				return os.Remove(gitDir)
+			},
+			undo: func() error {
+				if removeCfg {
+					if err := os.WriteFile(cfgPath, []byte(m.Config), 0o644); err != nil {
+						return err
+					}
+				}
+				return os.WriteFile(gitDir, []byte(gitFile), 0o644)
+			},
+		},
+		{
+			desc: fmt.Sprintf("worktree %s の index と HEAD を %s に戻す", m.Admin, bareDir),
+			do:   func() error { return detachMain(bareDir, adminDir, wt) },
+			undo: func() error { return attachMain(bareDir, adminDir, wt) },
+		},
+		{
+			desc: fmt.Sprintf("%s を %s に移動", wt, staging),
+			do: func() error {
+				if err := os.Rename(wt, staging); err != nil {
+					return err
+				}
+				removeDirs(parents)
+				return nil
+			},
+			undo: func() error {
+				if err := os.MkdirAll(filepath.Dir(wt), 0o755); err != nil {
+					return err
+				}
+				return os.Rename(staging, wt)
+			},
+		},
+		{
+			desc: fmt.Sprintf("作業ツリーのファイル（%d 件）を %s に戻す", len(names), root),
+			do: func() error {
+				if err := moveEntries(staging, root, names); err != nil {
+					return err
+				}
+				return os.Remove(staging)
+			},
+			undo: func() error { return moveEntries(root, staging, names) },
+		},
+		{
+			desc: fmt.Sprintf("%s を %s に移動", bareDir, gitDir),
+			do:   func() error { return os.Rename(bareDir, gitDir) },
+			undo: func() error { return os.Rename(gitDir, bareDir) },
+		},
+		{
+			desc: "core.bare = false に設定",
+			do:   func() error { return setBare(ctx, gitDir, false) },
+			undo: func() error { return setBare(ctx, gitDir, true) },
+		},
+	}
+	if m.LogRefs {
+		steps = append(steps, step{
+			desc: "変換時に設定した core.logAllRefUpdates を削除",
+			do:   func() error { return setLogRefs(ctx, gitDir, false) },
+			undo: func() error { return setLogRefs(ctx, gitDir, true) },
+		})
+	}
+	if len(links) > 0 {
+		steps = append(steps, step{
+			desc: fmt.Sprintf("linked worktree %d 件の参照を %s に更新", len(links), gitDir),
+			do:   func() error { return relink(links, false) },
+			undo: func() error { return relink(links, true) },
+		})
+	}
+	steps = append(steps,
+		step{
+			desc: "変換の記録を削除",
+			do:   func() error { return os.Remove(filepath.Join(gitDir, manifestFile)) },
+			undo: func() error { return writeManifest(gitDir, m) },
+		},
+		step{
+			desc: "git status と stash が元に戻す前と同じことを確認",
+			do:   func() error { return verify(ctx, root, before) },
+		},
+	)
+
+	util.Printf("repo:     %s\n", root)
+	util.Printf("worktree: %s\n", wt)
+	if dryRun {
+		printPlan(res, steps)
+		return res, nil
+	}
+	if err := runSteps(ctx, res, steps); err != nil {
+		return res, err
+	}
+	util.Info("\n元に戻しました: %s", root)
+	if m.Backup != "" {
+		util.Info("バックアップ %s は不要なら削除してください", m.Backup)
+	}
+	return res, nil
+}
+
+// checkConvertible rejects repositories whose state cannot be carried over
+func checkConvertible(ctx context.Context, root, gitDir string) error {
+	for _, name := range []string{Dir, stagingDir} {
+		if _, err := os.Lstat(filepath.Join(root, name)); err == nil {
+			return fmt.Errorf("%s が既にあります", filepath.Join(root, name))
+		}
+	}
+	for _, name := range inProgress {
+		if _, err := os.Stat(filepath.Join(gitDir, name)); err == nil {
+			return fmt.Errorf("進行中の操作があります（%s）。完了するか中止してから変換してください", name)
+		}
+	}
+	if entries, err := os.ReadDir(filepath.Join(gitDir, "modules")); err == nil && len(entries) > 0 {
+		// サブモジュールの .git ファイルは .git/modules を相対パスで指している
+		return errors.New("サブモジュールを含むリポジトリは変換できません")
+	}
+	if out, err := git.Git(ctx, root, "config", "--get", "core.worktree"); err == nil && strings.TrimSpace(out) != "" {
+		return fmt.Errorf("core.worktree が設定されたリポジトリは変換できません: %s", strings.TrimSpace(out))
+	}
+	return nil
+}
+
+// within returns path relative to dir when path is inside dir
+func within(dir, path string) (string, bool) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// adminName returns a free name under <gitDir>/worktrees, numbering it like git does
+func adminName(gitDir, base string) string {
+	name := base
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(filepath.Join(gitDir, "worktrees", name)); os.IsNotExist(err) {
+			return name
+		}
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+}
+
+// moveEntries renames the named entries of src into dst, creating dst.
+// On failure the entries already moved are put back.
+func moveEntries(src, dst string, names []string) error {
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return err
+	}
+	for i, name := range names {
+		if err := os.Rename(filepath.Join(src, name), filepath.Join(dst, name)); err != nil {
+			for _, done := range names[:i] {
+				_ = os.Rename(filepath.Join(dst, done), filepath.Join(src, done))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// missingParents returns dir and its ancestors that do not exist yet, deepest first
+func missingParents(dir string) []string {
+	var dirs []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Lstat(d); err == nil || filepath.Dir(d) == d {
+			return dirs
+		}
+		dirs = append(dirs, d)
+	}
+}
+
+// emptyParents returns the ancestors of path below root that only contain path, deepest first
+func emptyParents(path, root string) []string {
+	var dirs []string
+	for d := filepath.Dir(path); d != root; d = filepath.Dir(d) {
+		if _, ok := within(root, d); !ok {
+			break
+		}
+		entries, err := os.ReadDir(d)
+		if err != nil || len(entries) != 1 {
+			break
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs
+}
+
+// removeDirs removes the given directories in order, stopping at the first non-empty one
+func removeDirs(dirs []string) {
+	for _, d := range dirs {
+		if err := os.Remove(d); err != nil {
+			return
+		}
+	}
+}
+
+// attachMain moves the per-worktree state of the main worktree from commonDir into
+// adminDir and makes the directory wt a linked worktree using it
+func attachMain(commonDir, adminDir, wt string) error {
+	head, err := os.ReadFile(filepath.Join(commonDir, "HEAD"))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(adminDir, 0o755); err != nil {
+		return err
+	}
+	if err := moveFiles(commonDir, adminDir, perWorktreeFiles); err != nil {
+		return err
+	}
+	files := map[string]string{
+		filepath.Join(adminDir, "HEAD"):      string(head),
+		filepath.Join(adminDir, "gitdir"):    filepath.Join(wt, ".git") + "\n",
+		filepath.Join(adminDir, "commondir"): "../..\n",
+		filepath.Join(wt, ".git"):            "gitdir: " + adminDir + "\n",
+	}
+	for p, content := range files {
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// detachMain is the inverse of attachMain: the state of the linked worktree wt moves
+// back into commonDir as the main worktree's
+func detachMain(commonDir, adminDir, wt string) error {
+	head, err := os.ReadFile(filepath.Join(adminDir, "HEAD"))
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(commonDir, "HEAD"), head, 0o644); err != nil {
+		return err
+	}
+	if err := moveFiles(adminDir, commonDir, perWorktreeFiles); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(wt, ".git")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.RemoveAll(adminDir); err != nil {
+		return err
+	}
+	// 最後の worktree を外したら空の worktrees/ も片付ける
+	_ = os.Remove(filepath.Dir(adminDir))
+	return nil
+}
+
+// moveFiles renames the listed relative paths that exist in src into dst
+func moveFiles(src, dst string, rels []string) error {
+	for _, rel := range rels {
+		from := filepath.Join(src, rel)
+		if _, err := os.Lstat(from); err != nil {
+			continue
+		}
+		to := filepath.Join(dst, rel)
+		if err := os.MkdirAll(filepath.Dir(to), 0o755); err != nil {
+			return err
+		}
+		if err := os.Rename(from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setBare sets core.bare in the config of gitDir
+func setBare(ctx context.Context, gitDir string, bare bool) error {
+	_, err := git.Git(ctx, "", "config", "--file", filepath.Join(gitDir, "config"), "core.bare", fmt.Sprint(bare))
+	return err
+}
+
+// setLogRefs sets core.logAllRefUpdates to true in the config of gitDir, or unsets it
+func setLogRefs(ctx context.Context, gitDir string, on bool) error {
+	file := filepath.Join(gitDir, "config")
+	if on {
+		_, err := git.Git(ctx, "", "config", "--file", file, "core.logAllRefUpdates", "true")
+		return err
+	}
+	_, err := git.Git(ctx, "", "config", "--file", file, "--unset", "core.logAllRefUpdates")
+	return err
+}
+
+// link re-points a linked worktree at a moved common directory.
+// Paths are those after the move; the old contents are restored verbatim on undo.
+type link struct {
+	// adminGitdir is the <common>/worktrees/<name>/gitdir file
+	adminGitdir string
+	// gitFile is the .git file of the worktree
+	gitFile                string
+	oldGitdir, newGitdir   []byte
+	oldGitFile, newGitFile []byte
+	gitFileExists          bool
+}
+
+// planLinks prepares re-pointing the linked worktrees registered in oldCommon to
+// newCommon. move maps a worktree path to where it will be after the conversion.
+func planLinks(oldCommon, newCommon string, move func(string) string, skip ...string) ([]link, error) {
+	entries, err := os.ReadDir(filepath.Join(oldCommon, "worktrees"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var links []link
+	for _, e := range entries {
+		if !e.IsDir() || contains(skip, e.Name()) {
+			continue
+		}
+		oldAdmin := filepath.Join(oldCommon, "worktrees", e.Name())
+		data, err := os.ReadFile(filepath.Join(oldAdmin, "gitdir"))
+		if err != nil {
+			// gitdir のない管理ディレクトリは git worktree prune の対象なので触らない
+			continue
+		}
+		p := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(oldAdmin, p)
+		}
+		p = resolvePath(p)
+		newAdmin := filepath.Join(newCommon, "worktrees", e.Name())
+		l := link{
+			adminGitdir: filepath.Join(newAdmin, "gitdir"),
+			gitFile:     move(p),
+			oldGitdir:   data,
+			newGitdir:   []byte(move(p) + "\n"),
+			newGitFile:  []byte("gitdir: " + newAdmin + "\n"),
+		}
+		if old, err := os.ReadFile(p); err == nil {
+			l.oldGitFile, l.gitFileExists = old, true
+		}
+		links = append(links, l)
+	}
+	return links, nil
+}
+
+// relink writes the new (or, when undoing, the old) contents of each link
+func relink(links []link, undo bool) error {
+	for _, l := range links {
+		gitdir, gitFile := l.newGitdir, l.newGitFile
+		if undo {
+			gitdir, gitFile = l.oldGitdir, l.oldGitFile
+		}
+		if err := os.WriteFile(l.adminGitdir, gitdir, 0o644); err != nil {
+			return err
+		}
+		if !l.gitFileExists {
+			// 削除された worktree（prunable）は管理ディレクトリだけ更新する
+			continue
+		}
+		if err := os.WriteFile(l.gitFile, gitFile, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolvePath resolves symlinks in the directory part of p
+func resolvePath(p string) string {
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(p)); err == nil {
+		return filepath.Join(dir, filepath.Base(p))
+	}
+	return filepath.Clean(p)
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// state is what a conversion must not change
+type state struct {
+	status string
+	stash  string
+}
+
+// snapshot records the status and stashes seen from the worktree dir
+func snapshot(ctx context.Context, dir string) (state, error) {
+	status, err := git.Git(ctx, dir, "status", "--porcelain=v1", "-z", "--untracked-files=all")
+	if err != nil {
+		return state{}, err
+	}
+	stash, _ := git.Git(ctx, dir, "stash", "list", "--format=%H")
+	return state{status: status, stash: stash}, nil
+}
+
+// verify checks that the worktree dir shows the same state as before
+func verify(ctx context.Context, dir string, before state) error {
+	after, err := snapshot(ctx, dir)
+	if err != nil {
+		return err
+	}
+	if after.status != before.status {
+		return fmt.Errorf("git status の結果が変わりました:\n変換前: %q\n変換後: %q", before.status, after.status)
+	}
+	if after.stash != before.stash {
+		return errors.New("stash の一覧が変わりました")
+	}
+	return nil
+}
+
+// writeManifest records m in gitDir
+func writeManifest(gitDir string, m manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(gitDir, manifestFile), append(data, '\n'), 0o644)
+}
+
+// backup copies gitDir to dst, preferring copy-on-write clones. Hard links are not
+// used because the backup must not change with the repository.
+func backup(ctx context.Context, gitDir, dst string) error {
+	stats, err := clone.Tree(ctx, gitDir, dst, clone.ModeReflink, clone.Options{})
+	if err != nil && ctx.Err() == nil {
+		util.Verbose("[verbose] reflink できないためコピーします: %v", err)
+		if err := os.RemoveAll(dst); err != nil {
+			return err
+		}
+		stats, err = clone.Tree(ctx, gitDir, dst, clone.ModeCopy, clone.Options{})
+	}
+	if err != nil {
+		// 中断されたときに途中までのバックアップを残さない
+		_ = os.RemoveAll(dst)
+		return err
+	}
+	util.Verbose("[verbose] %s", stats.Summary())
+	return nil
+}
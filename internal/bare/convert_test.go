@@ -0,0 +1,238 @@
+package bare
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/manattan/clove/internal/git"
+)
+
+// initClone clones initRemote into <tmp>/app with a linked worktree, a stash, a hook
+// and staged, unstaged, untracked and ignored changes on feature/x
+func initClone(t *testing.T) (string, string) {
+	t.Helper()
+	src := initRemote(t)
+	ctx := context.Background()
+	tmp := filepath.Dir(src)
+	dir := filepath.Join(tmp, "app")
+	linked := filepath.Join(tmp, "app-dev")
+
+	write := func(name, data string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run := func(args ...string) {
+		t.Helper()
+		if _, err := git.Git(ctx, dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := git.Git(ctx, "", "clone", "-q", src, dir); err != nil {
+		t.Fatal(err)
+	}
+	write("a.txt", "a\n")
+	write(".gitignore", "*.log\n")
+	// ブランチ名と同じ feature/ ディレクトリがあっても変換できる
+	write("feature/f.txt", "f\n")
+	run("add", ".")
+	run("commit", "-q", "-m", "files")
+	run("worktree", "add", "-q", linked, "dev")
+	write("a.txt", "stash\n")
+	run("stash", "-q")
+	write("a.txt", "staged\n")
+	run("add", "a.txt")
+	write("a.txt", "unstaged\n")
+	write("untracked.txt", "u\n")
+	write("x.log", "l\n")
+	write(".git/hooks/pre-commit", "#!/bin/sh\n")
+	run("checkout", "-q", "-b", "feature/x")
+	return dir, linked
+}
+
+func TestConvert(t *testing.T) {
+	dir, linked := initClone(t)
+	ctx := context.Background()
+	// 未設定なら変換時に有効にし、元に戻すときに削除する
+	if _, err := git.Git(ctx, dir, "config", "--unset", "core.logAllRefUpdates"); err != nil {
+		t.Fatal(err)
+	}
+	before, err := snapshot(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// dry-run は何も変えない
+	res, err := Convert(ctx, dir, ConvertOptions{Layout: Layout, DryRun: true})
+	if err != nil || len(res.Steps) == 0 {
+		t.Fatalf("dry-run = %+v, %v", res, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, Dir)); !os.IsNotExist(err) {
+		t.Errorf("dry-run should not create %s: %v", Dir, err)
+	}
+
+	res, err = Convert(ctx, dir, ConvertOptions{Layout: Layout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt := filepath.Join(dir, "feature", "x")
+	if res.Worktree != wt || res.Bare != filepath.Join(dir, Dir) || res.Branch != "feature/x" {
+		t.Errorf("result = %+v", res)
+	}
+	if !reflect.DeepEqual(res.Relinked, []string{linked}) {
+		t.Errorf("relinked = %v, want %v", res.Relinked, []string{linked})
+	}
+	if _, err := os.Stat(filepath.Join(res.Backup, "HEAD")); err != nil {
+		t.Errorf("backup should be a copy of .git: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, ".git")); err != nil || string(data) != gitFile {
+		t.Errorf(".git = %q, %v", data, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, ".clove.toml")); err != nil || !strings.Contains(string(data), `path = "{branch}"`) {
+		t.Errorf(".clove.toml = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, Dir, "hooks", "pre-commit")); err != nil {
+		t.Errorf("hooks should be kept: %v", err)
+	}
+	for _, name := range []string{"a.txt", "untracked.txt", "x.log", "feature/f.txt"} {
+		if _, err := os.Stat(filepath.Join(wt, name)); err != nil {
+			t.Errorf("%s should be moved into the worktree: %v", name, err)
+		}
+	}
+
+	// 変換後も status・stash・ブランチが同じで、linked worktree も使える
+	after, err := snapshot(ctx, wt)
+	if err != nil || !reflect.DeepEqual(after, before) {
+		t.Errorf("state after convert = %+v, %v; want %+v", after, err, before)
+	}
+	repo, err := git.OpenRepository(ctx, wt)
+	if err != nil || repo.Main != dir || repo.Current != wt || !repo.Bare {
+		t.Errorf("OpenRepository = %+v, %v", repo, err)
+	}
+	if out, err := git.Git(ctx, wt, "symbolic-ref", "--short", "HEAD"); err != nil || strings.TrimSpace(out) != "feature/x" {
+		t.Errorf("HEAD = %q, %v", out, err)
+	}
+	if out, err := git.Git(ctx, linked, "status", "--porcelain"); err != nil || out != "" {
+		t.Errorf("linked worktree status = %q, %v", out, err)
+	}
+	if out, err := git.Git(ctx, wt, "config", "--bool", "core.logAllRefUpdates"); err != nil || strings.TrimSpace(out) != "true" {
+		t.Errorf("core.logAllRefUpdates = %q, %v", out, err)
+	}
+
+	// 変換済みのリポジトリは変換できない
+	if _, err := Convert(ctx, dir, ConvertOptions{Layout: Layout}); err == nil {
+		t.Error("Convert of a converted repository should fail")
+	}
+
+	// dry-run の undo は何も変えない
+	if _, err := Revert(ctx, wt, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, Dir)); err != nil {
+		t.Errorf("dry-run should keep %s: %v", Dir, err)
+	}
+
+	if _, err := Revert(ctx, dir, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{Dir, ".clove.toml", stagingDir, "feature/x"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed: %v", name, err)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(dir, ".git")); err != nil || !info.IsDir() {
+		t.Errorf(".git should be a directory again: %v", err)
+	}
+	if out, err := git.Git(ctx, dir, "config", "--bool", "core.bare"); err != nil || strings.TrimSpace(out) != "false" {
+		t.Errorf("core.bare = %q, %v", out, err)
+	}
+	if git.GitOk(ctx, dir, "config", "core.logAllRefUpdates") {
+		t.Error("core.logAllRefUpdates set by convert should be removed")
+	}
+	reverted, err := snapshot(ctx, dir)
+	if err != nil || !reflect.DeepEqual(reverted, before) {
+		t.Errorf("state after revert = %+v, %v; want %+v", reverted, err, before)
+	}
+	if out, err := git.Git(ctx, linked, "status", "--porcelain"); err != nil || out != "" {
+		t.Errorf("linked worktree status = %q, %v", out, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git", "worktrees", "x")); !os.IsNotExist(err) {
+		t.Errorf("the main worktree should be unregistered: %v", err)
+	}
+
+	// 変換していないリポジトリは戻せない
+	if _, err := Revert(ctx, dir, false); err == nil {
+		t.Error("Revert without a conversion should fail")
+	}
+}
+
+func TestConvert_Errors(t *testing.T) {
+	dir, _ := initClone(t)
+	ctx := context.Background()
+	tmp := filepath.Dir(dir)
+
+	// 途中の merge があるときは変換しない
+	head := filepath.Join(dir, ".git", "MERGE_HEAD")
+	out, err := git.Git(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(head, []byte(out), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Convert(ctx, dir, ConvertOptions{NoBackup: true}); err == nil {
+		t.Error("Convert during a merge should fail")
+	}
+	if err := os.Remove(head); err != nil {
+		t.Fatal(err)
+	}
+
+	// bare リポジトリは変換しない
+	bare := filepath.Join(tmp, "bare.git")
+	if _, err := git.Git(ctx, "", "clone", "-q", "--bare", dir, bare); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Convert(ctx, bare, ConvertOptions{NoBackup: true}); err == nil {
+		t.Error("Convert of a bare repository should fail")
+	}
+
+	// linked worktree からでもメインのリポジトリを変換する
+	res, err := Convert(ctx, filepath.Join(tmp, "app-dev"), ConvertOptions{Layout: "worktrees/{branch}", NoBackup: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "worktrees", "feature", "x"); res.Worktree != want || res.Backup != "" {
+		t.Errorf("result = %+v, want worktree %s without backup", res, want)
+	}
+}
+
+func TestRunSteps_Rollback(t *testing.T) {
+	var log []string
+	ok := func(name string) step {
+		return step{
+			desc: name,
+			do:   func() error { log = append(log, "do "+name); return nil },
+			undo: func() error { log = append(log, "undo "+name); return nil },
+		}
+	}
+	fail := step{desc: "c", do: func() error { return errors.New("boom") }}
+
+	res := &ConvertResult{}
+	if err := runSteps(context.Background(), res, []step{ok("a"), ok("b"), fail, ok("d")}); err == nil {
+		t.Fatal("runSteps should fail")
+	}
+	want := []string{"do a", "do b", "undo b", "undo a"}
+	if !reflect.DeepEqual(log, want) || !res.RolledBack {
+		t.Errorf("log = %v, rolledBack = %v; want %v", log, res.RolledBack, want)
+	}
+}
@@ -0,0 +1,90 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/manattan/clove/internal/clone"
+	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/util"
+)
+
+// Apply executes a planned step, carrying dependencies from repoRoot into target.
+// It stops the copy, or interrupts the install command, when ctx is cancelled.
+func Apply(ctx context.Context, step Step, repoRoot, target string) error {
+	if step.Strategy == StrategyInstall {
+		util.Verbose("[verbose] 実行中 (%s): %s", target, step.Command)
+		cmd := git.Command(ctx, "sh", "-c", step.Command)
+		cmd.Dir = target
+		cmd.Stdout = util.Output()
+		cmd.Stderr = util.Output()
+		err := cmd.Run()
+		if err != nil && ctx.Err() != nil {
+			return fmt.Errorf("中断されました: %w", ctx.Err())
+		}
+		return err
+	}
+
+	for _, p := range step.Paths {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("中断されました: %w", err)
+		}
+		src := filepath.Join(repoRoot, p)
+		dst := filepath.Join(target, p)
+		if _, err := os.Lstat(dst); err == nil {
+			util.Verbose("[verbose] 既に存在するためスキップします: %s", dst)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		util.Verbose("[verbose] %s: %s -> %s", step.Strategy, src, dst)
+		if err := applyPath(ctx, step.Strategy, src, dst); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("中断されました: %w", ctx.Err())
+			}
+			return fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// cloneModes maps tree strategies to clone modes
+var cloneModes = map[Strategy]clone.Mode{
+	StrategyAuto:     clone.ModeAuto,
+	StrategyReflink:  clone.ModeReflink,
+	StrategyHardlink: clone.ModeHardlink,
+	StrategyCopy:     clone.ModeCopy,
+}
+
+// applyPath carries a single directory using the given strategy
+func applyPath(ctx context.Context, st Strategy, src, dst string) error {
+	if st == StrategySymlink {
+		return os.Symlink(src, dst)
+	}
+	mode, ok := cloneModes[st]
+	if !ok {
+		return fmt.Errorf("未知の bootstrap 戦略です: %s", st)
+	}
+
+	var opts clone.Options
+	name := filepath.Base(src)
+	tty := util.OutputIsTerminal()
+	if tty {
+		opts.Progress = func(s clone.Stats) {
+			util.Printf("\r  %s: %d ファイル, %s ...", name, s.Files, clone.FormatBytes(s.Bytes))
+		}
+	}
+
+	stats, err := clone.Tree(ctx, src, dst, mode, opts)
+	if tty {
+		util.Printf("\r\033[K")
+	}
+	if err != nil {
+		return err
+	}
+	util.Printf("  %s: %s\n", name, stats.Summary())
+	return nil
+}
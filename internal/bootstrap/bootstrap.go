@@ -0,0 +1,189 @@
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Strategy is how dependency directories are carried into a new worktree
+type Strategy string
+
+const (
+	// StrategyAuto tries reflink, then hardlink, then a full copy
+	StrategyAuto Strategy = "auto"
+	// StrategyCopy copies the directories
+	StrategyCopy Strategy = "copy"
+	// StrategyHardlink creates a tree of hard links
+	StrategyHardlink Strategy = "hardlink"
+	// StrategySymlink symlinks the directories to the original checkout
+	StrategySymlink Strategy = "symlink"
+	// StrategyReflink creates copy-on-write clones
+	StrategyReflink Strategy = "reflink"
+	// StrategyInstall runs the ecosystem's install command in the new worktree
+	StrategyInstall Strategy = "install"
+	// StrategyNone skips the ecosystem
+	StrategyNone Strategy = "none"
+)
+
+// Strategies lists every supported strategy
+var Strategies = []Strategy{StrategyAuto, StrategyCopy, StrategyHardlink, StrategySymlink, StrategyReflink, StrategyInstall, StrategyNone}
+
+// ParseStrategy validates a strategy name
+func ParseStrategy(s string) (Strategy, error) {
+	for _, st := range Strategies {
+		if string(st) == s {
+			return st, nil
+		}
+	}
+	names := make([]string, len(Strategies))
+	for i, st := range Strategies {
+		names[i] = string(st)
+	}
+	return "", fmt.Errorf("未知の bootstrap 戦略です: %s（使用可能: %s）", s, strings.Join(names, ", "))
+}
+
+// Bootstrapper prepares the dependencies of one ecosystem in a new worktree
+type Bootstrapper interface {
+	// Name returns the identifier used by --bootstrap and config
+	Name() string
+	// Detect reports whether the ecosystem is used in repoRoot
+	Detect(repoRoot string) bool
+	// Paths returns the dependency directories relative to the repository root
+	Paths() []string
+	// InstallCommand returns the shell command used by the install strategy
+	InstallCommand(repoRoot string) string
+	// DefaultStrategy returns the strategy used when none is configured
+	DefaultStrategy() Strategy
+	// OptIn reports whether the bootstrapper only runs when its strategy or
+	// command is configured or it is named in Options.Only
+	OptIn() bool
+}
+
+var registry = map[string]Bootstrapper{}
+
+// Register adds a bootstrapper to the registry
+func Register(b Bootstrapper) {
+	registry[b.Name()] = b
+}
+
+// Lookup returns the registered bootstrapper with the given name
+func Lookup(name string) (Bootstrapper, bool) {
+	b, ok := registry[name]
+	return b, ok
+}
+
+// Names returns the names of all registered bootstrappers in sorted order
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for n := range registry {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DependencyPaths returns the dependency directories of every registered
+// bootstrapper in sorted order. They can be recreated, so losing them is harmless.
+func DependencyPaths() []string {
+	seen := map[string]bool{}
+	var paths []string
+	for _, b := range registry {
+		for _, p := range b.Paths() {
+			if !seen[p] {
+				seen[p] = true
+				paths = append(paths, p)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Options controls which bootstrappers run and how
+type Options struct {
+	// Disabled skips bootstrapping entirely
+	Disabled bool
+	// Only limits bootstrapping to the named bootstrappers (empty: all detected)
+	Only []string
+	// Strategies overrides the strategy per bootstrapper name
+	Strategies map[string]Strategy
+	// Commands overrides the install command per bootstrapper name
+	Commands map[string]string
+}
+
+// Step is a single planned bootstrap action
+type Step struct {
+	Name     string
+	Strategy Strategy
+	Paths    []string
+	Command  string
+}
+
+// Plan decides which bootstrap steps to run for repoRoot
+func Plan(repoRoot string, opts Options) ([]Step, error) {
+	if opts.Disabled {
+		return nil, nil
+	}
+
+	names := opts.Only
+	explicit := len(names) > 0
+	if !explicit {
+		names = Names()
+	}
+
+	var steps []Step
+	for _, name := range names {
+		b, ok := Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("未知の bootstrap です: %s（使用可能: %s）", name, strings.Join(Names(), ", "))
+		}
+		if !b.Detect(repoRoot) {
+			continue
+		}
+
+		st := b.DefaultStrategy()
+		if s, ok := opts.Strategies[name]; ok && s != "" {
+			st = s
+		} else if b.OptIn() && !explicit && opts.Commands[name] == "" {
+			// ネットワークやビルドを伴うことがあるため、設定されたときだけ実行する
+			continue
+		}
+		if st == StrategyNone {
+			continue
+		}
+
+		step := Step{Name: name, Strategy: st}
+		if st == StrategyInstall {
+			step.Command = b.InstallCommand(repoRoot)
+			if c, ok := opts.Commands[name]; ok && c != "" {
+				step.Command = c
+			}
+			if step.Command == "" {
+				return nil, fmt.Errorf("%s: install 戦略に使うコマンドがありません", name)
+			}
+		} else {
+			for _, p := range b.Paths() {
+				if _, err := os.Lstat(filepath.Join(repoRoot, p)); err == nil {
+					step.Paths = append(step.Paths, p)
+				}
+			}
+			if len(step.Paths) == 0 {
+				continue
+			}
+		}
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// Describe renders a step for dry-run output
+func (s Step) Describe() string {
+	if s.Strategy == StrategyInstall {
+		return fmt.Sprintf("%s: %s (%s)", s.Name, s.Strategy, s.Command)
+	}
+	return fmt.Sprintf("%s: %s %s", s.Name, s.Strategy, strings.Join(s.Paths, ", "))
+}
@@ -0,0 +1,164 @@
+package bootstrap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func touch(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, n := range names {
+		p := filepath.Join(dir, n)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(n), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func stepNames(steps []Step) []string {
+	var names []string
+	for _, s := range steps {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestPlan_Detect(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string
+		expected []string
+	}{
+		{"empty", nil, nil},
+		{"npm", []string{"package.json", "node_modules/a/index.js"}, []string{"node"}},
+		{"npm without node_modules", []string{"package.json"}, nil},
+		{"pnpm", []string{"package.json", "pnpm-lock.yaml", "node_modules/a/index.js"}, []string{"pnpm"}},
+		{"yarn", []string{"package.json", "yarn.lock", "node_modules/a/index.js"}, []string{"yarn"}},
+		// node 以外は設定したときだけ実行する
+		{"go", []string{"go.mod"}, nil},
+		{"python", []string{".venv/bin/python", "requirements.txt"}, nil},
+		{"ruby and rust", []string{"Gemfile", "vendor/bundle/x", "Cargo.toml", "target/debug/x"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			touch(t, repo, tt.files...)
+
+			steps, err := Plan(repo, Options{})
+			if err != nil {
+				t.Fatalf("Plan failed: %v", err)
+			}
+			if got := stepNames(steps); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Plan = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPlan_Options(t *testing.T) {
+	repo := t.TempDir()
+	touch(t, repo, "package.json", "node_modules/a/index.js", "go.mod")
+
+	steps, err := Plan(repo, Options{Disabled: true})
+	if err != nil || len(steps) != 0 {
+		t.Errorf("Plan(Disabled) = %v, %v, want no steps", steps, err)
+	}
+
+	steps, err = Plan(repo, Options{Only: []string{"node"}})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if got := stepNames(steps); !reflect.DeepEqual(got, []string{"node"}) {
+		t.Errorf("Plan(Only node) = %v, want [node]", got)
+	}
+
+	// 明示的に指定するか戦略を設定すると opt-in のエコシステムも実行する
+	steps, err = Plan(repo, Options{Only: []string{"go"}})
+	if err != nil || !reflect.DeepEqual(stepNames(steps), []string{"go"}) || steps[0].Command != "go mod download" {
+		t.Errorf("Plan(Only go) = %+v, %v", steps, err)
+	}
+	steps, err = Plan(repo, Options{Strategies: map[string]Strategy{"go": StrategyInstall}})
+	if err != nil || !reflect.DeepEqual(stepNames(steps), []string{"go", "node"}) {
+		t.Errorf("Plan(go strategy) = %v, %v, want [go node]", stepNames(steps), err)
+	}
+	steps, err = Plan(repo, Options{Only: []string{"go"}, Commands: map[string]string{"go": "go mod vendor"}})
+	if err != nil || len(steps) != 1 || steps[0].Command != "go mod vendor" {
+		t.Errorf("Plan(go command) = %+v, %v", steps, err)
+	}
+	steps, err = Plan(repo, Options{Commands: map[string]string{"go": "go mod vendor"}})
+	if err != nil || !reflect.DeepEqual(stepNames(steps), []string{"go", "node"}) {
+		t.Errorf("Plan(go command) = %v, %v, want [go node]", stepNames(steps), err)
+	}
+	steps, err = Plan(repo, Options{Strategies: map[string]Strategy{"node": StrategyNone}})
+	if err != nil || len(steps) != 0 {
+		t.Errorf("Plan(node none) = %v, %v, want no steps", stepNames(steps), err)
+	}
+
+	if _, err := Plan(repo, Options{Only: []string{"cobol"}}); err == nil {
+		t.Error("Plan should fail for unknown bootstrapper")
+	}
+
+	steps, err = Plan(repo, Options{
+		Only:       []string{"node"},
+		Strategies: map[string]Strategy{"node": StrategyInstall},
+		Commands:   map[string]string{"node": "npm ci --ignore-scripts"},
+	})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if steps[0].Strategy != StrategyInstall || steps[0].Command != "npm ci --ignore-scripts" {
+		t.Errorf("Plan override = %+v", steps[0])
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	for _, st := range Strategies {
+		if got, err := ParseStrategy(string(st)); err != nil || got != st {
+			t.Errorf("ParseStrategy(%q) = %q, %v", st, got, err)
+		}
+	}
+	if _, err := ParseStrategy("teleport"); err == nil {
+		t.Error("ParseStrategy should fail for unknown strategy")
+	}
+}
+
+func TestApply(t *testing.T) {
+	for _, st := range []Strategy{StrategyCopy, StrategySymlink} {
+		t.Run(string(st), func(t *testing.T) {
+			repo := t.TempDir()
+			target := t.TempDir()
+			touch(t, repo, "vendor/bundle/gems/a.rb")
+
+			step := Step{Name: "ruby", Strategy: st, Paths: []string{"vendor/bundle"}}
+			if err := Apply(context.Background(), step, repo, target); err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(target, "vendor/bundle/gems/a.rb"))
+			if err != nil {
+				t.Fatalf("file not carried over: %v", err)
+			}
+			if string(data) != "vendor/bundle/gems/a.rb" {
+				t.Errorf("unexpected content: %q", data)
+			}
+		})
+	}
+}
+
+func TestApply_Install(t *testing.T) {
+	target := t.TempDir()
+	step := Step{Name: "node", Strategy: StrategyInstall, Command: "echo ok > installed"}
+	if err := Apply(context.Background(), step, t.TempDir(), target); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "installed")); err != nil {
+		t.Errorf("install command did not run in target: %v", err)
+	}
+}
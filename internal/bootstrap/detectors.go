@@ -0,0 +1,129 @@
+package bootstrap
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ecosystem is a Bootstrapper defined by marker files and dependency directories
+type ecosystem struct {
+	name     string
+	markers  []string
+	excludes []string
+	paths    []string
+	install  func(repoRoot string) string
+	strategy Strategy
+	// optIn ecosystems run only when configured or named with --bootstrap
+	optIn bool
+}
+
+func (e ecosystem) Name() string { return e.name }
+
+func (e ecosystem) Detect(repoRoot string) bool {
+	for _, x := range e.excludes {
+		if exists(repoRoot, x) {
+			return false
+		}
+	}
+	for _, m := range e.markers {
+		if exists(repoRoot, m) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e ecosystem) Paths() []string { return e.paths }
+
+func (e ecosystem) InstallCommand(repoRoot string) string { return e.install(repoRoot) }
+
+func (e ecosystem) DefaultStrategy() Strategy { return e.strategy }
+
+func (e ecosystem) OptIn() bool { return e.optIn }
+
+// exists reports whether name exists under dir
+func exists(dir, name string) bool {
+	_, err := os.Stat(filepath.Join(dir, name))
+	return err == nil
+}
+
+// fixed returns an install function that always yields cmd
+func fixed(cmd string) func(string) string {
+	return func(string) string { return cmd }
+}
+
+func init() {
+	Register(ecosystem{
+		name:     "node",
+		markers:  []string{"package.json"},
+		excludes: []string{"pnpm-lock.yaml", "yarn.lock"},
+		paths:    []string{"node_modules"},
+		install: func(repoRoot string) string {
+			if exists(repoRoot, "package-lock.json") {
+				return "npm ci"
+			}
+			return "npm install"
+		},
+		strategy: StrategyAuto,
+	})
+	Register(ecosystem{
+		name:     "pnpm",
+		markers:  []string{"pnpm-lock.yaml"},
+		paths:    []string{"node_modules", ".pnpm-store"},
+		install:  fixed("pnpm install --frozen-lockfile"),
+		strategy: StrategyAuto,
+	})
+	Register(ecosystem{
+		name:     "yarn",
+		markers:  []string{"yarn.lock"},
+		paths:    []string{"node_modules", ".yarn/cache"},
+		install:  fixed("yarn install --frozen-lockfile"),
+		strategy: StrategyAuto,
+	})
+	Register(ecosystem{
+		name:     "go",
+		markers:  []string{"go.mod"},
+		paths:    []string{"vendor"},
+		install:  fixed("go mod download"),
+		strategy: StrategyInstall,
+		optIn:    true,
+	})
+	Register(ecosystem{
+		name: "python",
+		// ローカルの .venv を使っているリポジトリだけを対象にする
+		markers: []string{".venv"},
+		paths:   []string{".venv"},
+		install: func(repoRoot string) string {
+			switch {
+			case exists(repoRoot, "uv.lock"):
+				return "uv sync"
+			case exists(repoRoot, "poetry.lock"):
+				return "poetry install"
+			case exists(repoRoot, "Pipfile"):
+				return "pipenv install"
+			case exists(repoRoot, "requirements.txt"):
+				return "python3 -m venv .venv && .venv/bin/pip install -r requirements.txt"
+			}
+			return "python3 -m venv .venv && .venv/bin/pip install -e ."
+		},
+		// venv はパスが埋め込まれるためコピーでは動かない
+		strategy: StrategyInstall,
+		optIn:    true,
+	})
+	Register(ecosystem{
+		name:     "ruby",
+		markers:  []string{"Gemfile"},
+		paths:    []string{"vendor/bundle"},
+		install:  fixed("bundle install"),
+		strategy: StrategyAuto,
+		optIn:    true,
+	})
+	Register(ecosystem{
+		name:     "rust",
+		markers:  []string{"Cargo.toml"},
+		paths:    []string{"target"},
+		install:  fixed("cargo fetch"),
+		strategy: StrategyAuto,
+		optIn:    true,
+	})
+}
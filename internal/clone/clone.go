@@ -0,0 +1,368 @@
+package clone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Mode selects how files are cloned
+type Mode int
+
+const (
+	// ModeAuto tries reflink, then hardlink, then a full copy
+	ModeAuto Mode = iota
+	// ModeReflink only uses copy-on-write clones
+	ModeReflink
+	// ModeHardlink only uses hard links
+	ModeHardlink
+	// ModeCopy always copies file contents
+	ModeCopy
+)
+
+// method is the per-file operation actually used
+type method int32
+
+const (
+	methodReflink method = iota
+	methodHardlink
+	methodCopy
+)
+
+// errUnsupported is returned when the filesystem does not support an operation
+var errUnsupported = errors.New("この環境ではサポートされていません")
+
+// Stats reports the progress of a clone
+type Stats struct {
+	Files      int64
+	Dirs       int64
+	Bytes      int64
+	Reflinked  int64
+	Hardlinked int64
+	Copied     int64
+	Elapsed    time.Duration
+}
+
+// Method summarizes which operations were used
+func (s Stats) Method() string {
+	var parts []string
+	if s.Reflinked > 0 {
+		parts = append(parts, fmt.Sprintf("reflink %d", s.Reflinked))
+	}
+	if s.Hardlinked > 0 {
+		parts = append(parts, fmt.Sprintf("hardlink %d", s.Hardlinked))
+	}
+	if s.Copied > 0 {
+		parts = append(parts, fmt.Sprintf("copy %d", s.Copied))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Summary renders a one-line byte/second summary
+func (s Stats) Summary() string {
+	rate := float64(0)
+	if sec := s.Elapsed.Seconds(); sec > 0 {
+		rate = float64(s.Bytes) / sec
+	}
+	return fmt.Sprintf("%d ファイル / %s を %s で処理しました (%s/s, %s)",
+		s.Files, FormatBytes(s.Bytes), s.Elapsed.Round(time.Millisecond), FormatBytes(int64(rate)), s.Method())
+}
+
+// Options controls a clone
+type Options struct {
+	// Workers is the number of parallel file workers (default: NumCPU)
+	Workers int
+	// Progress is called periodically with a snapshot of the stats
+	Progress func(Stats)
+	// Interval is the progress reporting interval (default: 500ms)
+	Interval time.Duration
+}
+
+// cloner holds the shared state of one Tree call
+type cloner struct {
+	mode  Mode
+	level atomic.Int32
+
+	files, dirs, bytes            atomic.Int64
+	reflinked, hardlinked, copied atomic.Int64
+}
+
+// fileJob is a single regular file to clone
+type fileJob struct {
+	src, dst string
+	info     fs.FileInfo
+}
+
+// Tree clones the directory tree src to dst, which must not exist yet.
+// When ctx is cancelled the walk stops, queued files are skipped and ctx.Err()
+// is returned with whatever was cloned so far left in dst.
+func Tree(ctx context.Context, src, dst string, mode Mode, opts Options) (Stats, error) {
+	start := time.Now()
+	if _, err := os.Lstat(dst); err == nil {
+		return Stats{}, fmt.Errorf("コピー先が既に存在します: %s", dst)
+	}
+
+	c := &cloner{mode: mode}
+	switch mode {
+	case ModeHardlink:
+		c.level.Store(int32(methodHardlink))
+	case ModeCopy:
+		c.level.Store(int32(methodCopy))
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+
+	jobs := make(chan fileJob, workers*4)
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	setErr := func(err error) {
+		errOnce.Do(func() { firstErr = err })
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if ctx.Err() != nil {
+					// 中断されたら残りのジョブは読み捨てる
+					continue
+				}
+				if err := c.cloneFile(j); err != nil {
+					setErr(err)
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	var progressWg sync.WaitGroup
+	if opts.Progress != nil {
+		progressWg.Add(1)
+		go func() {
+			defer progressWg.Done()
+			t := time.NewTicker(interval)
+			defer t.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-t.C:
+					opts.Progress(c.stats(time.Since(start)))
+				}
+			}
+		}()
+	}
+
+	// ディレクトリの権限は最後に設定する（読み取り専用ディレクトリへの書き込みを避けるため）
+	type dirMode struct {
+		path string
+		info fs.FileInfo
+	}
+	var dirModes []dirMode
+
+	walkErr := filepath.Walk(src, func(p string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			c.dirs.Add(1)
+			dirModes = append(dirModes, dirMode{target, info})
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+			c.files.Add(1)
+		case info.Mode().IsRegular():
+			select {
+			case jobs <- fileJob{src: p, dst: target, info: info}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		default:
+			// ソケットやデバイスファイルは複製しない
+		}
+		return nil
+	})
+
+	close(jobs)
+	wg.Wait()
+	close(done)
+	progressWg.Wait()
+
+	if walkErr != nil {
+		setErr(walkErr)
+	}
+
+	for i := len(dirModes) - 1; i >= 0; i-- {
+		d := dirModes[i]
+		_ = os.Chmod(d.path, d.info.Mode().Perm())
+		_ = os.Chtimes(d.path, d.info.ModTime(), d.info.ModTime())
+	}
+
+	st := c.stats(time.Since(start))
+	if err := ctx.Err(); err != nil {
+		// 読み捨てたファイルのエラーより中断を優先して返す
+		return st, err
+	}
+	return st, firstErr
+}
+
+// stats takes a snapshot of the counters
+func (c *cloner) stats(elapsed time.Duration) Stats {
+	return Stats{
+		Files:      c.files.Load(),
+		Dirs:       c.dirs.Load(),
+		Bytes:      c.bytes.Load(),
+		Reflinked:  c.reflinked.Load(),
+		Hardlinked: c.hardlinked.Load(),
+		Copied:     c.copied.Load(),
+		Elapsed:    elapsed,
+	}
+}
+
+// cloneFile clones a single file, downgrading the shared method when unsupported
+func (c *cloner) cloneFile(j fileJob) error {
+	for {
+		m := method(c.level.Load())
+		var err error
+		switch m {
+		case methodReflink:
+			err = reflinkFile(j.src, j.dst, j.info)
+		case methodHardlink:
+			err = os.Link(j.src, j.dst)
+			if err != nil && isCrossDevice(err) {
+				err = errUnsupported
+			}
+		default:
+			err = copyFile(j.src, j.dst, j.info)
+		}
+
+		if err == nil {
+			c.files.Add(1)
+			c.bytes.Add(j.info.Size())
+			switch m {
+			case methodReflink:
+				c.reflinked.Add(1)
+			case methodHardlink:
+				c.hardlinked.Add(1)
+			default:
+				c.copied.Add(1)
+			}
+			return nil
+		}
+
+		if !errors.Is(err, errUnsupported) || c.mode != ModeAuto || m == methodCopy {
+			return fmt.Errorf("%s: %w", j.src, err)
+		}
+		// 次の方式に切り替える（他のワーカーが既に切り替えていても問題ない）
+		c.level.CompareAndSwap(int32(m), int32(m+1))
+	}
+}
+
+// File copies a single regular file, preserving permissions and mtime
+func File(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("通常ファイルではありません: %s", src)
+	}
+	return copyFile(src, dst, info)
+}
+
+// copyFile copies the contents, permissions and mtime of src to dst
+func copyFile(src, dst string, info fs.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm()|0o200)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// FormatBytes renders a byte count in human readable units
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// ParseBytes parses a size such as "512", "50MB", "50MiB" or "1G" (units are powers of 1024)
+func ParseBytes(s string) (int64, error) {
+	t := strings.ToUpper(strings.TrimSpace(s))
+	t = strings.TrimSuffix(strings.TrimSuffix(t, "B"), "I")
+	mult := int64(1)
+	if t != "" {
+		if i := strings.IndexByte("KMGT", t[len(t)-1]); i >= 0 {
+			mult = int64(1) << (10 * (i + 1))
+			t = strings.TrimSpace(t[:len(t)-1])
+		}
+	}
+	n, err := strconv.ParseFloat(t, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("サイズを解釈できません: %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
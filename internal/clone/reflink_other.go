@@ -0,0 +1,19 @@
+//go:build !linux
+
+package clone
+
+import (
+	"errors"
+	"io/fs"
+	"syscall"
+)
+
+// reflinkFile is not implemented outside Linux; Tree falls back to hard links
+func reflinkFile(src, dst string, info fs.FileInfo) error {
+	return errUnsupported
+}
+
+// isCrossDevice reports whether err means a link across filesystems
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV) || errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EMLINK)
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Source identifies where a config value came from
+type Source string
+
+const (
+	// SourceDefault is the built-in default
+	SourceDefault Source = "default"
+	// SourceGlobal is the user's global config file
+	SourceGlobal Source = "global"
+	// SourceRepo is the repository's .clove.* file
+	SourceRepo Source = "repo"
+)
+
+// RepoFileNames are the candidate repo config file names, in priority order
+var RepoFileNames = []string{".clove.toml", ".clove.yaml", ".clove.yml"}
+
+// globalFileNames are the candidate global config file names, in priority order
+var globalFileNames = []string{"config", "config.toml", "config.yaml", "config.yml"}
+
+// Value is a resolved config value
+type Value struct {
+	Items  []string
+	List   bool
+	Source Source
+	Path   string
+}
+
+// String renders the value for display
+func (v Value) String() string {
+	if v.List {
+		return strings.Join(v.Items, ",")
+	}
+	return first(v.Items)
+}
+
+// Config holds layered config values
+type Config struct {
+	values map[string]Value
+}
+
+// GlobalDir returns the directory holding the global config file
+func GlobalDir() (string, error) {
+	if d := os.Getenv("XDG_CONFIG_HOME"); d != "" {
+		return filepath.Join(d, "clove"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "clove"), nil
+}
+
+// FindGlobalFile returns the global config file path, or "" if none exists
+func FindGlobalFile() string {
+	dir, err := GlobalDir()
+	if err != nil {
+		return ""
+	}
+	return findFile(dir, globalFileNames)
+}
+
+// FindRepoFile returns the repo config file path, or "" if none exists
+func FindRepoFile(repoRoot string) string {
+	if repoRoot == "" {
+		return ""
+	}
+	return findFile(repoRoot, RepoFileNames)
+}
+
+// findFile returns the first existing regular file among names in dir
+func findFile(dir string, names []string) string {
+	for _, n := range names {
+		p := filepath.Join(dir, n)
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+			return p
+		}
+	}
+	return ""
+}
+
+// Load resolves config from built-in defaults, the global file and the repo file.
+// repoRoot may be empty when not inside a repository.
+func Load(repoRoot string) (*Config, error) {
+	c := &Config{values: map[string]Value{}}
+	for _, d := range keyDefs {
+		if strings.HasSuffix(d.Name, ".*") {
+			continue
+		}
+		v := Value{Source: SourceDefault, List: d.Kind == KindList}
+		if d.Default != "" {
+			v.Items = splitDefault(d)
+		}
+		c.values[d.Name] = v
+	}
+
+	layers := []struct {
+		path   string
+		source Source
+	}{
+		{FindGlobalFile(), SourceGlobal},
+		{FindRepoFile(repoRoot), SourceRepo},
+	}
+	for _, l := range layers {
+		if l.path == "" {
+			continue
+		}
+		entries, err := readEntries(l.path)
+		if err != nil {
+			return nil, err
+		}
+		for key, e := range entries {
+			if err := validate(key, e); err != nil {
+				return nil, fmt.Errorf("%s: %w", l.path, err)
+			}
+			c.values[key] = Value{Items: e.items, List: e.list, Source: l.source, Path: l.path}
+		}
+	}
+
+	return c, nil
+}
+
+// splitDefault converts a KeyDef default into items
+func splitDefault(d KeyDef) []string {
+	if d.Kind == KindList {
+		return strings.Split(d.Default, ",")
+	}
+	return []string{d.Default}
+}
+
+// validate checks a value against its key definition
+func validate(key string, e entry) error {
+	def, ok := LookupKey(key)
+	if !ok {
+		// 未知のキーは将来の拡張やタイポの可能性があるので保持だけする
+		return nil
+	}
+	switch def.Kind {
+	case KindBool:
+		if e.list {
+			return fmt.Errorf("%s は true/false で指定してください", key)
+		}
+		if _, err := strconv.ParseBool(first(e.items)); err != nil {
+			return fmt.Errorf("%s は true/false で指定してください: %s", key, first(e.items))
+		}
+	case KindString:
+		if e.list {
+			return fmt.Errorf("%s は文字列で指定してください", key)
+		}
+	}
+	return nil
+}
+
+// readEntries reads and parses a config file
+func readEntries(path string) (map[string]entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseFile(path, data)
+}
+
+// Lookup returns the resolved value for key
+func (c *Config) Lookup(key string) (Value, bool) {
+	v, ok := c.values[key]
+	return v, ok
+}
+
+// String returns the value for key as a string
+func (c *Config) String(key string) string {
+	return c.values[key].String()
+}
+
+// Bool returns the value for key as a bool
+func (c *Config) Bool(key string) bool {
+	b, _ := strconv.ParseBool(first(c.values[key].Items))
+	return b
+}
+
+// List returns the value for key as a list.
+// A scalar value is split on commas.
+func (c *Config) List(key string) []string {
+	v := c.values[key]
+	if v.List {
+		return append([]string(nil), v.Items...)
+	}
+	var items []string
+	for _, s := range strings.Split(first(v.Items), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			items = append(items, s)
+		}
+	}
+	return items
+}
+
+// Keys returns all resolved keys in sorted order
+func (c *Config) Keys() []string {
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Set writes key = value to the config file at path, creating it if needed.
+// List values are given comma separated.
+func Set(path, key, value string) error {
+	def, ok := LookupKey(key)
+	if !ok {
+		return fmt.Errorf("未知の設定キーです: %s", key)
+	}
+
+	entries := map[string]entry{}
+	if _, err := os.Stat(path); err == nil {
+		entries, err = readEntries(path)
+		if err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	e := entry{items: []string{value}}
+	if def.Kind == KindList {
+		e = entry{list: true}
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				e.items = append(e.items, s)
+			}
+		}
+	}
+	if err := validate(key, e); err != nil {
+		return err
+	}
+	entries[key] = e
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, formatFile(path, entries), 0o644)
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	input := `# comment
+top = "value"
+
+[add]
+base = "origin/develop" # trailing comment
+no-fetch = true
+copy = [".env*", 'config/*.local.*']
+
+[hooks]
+post-add = "echo 'a # b'"
+list = [
+  "one",
+  "two",
+]
+`
+	entries, err := parseTOML("test.toml", input)
+	if err != nil {
+		t.Fatalf("parseTOML failed: %v", err)
+	}
+
+	expected := map[string]entry{
+		"top":            {items: []string{"value"}},
+		"add.base":       {items: []string{"origin/develop"}},
+		"add.no-fetch":   {items: []string{"true"}},
+		"add.copy":       {items: []string{".env*", "config/*.local.*"}, list: true},
+		"hooks.post-add": {items: []string{"echo 'a # b'"}},
+		"hooks.list":     {items: []string{"one", "two"}, list: true},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("parseTOML = %#v, want %#v", entries, expected)
+	}
+}
+
+func TestParseTOML_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"no equals", "[add]\nbase\n"},
+		{"empty table", "[]\n"},
+		{"unterminated string", "base = \"abc\n"},
+		{"unterminated array", "copy = [\"a\"\n"},
+		{"empty value", "base =\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseTOML("test.toml", tt.input); err == nil {
+				t.Errorf("parseTOML(%q) should fail", tt.input)
+			}
+		})
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	input := `# comment
+add:
+  base: origin/develop
+  open: "cursor"
+  no-fetch: true
+  copy:
+    - .env*
+    - 'config/*.local.*'
+remove:
+  force: false
+list: [a, "b"]
+`
+	entries, err := parseYAML("test.yaml", input)
+	if err != nil {
+		t.Fatalf("parseYAML failed: %v", err)
+	}
+
+	expected := map[string]entry{
+		"add.base":     {items: []string{"origin/develop"}},
+		"add.open":     {items: []string{"cursor"}},
+		"add.no-fetch": {items: []string{"true"}},
+		"add.copy":     {items: []string{".env*", "config/*.local.*"}, list: true},
+		"remove.force": {items: []string{"false"}},
+		"list":         {items: []string{"a", "b"}, list: true},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("parseYAML = %#v, want %#v", entries, expected)
+	}
+}
+
+func TestParseYAML_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"orphan list item", "- a\n"},
+		{"no colon", "add\n"},
+		{"tab indent", "add:\n\tbase: x\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseYAML("test.yaml", tt.input); err == nil {
+				t.Errorf("parseYAML(%q) should fail", tt.input)
+			}
+		})
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	entries := map[string]entry{
+		"add.base":     {items: []string{"origin/develop"}},
+		"add.no-fetch": {items: []string{"true"}},
+		"add.copy":     {items: []string{".env*", "a \"b\""}, list: true},
+		"remove.force": {items: []string{"false"}},
+	}
+
+	for _, name := range []string{"config.toml", "config.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			out := formatFile(name, entries)
+			got, err := parseFile(name, out)
+			if err != nil {
+				t.Fatalf("parse of formatted output failed: %v\n%s", err, out)
+			}
+			if !reflect.DeepEqual(got, entries) {
+				t.Errorf("round trip = %#v, want %#v\n%s", got, entries, out)
+			}
+		})
+	}
+}
+
+func TestLoad_Layers(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	repo := t.TempDir()
+
+	globalDir := filepath.Join(home, "clove")
+	if err := os.MkdirAll(globalDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(globalDir, "config"), "[add]\nbase = \"origin/main\"\nopen = \"code\"\n")
+	writeFile(t, filepath.Join(repo, ".clove.yaml"), "add:\n  base: origin/develop\n")
+
+	cfg, err := Load(repo)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	tests := []struct {
+		key    string
+		value  string
+		source Source
+	}{
+		{"add.base", "origin/develop", SourceRepo},
+		{"add.open", "code", SourceGlobal},
+		{"add.prefix", "", SourceDefault},
+		{"prune.verbose", "true", SourceDefault},
+	}
+	for _, tt := range tests {
+		v, ok := cfg.Lookup(tt.key)
+		if !ok {
+			t.Errorf("Lookup(%q) not found", tt.key)
+			continue
+		}
+		if v.String() != tt.value || v.Source != tt.source {
+			t.Errorf("Lookup(%q) = %q (%s), want %q (%s)", tt.key, v.String(), v.Source, tt.value, tt.source)
+		}
+	}
+
+	if !cfg.Bool("prune.verbose") {
+		t.Error("Bool(prune.verbose) should be true by default")
+	}
+}
+
+func TestLoad_InvalidBool(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	repo := t.TempDir()
+	writeFile(t, filepath.Join(repo, ".clove.toml"), "[remove]\nforce = \"maybe\"\n")
+
+	if _, err := Load(repo); err == nil {
+		t.Error("Load should fail for non-bool value of a bool key")
+	}
+}
+
+func TestSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".clove.toml")
+
+	if err := Set(path, "add.base", "origin/develop"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := Set(path, "add.no-fetch", "true"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := Set(path, "add.no-fetch", "yes please"); err == nil {
+		t.Error("Set should reject invalid bool")
+	}
+	if err := Set(path, "unknown.key", "x"); err == nil {
+		t.Error("Set should reject unknown key")
+	}
+
+	entries, err := readEntries(path)
+	if err != nil {
+		t.Fatalf("readEntries failed: %v", err)
+	}
+	if got := first(entries["add.base"].items); got != "origin/develop" {
+		t.Errorf("add.base = %q, want origin/develop", got)
+	}
+	if got := first(entries["add.no-fetch"].items); got != "true" {
+		t.Errorf("add.no-fetch = %q, want true", got)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import "strings"
+
+// Kind represents the type of a config value
+type Kind int
+
+const (
+	// KindString is a plain string value
+	KindString Kind = iota
+	// KindBool is a true/false value
+	KindBool
+	// KindList is a list of strings
+	KindList
+)
+
+// KeyDef describes a known config key
+type KeyDef struct {
+	Name        string
+	Kind        Kind
+	Default     string
+	Description string
+}
+
+// keyDefs lists every config key clove understands
+var keyDefs = []KeyDef{
+	{Name: "git.backend", Kind: KindString, Default: "exec", Description: "読み取り専用の問い合わせに使う git 実装（exec: git コマンド / native: go-git）"},
+	{Name: "git.timeout", Kind: KindString, Default: "0", Description: "git コマンド1つあたりのタイムアウト（0 で無制限）"},
+	{Name: "remote.default", Kind: KindString, Description: "既定のリモート（空なら checkout.defaultRemote、origin、upstream、唯一のリモートの順に決定）"},
+	{Name: "layout.path", Kind: KindString, Description: "worktree の作成先のテンプレート（空なら {repoParent}/{prefix}-{sanitized}{suffix}）"},
+	{Name: "layout.unicode", Kind: KindBool, Default: "false", Description: "{sanitized} で日本語などの非 ASCII 文字をそのまま残す"},
+	{Name: "layout.max-length", Kind: KindString, Default: "255", Description: "作成するディレクトリ名の最大バイト数（超える部分はハッシュに置き換える、0 で無制限）"},
+	{Name: "add.base", Kind: KindString, Description: "起点にするref"},
+	{Name: "add.prefix", Kind: KindString, Description: "作成するディレクトリ名の接頭辞"},
+	{Name: "add.suffix", Kind: KindString, Description: "作成するディレクトリ名の接尾辞"},
+	{Name: "add.dir", Kind: KindString, Description: "作成するディレクトリ名"},
+	{Name: "add.open", Kind: KindString, Description: "作成後にディレクトリを開くコマンド"},
+	{Name: "add.dry-run", Kind: KindBool, Default: "false", Description: "実行せず、実行内容だけ表示する"},
+	{Name: "add.no-fetch", Kind: KindBool, Default: "false", Description: "git fetch をスキップする"},
+	{Name: "add.track", Kind: KindString, Description: "新しいブランチの upstream（remote: リモートの同名ブランチ / none: 設定しない / 空: git の既定）"},
+	{Name: "add.push-upstream", Kind: KindBool, Default: "false", Description: "新しいブランチをすぐに push して upstream に設定する"},
+	{Name: "add.copy", Kind: KindList, Description: "新しい worktree にコピーする未追跡ファイルの glob パターン"},
+	{Name: "add.copy-mode", Kind: KindString, Default: "copy", Description: "add.copy のファイルの持ち込み方（copy / symlink）"},
+	{Name: "add.bootstrap", Kind: KindList, Description: "実行する bootstrap の一覧（空なら検出されたものすべて）"},
+	{Name: "add.no-bootstrap", Kind: KindBool, Default: "false", Description: "bootstrap をスキップする"},
+	{Name: "remove.force", Kind: KindBool, Default: "false", Description: "強制削除する"},
+	{Name: "remove.dry-run", Kind: KindBool, Default: "false", Description: "実行せず、実行内容だけ表示する"},
+	{Name: "remove.delete-branch", Kind: KindString, Description: "ローカルブランチも削除する（safe / force、空なら削除しない）"},
+	{Name: "remove.delete-remote", Kind: KindBool, Default: "false", Description: "リモートブランチも削除する"},
+	{Name: "remove.ignored-threshold", Kind: KindString, Default: "50MB", Description: "削除前に報告する ignore 対象ファイルの最小サイズ（0 で確認しない）"},
+	{Name: "prune.dry-run", Kind: KindBool, Default: "false", Description: "削除される予定のものを表示するだけ"},
+	{Name: "prune.verbose", Kind: KindBool, Default: "true", Description: "詳細表示"},
+	{Name: "gc.base", Kind: KindString, Description: "マージ判定に使う base ref"},
+	{Name: "gc.merged", Kind: KindBool, Default: "true", Description: "base にマージ済みのブランチを対象にする"},
+	{Name: "gc.gone", Kind: KindBool, Default: "true", Description: "upstream が削除されたブランチを対象にする"},
+	{Name: "gc.stale-days", Kind: KindString, Default: "0", Description: "最後のコミットからこの日数が経った worktree を対象にする（0 で無効）"},
+	{Name: "gc.delete-branch", Kind: KindBool, Default: "false", Description: "マージ済みのローカルブランチも削除する"},
+	{Name: "gc.force", Kind: KindBool, Default: "false", Description: "未コミットの変更がある worktree も削除する"},
+	{Name: "gc.dry-run", Kind: KindBool, Default: "false", Description: "削除計画を表示するだけ"},
+	{Name: "list.porcelain", Kind: KindBool, Default: "false", Description: "機械処理しやすい形式で表示する"},
+	{Name: "list.columns", Kind: KindList, Description: "表示する列（空ならデフォルトの列）"},
+	{Name: "list.base", Kind: KindString, Description: "ahead/behind の比較に使う base ref"},
+	{Name: "switch.create", Kind: KindBool, Default: "false", Description: "worktree が見つからなければ作成する"},
+	{Name: "pr.remote", Kind: KindString, Description: "clove pr で PR を取得するリモート（空なら remote.default）"},
+	{Name: "pr.provider", Kind: KindString, Description: "ホスティングサービス（github / gitlab、空ならリモート URL から判定）"},
+	{Name: "pr.refspec", Kind: KindString, Description: "PR の head の ref（{number} が PR 番号に置き換わる。空なら provider から決定）"},
+	{Name: "open.command", Kind: KindString, Description: "clove open で worktree を開くコマンド（空なら add.open）"},
+	{Name: "hooks.post-add", Kind: KindString, Description: "worktree 作成後に実行するコマンド"},
+	{Name: "hooks.pre-remove", Kind: KindString, Description: "worktree 削除前に実行するコマンド"},
+	{Name: "hooks.post-remove", Kind: KindString, Description: "worktree 削除後に実行するコマンド"},
+	{Name: "hooks.post-prune", Kind: KindString, Description: "prune 後に実行するコマンド"},
+	{Name: "hooks.timeout", Kind: KindString, Default: "5m", Description: "hook 1つあたりのタイムアウト（0 で無制限）"},
+	{Name: "hooks.on-error", Kind: KindString, Default: "abort", Description: "hook が失敗したときの動作（abort / warn）"},
+	{Name: "bootstrap.*", Kind: KindString, Description: "bootstrap ごとの設定（<名前>.strategy / <名前>.command）"},
+}
+
+// Keys returns all known key definitions
+func Keys() []KeyDef {
+	return append([]KeyDef(nil), keyDefs...)
+}
+
+// LookupKey returns the definition for the given key
+func LookupKey(name string) (KeyDef, bool) {
+	for _, d := range keyDefs {
+		if d.Name == name {
+			return d, true
+		}
+		// "bootstrap.*" のようなワイルドカード定義
+		if strings.HasSuffix(d.Name, ".*") && strings.HasPrefix(name, strings.TrimSuffix(d.Name, "*")) {
+			d.Name = name
+			return d, true
+		}
+	}
+	return KeyDef{}, false
+}
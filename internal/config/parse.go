@@ -0,0 +1,413 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// entry is a raw value read from a single config file
+type entry struct {
+	items []string
+	list  bool
+}
+
+// isYAML reports whether the file should be parsed as YAML (otherwise TOML)
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// parseFile parses file contents according to its extension
+func parseFile(path string, data []byte) (map[string]entry, error) {
+	if isYAML(path) {
+		return parseYAML(path, string(data))
+	}
+	return parseTOML(path, string(data))
+}
+
+// formatFile serializes entries according to the file extension
+func formatFile(path string, entries map[string]entry) []byte {
+	if isYAML(path) {
+		return []byte(formatYAML(entries))
+	}
+	return []byte(formatTOML(entries))
+}
+
+// parseTOML parses the subset of TOML used by clove config files:
+// [section] tables, key = value pairs, strings, booleans, numbers and string arrays
+func parseTOML(path, data string) (map[string]entry, error) {
+	entries := map[string]entry{}
+	section := ""
+	lines := strings.Split(data, "\n")
+
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		ln := strings.TrimSpace(stripComment(lines[i]))
+		if ln == "" {
+			continue
+		}
+
+		if strings.HasPrefix(ln, "[") {
+			if !strings.HasSuffix(ln, "]") || strings.HasPrefix(ln, "[[") {
+				return nil, fmt.Errorf("%s:%d: 解釈できないテーブル定義です: %s", path, lineNo, ln)
+			}
+			section = strings.TrimSpace(ln[1 : len(ln)-1])
+			if section == "" {
+				return nil, fmt.Errorf("%s:%d: テーブル名が空です", path, lineNo)
+			}
+			continue
+		}
+
+		eq := strings.Index(ln, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("%s:%d: 解釈できない行です: %s", path, lineNo, ln)
+		}
+		key := strings.TrimSpace(ln[:eq])
+		raw := strings.TrimSpace(ln[eq+1:])
+		if key == "" {
+			return nil, fmt.Errorf("%s:%d: キーが空です", path, lineNo)
+		}
+		key = unquoteKey(key)
+		if section != "" {
+			key = section + "." + key
+		}
+
+		// 複数行にまたがる配列は閉じ括弧まで連結する
+		if strings.HasPrefix(raw, "[") {
+			for !strings.HasSuffix(raw, "]") && i+1 < len(lines) {
+				i++
+				raw += " " + strings.TrimSpace(stripComment(lines[i]))
+			}
+		}
+
+		e, err := parseTOMLValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s: %w", path, lineNo, key, err)
+		}
+		entries[key] = e
+	}
+
+	return entries, nil
+}
+
+// parseTOMLValue parses a single TOML value
+func parseTOMLValue(raw string) (entry, error) {
+	if raw == "" {
+		return entry{}, fmt.Errorf("値が空です")
+	}
+	if strings.HasPrefix(raw, "[") {
+		if !strings.HasSuffix(raw, "]") {
+			return entry{}, fmt.Errorf("配列が閉じられていません")
+		}
+		items, err := splitList(raw[1 : len(raw)-1])
+		if err != nil {
+			return entry{}, err
+		}
+		return entry{items: items, list: true}, nil
+	}
+	s, err := parseScalar(raw)
+	if err != nil {
+		return entry{}, err
+	}
+	return entry{items: []string{s}}, nil
+}
+
+// parseYAML parses the subset of YAML used by clove config files:
+// nested mappings, scalars, block lists ("- item") and flow lists ("[a, b]")
+func parseYAML(path, data string) (map[string]entry, error) {
+	type frame struct {
+		indent int
+		prefix string
+	}
+
+	entries := map[string]entry{}
+	var stack []frame
+	listKey := ""
+	listIndent := -1
+
+	for i, line := range strings.Split(data, "\n") {
+		lineNo := i + 1
+		line = strings.TrimRight(stripComment(line), " \t\r")
+		if strings.TrimSpace(line) == "" || strings.TrimSpace(line) == "---" {
+			continue
+		}
+		if strings.Contains(line, "\t") {
+			return nil, fmt.Errorf("%s:%d: インデントにタブは使えません", path, lineNo)
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		ln := strings.TrimSpace(line)
+
+		if ln == "-" || strings.HasPrefix(ln, "- ") {
+			if listKey == "" || indent < listIndent {
+				return nil, fmt.Errorf("%s:%d: リスト要素の親キーがありません", path, lineNo)
+			}
+			s, err := parseScalar(strings.TrimSpace(strings.TrimPrefix(ln, "-")))
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+			}
+			e := entries[listKey]
+			e.items = append(e.items, s)
+			e.list = true
+			entries[listKey] = e
+			continue
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		prefix := ""
+		if len(stack) > 0 {
+			prefix = stack[len(stack)-1].prefix
+		}
+
+		key, raw, ok := splitYAMLPair(ln)
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: 解釈できない行です: %s", path, lineNo, ln)
+		}
+		full := prefix + unquoteKey(key)
+		listKey = ""
+		listIndent = -1
+
+		if raw == "" {
+			// 子要素（マッピングまたはリスト）が続く
+			stack = append(stack, frame{indent: indent, prefix: full + "."})
+			listKey = full
+			listIndent = indent
+			continue
+		}
+
+		if strings.HasPrefix(raw, "[") {
+			if !strings.HasSuffix(raw, "]") {
+				return nil, fmt.Errorf("%s:%d: 配列が閉じられていません", path, lineNo)
+			}
+			items, err := splitList(raw[1 : len(raw)-1])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+			}
+			entries[full] = entry{items: items, list: true}
+			continue
+		}
+
+		s, err := parseScalar(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		entries[full] = entry{items: []string{s}}
+	}
+
+	return entries, nil
+}
+
+// splitYAMLPair splits "key: value" into its parts
+func splitYAMLPair(ln string) (string, string, bool) {
+	if strings.HasSuffix(ln, ":") {
+		return strings.TrimSpace(ln[:len(ln)-1]), "", true
+	}
+	idx := strings.Index(ln, ": ")
+	if idx <= 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(ln[:idx]), strings.TrimSpace(ln[idx+2:]), true
+}
+
+// stripComment removes a trailing "#" comment that is not inside quotes
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseScalar parses a quoted or bare scalar value
+func parseScalar(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"' {
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("文字列を解釈できません: %s", raw)
+		}
+		return s, nil
+	}
+	if len(raw) >= 2 && raw[0] == '\'' && raw[len(raw)-1] == '\'' {
+		return raw[1 : len(raw)-1], nil
+	}
+	if strings.HasPrefix(raw, "\"") || strings.HasPrefix(raw, "'") {
+		return "", fmt.Errorf("文字列が閉じられていません: %s", raw)
+	}
+	return raw, nil
+}
+
+// splitList splits the inside of "[...]" into scalar items
+func splitList(inner string) ([]string, error) {
+	var items []string
+	var cur strings.Builder
+	var quote byte
+
+	flush := func() error {
+		s := strings.TrimSpace(cur.String())
+		cur.Reset()
+		if s == "" {
+			return nil
+		}
+		v, err := parseScalar(s)
+		if err != nil {
+			return err
+		}
+		items = append(items, v)
+		return nil
+	}
+
+	for i := 0; i < len(inner); i++ {
+		c := inner[i]
+		switch {
+		case quote != 0:
+			cur.WriteByte(c)
+			if c == '\\' && quote == '"' && i+1 < len(inner) {
+				i++
+				cur.WriteByte(inner[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			cur.WriteByte(c)
+		case c == ',':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("文字列が閉じられていません")
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// unquoteKey removes surrounding quotes from a key
+func unquoteKey(key string) string {
+	if s, err := parseScalar(key); err == nil {
+		return s
+	}
+	return key
+}
+
+// formatScalar renders a scalar, leaving booleans unquoted for bool keys
+func formatScalar(key, v string) string {
+	if def, ok := LookupKey(key); ok && def.Kind == KindBool {
+		return v
+	}
+	return strconv.Quote(v)
+}
+
+// formatList renders a flow list
+func formatList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, it := range items {
+		quoted[i] = strconv.Quote(it)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// formatTOML serializes entries as TOML grouped by table
+func formatTOML(entries map[string]entry) string {
+	sections := map[string][]string{}
+	for key := range entries {
+		section, name := "", key
+		if idx := strings.LastIndex(key, "."); idx >= 0 {
+			section, name = key[:idx], key[idx+1:]
+		}
+		sections[section] = append(sections[section], name)
+	}
+
+	names := make([]string, 0, len(sections))
+	for s := range sections {
+		names = append(names, s)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for i, section := range names {
+		if section != "" {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "[%s]\n", section)
+		}
+		keys := sections[section]
+		sort.Strings(keys)
+		for _, name := range keys {
+			full := name
+			if section != "" {
+				full = section + "." + name
+			}
+			e := entries[full]
+			if e.list {
+				fmt.Fprintf(&b, "%s = %s\n", name, formatList(e.items))
+			} else {
+				fmt.Fprintf(&b, "%s = %s\n", name, formatScalar(full, first(e.items)))
+			}
+		}
+	}
+	return b.String()
+}
+
+// formatYAML serializes entries as nested YAML mappings
+func formatYAML(entries map[string]entry) string {
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	var prev []string
+	for _, key := range keys {
+		parts := strings.Split(key, ".")
+		common := 0
+		for common < len(prev)-1 && common < len(parts)-1 && prev[common] == parts[common] {
+			common++
+		}
+		for depth := common; depth < len(parts)-1; depth++ {
+			fmt.Fprintf(&b, "%s%s:\n", strings.Repeat("  ", depth), parts[depth])
+		}
+		indent := strings.Repeat("  ", len(parts)-1)
+		name := parts[len(parts)-1]
+		e := entries[key]
+		if e.list {
+			fmt.Fprintf(&b, "%s%s: %s\n", indent, name, formatList(e.items))
+		} else {
+			fmt.Fprintf(&b, "%s%s: %s\n", indent, name, formatScalar(key, first(e.items)))
+		}
+		prev = parts
+	}
+	return b.String()
+}
+
+// first returns the first item or an empty string
+func first(items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	return items[0]
+}
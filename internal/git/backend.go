@@ -0,0 +1,163 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatusCounts summarizes `git status` for a worktree
+type StatusCounts struct {
+	// Dirty counts tracked paths with staged or unstaged changes
+	Dirty int
+	// Untracked counts untracked paths; a wholly untracked directory counts once
+	Untracked int
+}
+
+// Backend answers read-only repository queries. Commands that modify the
+// repository always run the git executable.
+type Backend interface {
+	// Name identifies the backend in the git.backend config key
+	Name() string
+	// ResolveRef returns the commit id ref resolves to in the repository at dir
+	ResolveRef(ctx context.Context, dir, ref string) (string, error)
+	// Upstream returns the short name of the upstream of HEAD, e.g. origin/main
+	Upstream(ctx context.Context, dir string) (string, error)
+	// AheadBehind counts commits reachable from a but not b, and from b but not a
+	AheadBehind(ctx context.Context, dir, a, b string) (int, int, error)
+	// Status counts the changed and untracked paths of the worktree at dir
+	Status(ctx context.Context, dir string) (StatusCounts, error)
+	// LastCommit returns the committer time of HEAD
+	LastCommit(ctx context.Context, dir string) (time.Time, error)
+}
+
+// backends lists the selectable backends by name
+var backends = map[string]Backend{
+	"exec":   execBackend{},
+	"native": nativeBackend{},
+}
+
+// current is the backend used by the package-level query functions
+var current Backend = execBackend{}
+
+// BackendNames returns the names accepted by UseBackend
+func BackendNames() []string {
+	return []string{"exec", "native"}
+}
+
+// UseBackend selects the backend for read-only queries ("" selects exec).
+// It must be called before queries run concurrently.
+func UseBackend(name string) error {
+	if name == "" {
+		name = "exec"
+	}
+	b, ok := backends[name]
+	if !ok {
+		return fmt.Errorf("未知の git backend です: %s（使用可能: %s）", name, strings.Join(BackendNames(), ", "))
+	}
+	current = b
+	return nil
+}
+
+// CurrentBackend returns the selected backend
+func CurrentBackend() Backend {
+	return current
+}
+
+// ResolveRef returns the commit id of ref using the selected backend
+func ResolveRef(ctx context.Context, dir, ref string) (string, error) {
+	return current.ResolveRef(ctx, dir, ref)
+}
+
+// Upstream returns the upstream of HEAD using the selected backend
+func Upstream(ctx context.Context, dir string) (string, error) {
+	return current.Upstream(ctx, dir)
+}
+
+// AheadBehind counts diverging commits using the selected backend
+func AheadBehind(ctx context.Context, dir, a, b string) (int, int, error) {
+	return current.AheadBehind(ctx, dir, a, b)
+}
+
+// Status counts changed and untracked paths using the selected backend
+func Status(ctx context.Context, dir string) (StatusCounts, error) {
+	return current.Status(ctx, dir)
+}
+
+// LastCommit returns the time of HEAD using the selected backend
+func LastCommit(ctx context.Context, dir string) (time.Time, error) {
+	return current.LastCommit(ctx, dir)
+}
+
+// execBackend runs the git executable for every query
+type execBackend struct{}
+
+func (execBackend) Name() string { return "exec" }
+
+func (execBackend) ResolveRef(ctx context.Context, dir, ref string) (string, error) {
+	out, err := Git(ctx, dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("ref が見つかりません: %s", ref)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (execBackend) Upstream(ctx context.Context, dir string) (string, error) {
+	out, err := Git(ctx, dir, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (execBackend) AheadBehind(ctx context.Context, dir, a, b string) (int, int, error) {
+	out, err := Git(ctx, dir, "rev-list", "--left-right", "--count", a+"..."+b)
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("rev-list の出力を解釈できません: %q", out)
+	}
+	ahead, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	behind, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return ahead, behind, nil
+}
+
+func (execBackend) Status(ctx context.Context, dir string) (StatusCounts, error) {
+	var c StatusCounts
+	out, err := Git(ctx, dir, "status", "--porcelain")
+	if err != nil {
+		return c, err
+	}
+	for _, ln := range strings.Split(out, "\n") {
+		switch {
+		case ln == "":
+		case strings.HasPrefix(ln, "??"):
+			c.Untracked++
+		default:
+			c.Dirty++
+		}
+	}
+	return c, nil
+}
+
+func (execBackend) LastCommit(ctx context.Context, dir string) (time.Time, error) {
+	out, err := Git(ctx, dir, "log", "-1", "--format=%ct")
+	if err != nil {
+		return time.Time{}, err
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
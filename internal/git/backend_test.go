@@ -0,0 +1,159 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// backendRepo creates a repository with a diverged feature branch checked out in a
+// linked worktree, an upstream, and changed, untracked and ignored files
+func backendRepo(t *testing.T) (repo, wt string) {
+	t.Helper()
+	tmp := t.TempDir()
+	remote := filepath.Join(tmp, "remote")
+	initRepo(t, remote, "main")
+
+	repo = filepath.Join(tmp, "repo")
+	if _, err := Git(context.Background(), "", "clone", "-q", remote, repo); err != nil {
+		t.Fatal(err)
+	}
+	wt = filepath.Join(tmp, "wt")
+	run := func(dir string, args ...string) {
+		t.Helper()
+		if _, err := Git(context.Background(), dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		p := filepath.Join(wt, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run(repo, "worktree", "add", "-q", "-b", "feature", wt, "origin/main")
+	write(".gitignore", "*.log\n")
+	write("src/a.txt", "a\n")
+	write("b.txt", "b\n")
+	run(wt, "add", ".")
+	run(wt, "commit", "-q", "-m", "feature 1")
+	write("b.txt", "b2\n")
+	run(wt, "commit", "-q", "-am", "feature 2")
+
+	// main を 1 コミット進めて、feature とマージしたコミットを作る
+	run(remote, "commit", "-q", "--allow-empty", "-m", "main 1")
+	run(repo, "fetch", "-q", "origin")
+	run(wt, "merge", "-q", "--no-edit", "origin/main")
+	run(remote, "commit", "-q", "--allow-empty", "-m", "main 2")
+	run(repo, "fetch", "-q", "origin")
+	run(wt, "branch", "-q", "--set-upstream-to", "origin/main")
+
+	// 変更 2 件（変更・ステージ済み追加）と未追跡 2 件（ファイルと丸ごと未追跡のディレクトリ）
+	write("b.txt", "b3\n")
+	write("src/staged.txt", "s\n")
+	run(wt, "add", "src/staged.txt")
+	write("src/new.txt", "n\n")
+	write("tmp/x/1.txt", "1\n")
+	write("tmp/2.txt", "2\n")
+	write("debug.log", "ignored\n")
+	return repo, wt
+}
+
+func TestBackends(t *testing.T) {
+	repo, wt := backendRepo(t)
+	head, err := Git(context.Background(), wt, "rev-parse", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	head = strings.TrimSpace(head)
+
+	for _, name := range BackendNames() {
+		t.Run(name, func(t *testing.T) {
+			if err := UseBackend(name); err != nil {
+				t.Fatal(err)
+			}
+			defer UseBackend("")
+			b := CurrentBackend()
+
+			for _, ref := range []string{"HEAD", "feature", "refs/heads/feature", "origin/main"} {
+				if _, err := b.ResolveRef(context.Background(), repo, ref); err != nil {
+					t.Errorf("ResolveRef(%q) failed: %v", ref, err)
+				}
+			}
+			if got, err := b.ResolveRef(context.Background(), wt, "HEAD"); err != nil || got != head {
+				t.Errorf("ResolveRef(wt, HEAD) = %q, %v; want %q", got, err, head)
+			}
+			if _, err := b.ResolveRef(context.Background(), repo, "refs/heads/missing"); err == nil {
+				t.Error("ResolveRef should fail for a missing ref")
+			}
+
+			if got, err := b.Upstream(context.Background(), wt); err != nil || got != "origin/main" {
+				t.Errorf("Upstream(wt) = %q, %v", got, err)
+			}
+			if _, err := b.Upstream(context.Background(), repo); err != nil {
+				t.Errorf("Upstream(repo) failed: %v", err)
+			}
+			if _, err := Git(context.Background(), repo, "checkout", "-q", "--detach"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := b.Upstream(context.Background(), repo); err == nil {
+				t.Error("Upstream should fail on a detached HEAD")
+			}
+			if _, err := Git(context.Background(), repo, "checkout", "-q", "main"); err != nil {
+				t.Fatal(err)
+			}
+
+			// feature: feature 1, feature 2, マージ / origin/main: main 2
+			if a, bh, err := b.AheadBehind(context.Background(), wt, "HEAD", "origin/main"); err != nil || a != 3 || bh != 1 {
+				t.Errorf("AheadBehind = %d, %d, %v; want 3, 1", a, bh, err)
+			}
+			if a, bh, err := b.AheadBehind(context.Background(), wt, "HEAD", "HEAD"); err != nil || a != 0 || bh != 0 {
+				t.Errorf("AheadBehind(HEAD, HEAD) = %d, %d, %v", a, bh, err)
+			}
+
+			if c, err := b.Status(context.Background(), wt); err != nil || c.Dirty != 2 || c.Untracked != 2 {
+				t.Errorf("Status(wt) = %+v, %v; want 2 dirty, 2 untracked", c, err)
+			}
+			if c, err := b.Status(context.Background(), repo); err != nil || c.Dirty != 0 || c.Untracked != 0 {
+				t.Errorf("Status(repo) = %+v, %v; want clean", c, err)
+			}
+
+			if got, err := b.LastCommit(context.Background(), wt); err != nil || got.IsZero() {
+				t.Errorf("LastCommit = %v, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestUseBackend(t *testing.T) {
+	defer UseBackend("")
+	if err := UseBackend("native"); err != nil || CurrentBackend().Name() != "native" {
+		t.Errorf("UseBackend(native) = %v", err)
+	}
+	if err := UseBackend(""); err != nil || CurrentBackend().Name() != "exec" {
+		t.Errorf("UseBackend(\"\") = %v", err)
+	}
+	if err := UseBackend("libgit2"); err == nil {
+		t.Error("UseBackend should fail for an unknown backend")
+	}
+}
+
+func TestCollapseUntracked(t *testing.T) {
+	tracked := map[string]bool{"src": true, "src/lib": true}
+	got := collapseUntracked([]string{"a.txt", "src/new.txt", "src/lib/x.go", "tmp/1", "tmp/sub/2", "src/gen/y"}, tracked)
+	for _, want := range []string{"a.txt", "src/new.txt", "src/lib/x.go", "tmp/", "src/gen/"} {
+		if !got[want] {
+			t.Errorf("collapseUntracked missing %q: %v", want, got)
+		}
+	}
+	if len(got) != 5 {
+		t.Errorf("collapseUntracked = %v", got)
+	}
+}
@@ -2,46 +2,118 @@ package git
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/manattan/clove/internal/util"
 )
 
-// Git executes a git command and returns stdout/stderr
-func Git(repoRoot string, args ...string) (string, error) {
-	var cmd *exec.Cmd
+// timeout limits each git command; 0 means no limit
+var timeout time.Duration
+
+// waitDelay is how long a cancelled command may take to exit after SIGINT before
+// it is killed and its output pipes are closed
+var waitDelay = 5 * time.Second
+
+// SetTimeout limits how long a single git command may run (0 disables the limit).
+// It must be called before commands run concurrently.
+func SetTimeout(d time.Duration) {
+	timeout = d
+}
+
+// Command builds a command that is interrupted with SIGINT when ctx is done,
+// and killed if it does not exit within a few seconds after that
+func Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = waitDelay
+	return cmd
+}
+
+// runGit runs git in repoRoot under the configured timeout with the given I/O
+func runGit(ctx context.Context, repoRoot string, stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 	if repoRoot != "" {
-		a := append([]string{"-C", repoRoot}, args...)
-		cmd = exec.Command("git", a...)
-	} else {
-		cmd = exec.Command("git", args...)
+		args = append([]string{"-C", repoRoot}, args...)
 	}
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
+	cmd := Command(ctx, "git", args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
 	err := cmd.Run()
-	if err != nil {
+	if err != nil && ctx.Err() != nil {
+		return interrupted(ctx, cmd.Args)
+	}
+	return err
+}
+
+// interrupted explains why a command was stopped by ctx
+func interrupted(ctx context.Context, args []string) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s がタイムアウトしました（%s）: %w", util.ShellJoin(args), timeout, ctx.Err())
+	}
+	return fmt.Errorf("%s は中断されました: %w", util.ShellJoin(args), ctx.Err())
+}
+
+// Git executes a git command and returns stdout/stderr
+func Git(ctx context.Context, repoRoot string, args ...string) (string, error) {
+	var out bytes.Buffer
+	if err := runGit(ctx, repoRoot, nil, &out, &out, args...); err != nil {
+		if IsInterrupted(err) {
+			return "", err
+		}
+		return "", fmt.Errorf("%w: %s", err, out.String())
+	}
+	return out.String(), nil
+}
+
+// GitInput executes a git command with input on stdin and returns stdout/stderr
+func GitInput(ctx context.Context, repoRoot, input string, args ...string) (string, error) {
+	var out bytes.Buffer
+	if err := runGit(ctx, repoRoot, strings.NewReader(input), &out, &out, args...); err != nil {
+		if IsInterrupted(err) {
+			return "", err
+		}
 		return "", fmt.Errorf("%w: %s", err, out.String())
 	}
 	return out.String(), nil
 }
 
 // GitOk checks if git command succeeds
-func GitOk(repoRoot string, args ...string) bool {
-	var cmd *exec.Cmd
-	if repoRoot != "" {
-		a := append([]string{"-C", repoRoot}, args...)
-		cmd = exec.Command("git", a...)
-	} else {
-		cmd = exec.Command("git", args...)
+func GitOk(ctx context.Context, repoRoot string, args ...string) bool {
+	return runGit(ctx, repoRoot, nil, nil, nil, args...) == nil
+}
+
+// Run executes a command with stdout/stderr attached.
+// git commands are limited by the configured timeout.
+func Run(ctx context.Context, name string, args ...string) error {
+	w := util.OutputOf(ctx)
+	if name == "git" {
+		return runGit(ctx, "", nil, w, w, args...)
+	}
+	cmd := Command(ctx, name, args...)
+	cmd.Stdout = w
+	cmd.Stderr = w // エラー出力も標準出力に出す
+	err := cmd.Run()
+	if err != nil && ctx.Err() != nil {
+		return interrupted(ctx, cmd.Args)
 	}
-	return cmd.Run() == nil
+	return err
 }
 
-// Run executes a command with stdout/stderr attached
-func Run(name string, args ...string) error {
-	cmd := exec.Command(name, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stdout // エラー出力も標準出力に出す
-	return cmd.Run()
+// IsInterrupted reports whether err was caused by cancellation or a timeout
+func IsInterrupted(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
 }
@@ -1,13 +1,18 @@
 package git
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestGit(t *testing.T) {
 	// Test that git command can be executed (requires git to be installed)
-	out, err := Git("", "version")
+	out, err := Git(context.Background(), "", "version")
 	if err != nil {
 		t.Fatalf("Git version failed: %v", err)
 	}
@@ -16,21 +21,32 @@ func TestGit(t *testing.T) {
 	}
 }
 
+func TestGitInput(t *testing.T) {
+	out, err := GitInput(context.Background(), "", "hello\n", "hash-object", "--stdin")
+	if err != nil {
+		t.Fatalf("GitInput failed: %v", err)
+	}
+	// git hash-object of "hello\n" is well known
+	if strings.TrimSpace(out) != "ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("unexpected hash: %s", out)
+	}
+}
+
 func TestGitOk(t *testing.T) {
 	// Test with a command that should succeed
-	if !GitOk("", "version") {
+	if !GitOk(context.Background(), "", "version") {
 		t.Error("GitOk(version) should return true")
 	}
 
 	// Test with a command that should fail
-	if GitOk("", "invalid-command-that-does-not-exist") {
+	if GitOk(context.Background(), "", "invalid-command-that-does-not-exist") {
 		t.Error("GitOk(invalid-command) should return false")
 	}
 }
 
 func TestGetRepoRoot(t *testing.T) {
 	// This test requires running in a git repository
-	root, err := GetRepoRoot()
+	root, err := GetRepoRoot(context.Background())
 	if err != nil {
 		t.Skipf("Not in a git repository, skipping: %v", err)
 	}
@@ -42,23 +58,195 @@ func TestGetRepoRoot(t *testing.T) {
 	}
 }
 
-func TestGetOriginHead(t *testing.T) {
-	// This test requires running in a git repository
-	root, err := GetRepoRoot()
-	if err != nil {
-		t.Skipf("Not in a git repository, skipping: %v", err)
+// initRepo creates a repository with one commit on branch
+func initRepo(t *testing.T, dir, branch string) {
+	t.Helper()
+	t.Setenv("GIT_AUTHOR_NAME", "clove")
+	t.Setenv("GIT_AUTHOR_EMAIL", "clove@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "clove")
+	t.Setenv("GIT_COMMITTER_EMAIL", "clove@example.com")
+	// 利用者の init.defaultBranch などに左右されないようにする
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	if _, err := Git(context.Background(), "", "init", "-q", "-b", branch, dir); err != nil {
+		t.Skipf("git init failed: %v", err)
+	}
+	if _, err := Git(context.Background(), dir, "commit", "-q", "--allow-empty", "-m", "initial"); err != nil {
+		t.Fatal(err)
 	}
+}
 
-	head, err := GetOriginHead(root)
+func TestOpenRepository(t *testing.T) {
+	tmp, err := filepath.EvalSymlinks(t.TempDir())
 	if err != nil {
-		t.Fatalf("GetOriginHead failed: %v", err)
+		t.Fatal(err)
+	}
+	main := filepath.Join(tmp, "app")
+	initRepo(t, main, "main")
+	linked := filepath.Join(tmp, "app-feature")
+	if _, err := Git(context.Background(), main, "worktree", "add", "-q", "-b", "feature", linked); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(linked, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(dir, wantCurrent string) {
+		t.Helper()
+		repo, err := OpenRepository(context.Background(), dir)
+		if err != nil {
+			t.Fatalf("OpenRepository(%s) failed: %v", dir, err)
+		}
+		want := Repository{Main: main, Current: wantCurrent, CommonDir: filepath.Join(main, ".git")}
+		if *repo != want {
+			t.Errorf("OpenRepository(%s) = %+v, want %+v", dir, *repo, want)
+		}
+	}
+	check(main, main)
+	check(linked, linked)
+	check(filepath.Join(linked, "sub"), linked)
+
+	// bare リポジトリではリポジトリのディレクトリがメインになる
+	bare := filepath.Join(tmp, "bare.git")
+	if _, err := Git(context.Background(), "", "clone", "-q", "--bare", main, bare); err != nil {
+		t.Fatal(err)
+	}
+	wt := filepath.Join(tmp, "bare-main")
+	if _, err := Git(context.Background(), bare, "worktree", "add", "-q", wt, "main"); err != nil {
+		t.Fatal(err)
+	}
+	for dir, current := range map[string]string{bare: "", wt: wt} {
+		repo, err := OpenRepository(context.Background(), dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Repository{Main: bare, Current: current, CommonDir: bare, Bare: true}
+		if *repo != want {
+			t.Errorf("OpenRepository(%s) = %+v, want %+v", dir, *repo, want)
+		}
+	}
+
+	// <dir>/.bare と gitdir: ./.bare の .git ファイルでは <dir> がメインになる
+	container := filepath.Join(tmp, "container")
+	if _, err := Git(context.Background(), "", "clone", "-q", "--bare", main, filepath.Join(container, ".bare")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(container, ".git"), []byte("gitdir: ./.bare\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cwt := filepath.Join(container, "main")
+	if _, err := Git(context.Background(), container, "worktree", "add", "-q", cwt, "main"); err != nil {
+		t.Fatal(err)
+	}
+	for dir, current := range map[string]string{container: "", cwt: cwt} {
+		repo, err := OpenRepository(context.Background(), dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Repository{Main: container, Current: current, CommonDir: filepath.Join(container, ".bare"), Bare: true}
+		if *repo != want {
+			t.Errorf("OpenRepository(%s) = %+v, want %+v", dir, *repo, want)
+		}
+	}
+
+	// .git が worktree の外にあるリポジトリ
+	sep := filepath.Join(tmp, "sep")
+	if _, err := Git(context.Background(), "", "init", "-q", "--separate-git-dir", filepath.Join(tmp, "sep.git"), sep); err != nil {
+		t.Fatal(err)
+	}
+	if repo, err := OpenRepository(context.Background(), sep); err != nil || repo.Main != sep {
+		t.Errorf("OpenRepository(separate git dir) = %+v, %v", repo, err)
 	}
 
-	// Should either return origin/HEAD or fallback to origin/main
-	if head == "" {
-		t.Error("GetOriginHead returned empty string")
+	if _, err := OpenRepository(context.Background(), tmp); err == nil {
+		t.Error("OpenRepository should fail outside a repository")
+	}
+}
+
+func TestResolveBase(t *testing.T) {
+	tmp := t.TempDir()
+	upstream := filepath.Join(tmp, "upstream")
+	initRepo(t, upstream, "develop")
+
+	// develop だけを持ち、origin/HEAD が未設定のクローン
+	work := filepath.Join(tmp, "work")
+	initRepo(t, work, "trunk")
+	for _, args := range [][]string{
+		{"remote", "add", "origin", upstream},
+		{"fetch", "-q", "origin"},
+	} {
+		if _, err := Git(context.Background(), work, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(explicit string, online bool, wantRef string, wantSrc BaseSource) {
+		t.Helper()
+		ref, src, err := ResolveBase(context.Background(), work, explicit, "origin", online)
+		if err != nil || ref != wantRef || src != wantSrc {
+			t.Errorf("ResolveBase(%q, online=%v) = %q, %q, %v; want %q, %q", explicit, online, ref, src, err, wantRef, wantSrc)
+		}
+	}
+
+	check("trunk", false, "trunk", BaseExplicit)
+	if _, _, err := ResolveBase(context.Background(), work, "origin/missing", "origin", false); err == nil {
+		t.Error("ResolveBase should fail for a missing explicit ref")
+	}
+	check("", false, "origin/develop", BaseCandidate)
+
+	if _, err := Git(context.Background(), work, "config", "init.defaultBranch", "develop"); err != nil {
+		t.Fatal(err)
+	}
+	check("", false, "origin/develop", BaseInitDefault)
+
+	// ls-remote の結果は origin/HEAD として保存される
+	check("", true, "origin/develop", BaseLsRemote)
+	check("", false, "origin/develop", BaseRemoteHead)
+
+	// リモートがなければローカルのブランチから探す
+	if _, err := Git(context.Background(), work, "remote", "remove", "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Git(context.Background(), work, "config", "--unset", "init.defaultBranch"); err != nil {
+		t.Fatal(err)
+	}
+	check("", false, "trunk", BaseCandidate)
+
+	if _, err := Git(context.Background(), work, "branch", "-m", "trunk", "weird"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ResolveBase(context.Background(), work, "", "origin", false); err == nil {
+		t.Error("ResolveBase should fail without any candidate branch")
+	}
+}
+
+func TestGit_Timeout(t *testing.T) {
+	SetTimeout(200 * time.Millisecond)
+	defer SetTimeout(0)
+	defer func(d time.Duration) { waitDelay = d }(waitDelay)
+	waitDelay = 500 * time.Millisecond
+
+	start := time.Now()
+	_, err := Git(context.Background(), "", "-c", "alias.slow=!sleep 10", "slow")
+	if !IsInterrupted(err) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Git should time out, got %v", err)
+	}
+	// SIGINT で止まらない子プロセスが残っても、waitDelay の後には戻る
+	if d := time.Since(start); d > 3*time.Second {
+		t.Errorf("Git took %s after the timeout", d)
+	}
+}
+
+func TestGit_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Git(ctx, "", "version"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Git with a cancelled context = %v", err)
+	}
+	if GitOk(ctx, "", "version") {
+		t.Error("GitOk with a cancelled context should fail")
 	}
-	if !strings.HasPrefix(head, "origin/") {
-		t.Errorf("GetOriginHead should return origin/* ref, got: %s", head)
+	if err := Run(ctx, "true"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run with a cancelled context = %v", err)
 	}
 }
@@ -0,0 +1,266 @@
+package git
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/manattan/clove/internal/util"
+)
+
+// nativeBackend answers queries in-process with go-git.
+// Repositories go-git cannot open (e.g. unsupported extensions) are handled by the exec backend.
+type nativeBackend struct {
+	exec execBackend
+}
+
+func (nativeBackend) Name() string { return "native" }
+
+// openRepo opens the repository containing dir, following the .git file of linked worktrees
+func openRepo(dir string) (*gogit.Repository, error) {
+	repo, err := gogit.PlainOpenWithOptions(dir, &gogit.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+	if err != nil {
+		util.Verbose("[verbose] go-git で %s を開けないため git コマンドを使います: %v", dir, err)
+	}
+	return repo, err
+}
+
+// resolve returns the commit ref points to
+func resolve(repo *gogit.Repository, ref string) (*object.Commit, error) {
+	h, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, fmt.Errorf("ref が見つかりません: %s", ref)
+	}
+	c, err := repo.CommitObject(*h)
+	if err != nil {
+		return nil, fmt.Errorf("ref がコミットを指していません: %s", ref)
+	}
+	return c, nil
+}
+
+func (b nativeBackend) ResolveRef(ctx context.Context, dir, ref string) (string, error) {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return b.exec.ResolveRef(ctx, dir, ref)
+	}
+	c, err := resolve(repo, ref)
+	if err != nil {
+		return "", err
+	}
+	return c.Hash.String(), nil
+}
+
+func (b nativeBackend) Upstream(ctx context.Context, dir string) (string, error) {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return b.exec.Upstream(ctx, dir)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	if !head.Name().IsBranch() {
+		return "", errors.New("HEAD がブランチを指していません")
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return "", err
+	}
+	br, ok := cfg.Branches[head.Name().Short()]
+	if !ok || br.Remote == "" || br.Merge == "" {
+		return "", fmt.Errorf("%s に upstream が設定されていません", head.Name().Short())
+	}
+
+	// git と同じく、upstream の ref が実在しなければエラーにする
+	name, tracking := br.Merge.Short(), br.Merge
+	if br.Remote != "." {
+		name = br.Remote + "/" + br.Merge.Short()
+		tracking = plumbing.NewRemoteReferenceName(br.Remote, br.Merge.Short())
+	}
+	if _, err := repo.Reference(tracking, true); err != nil {
+		return "", fmt.Errorf("upstream が見つかりません: %s", name)
+	}
+	return name, nil
+}
+
+// Flags painted on commits while walking history from both sides
+const (
+	fromA = 1 << iota
+	fromB
+)
+
+func (b nativeBackend) AheadBehind(ctx context.Context, dir, refA, refB string) (int, int, error) {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return b.exec.AheadBehind(ctx, dir, refA, refB)
+	}
+	a, err := resolve(repo, refA)
+	if err != nil {
+		return 0, 0, err
+	}
+	c, err := resolve(repo, refB)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// git rev-list --left-right と同じく、新しいコミットから順に両側の印を祖先へ伝え、
+	// 待ち行列がすべて両側から到達済みになったら打ち切る
+	flags := map[plumbing.Hash]int{a.Hash: fromA}
+	flags[c.Hash] |= fromB
+	q := &commitQueue{a}
+	if c.Hash != a.Hash {
+		q.Push(c)
+	}
+	heap.Init(q)
+	for q.Len() > 0 && !q.stale(flags) {
+		if err := ctx.Err(); err != nil {
+			return 0, 0, err
+		}
+		cur := heap.Pop(q).(*object.Commit)
+		f := flags[cur.Hash]
+		for _, ph := range cur.ParentHashes {
+			if flags[ph]|f == flags[ph] {
+				continue
+			}
+			flags[ph] |= f
+			p, err := repo.CommitObject(ph)
+			if err != nil {
+				// shallow clone の境界など
+				continue
+			}
+			heap.Push(q, p)
+		}
+	}
+
+	ahead, behind := 0, 0
+	for _, f := range flags {
+		switch f {
+		case fromA:
+			ahead++
+		case fromB:
+			behind++
+		}
+	}
+	return ahead, behind, nil
+}
+
+// commitQueue orders commits newest first
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int            { return len(q) }
+func (q commitQueue) Less(i, j int) bool  { return q[i].Committer.When.After(q[j].Committer.When) }
+func (q commitQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x interface{}) { *q = append(*q, x.(*object.Commit)) }
+func (q *commitQueue) Pop() interface{} {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// stale reports whether every queued commit is reachable from both sides
+func (q commitQueue) stale(flags map[plumbing.Hash]int) bool {
+	for _, c := range q {
+		if flags[c.Hash] != fromA|fromB {
+			return false
+		}
+	}
+	return true
+}
+
+func (b nativeBackend) Status(ctx context.Context, dir string) (StatusCounts, error) {
+	var counts StatusCounts
+	repo, err := openRepo(dir)
+	if err != nil {
+		return b.exec.Status(ctx, dir)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return counts, err
+	}
+	// go-git はリポジトリ内の .gitignore しか見ないため、git と同じくグローバル・システムの設定も読む
+	root := osfs.New("/")
+	if ps, err := gitignore.LoadGlobalPatterns(root); err == nil {
+		wt.Excludes = append(wt.Excludes, ps...)
+	}
+	if ps, err := gitignore.LoadSystemPatterns(root); err == nil {
+		wt.Excludes = append(wt.Excludes, ps...)
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return counts, err
+	}
+
+	var untracked []string
+	for p, fs := range status {
+		switch {
+		case fs.Worktree == gogit.Untracked && fs.Staging == gogit.Untracked:
+			untracked = append(untracked, p)
+		case fs.Worktree != gogit.Unmodified || fs.Staging != gogit.Unmodified:
+			counts.Dirty++
+		}
+	}
+	if len(untracked) > 0 {
+		tracked, err := trackedDirs(repo)
+		if err != nil {
+			return counts, err
+		}
+		counts.Untracked = len(collapseUntracked(untracked, tracked))
+	}
+	return counts, nil
+}
+
+// trackedDirs returns every directory containing a file in the index
+func trackedDirs(repo *gogit.Repository) (map[string]bool, error) {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	dirs := map[string]bool{}
+	for _, e := range idx.Entries {
+		for d := path.Dir(e.Name); d != "." && !dirs[d]; d = path.Dir(d) {
+			dirs[d] = true
+		}
+	}
+	return dirs, nil
+}
+
+// collapseUntracked reports untracked files the way `git status` does:
+// a directory without tracked files is listed once as "dir/"
+func collapseUntracked(files []string, tracked map[string]bool) map[string]bool {
+	entries := map[string]bool{}
+	for _, f := range files {
+		entry := f
+		parts := strings.Split(f, "/")
+		for i := 1; i < len(parts); i++ {
+			if d := strings.Join(parts[:i], "/"); !tracked[d] {
+				entry = d + "/"
+				break
+			}
+		}
+		entries[entry] = true
+	}
+	return entries
+}
+
+func (b nativeBackend) LastCommit(ctx context.Context, dir string) (time.Time, error) {
+	repo, err := openRepo(dir)
+	if err != nil {
+		return b.exec.LastCommit(ctx, dir)
+	}
+	c, err := resolve(repo, "HEAD")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return c.Committer.When, nil
+}
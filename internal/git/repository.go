@@ -1,30 +1,289 @@
 package git
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+
+	"github.com/manattan/clove/internal/util"
 )
 
-// GetRepoRoot returns the repository root path
-func GetRepoRoot() (string, error) {
-	out, err := Git("", "rev-parse", "--show-toplevel")
+// Repository locates a repository and its worktrees
+type Repository struct {
+	// Main is the main worktree, or the repository directory of a bare repository
+	// (the directory containing it when a .git file there points to it)
+	Main string
+	// Current is the worktree containing the directory the repository was opened from;
+	// empty when opened from a bare repository itself
+	Current string
+	// CommonDir is the git directory shared by every worktree
+	CommonDir string
+	// Bare reports whether the repository has no main worktree
+	Bare bool
+}
+
+// OpenRepository finds the repository containing dir ("" for the working directory).
+// The main worktree is derived from the common git directory, so the result is
+// the same whichever worktree dir belongs to.
+func OpenRepository(ctx context.Context, dir string) (*Repository, error) {
+	base := dir
+	if base == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		base = wd
+	}
+	out, err := Git(ctx, dir, "rev-parse", "--git-common-dir")
+	if err != nil {
+		if IsInterrupted(err) {
+			return nil, err
+		}
+		return nil, errors.New("gitリポジトリではありません")
+	}
+	common := strings.TrimSpace(out)
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(base, common)
+	}
+	repo := &Repository{CommonDir: realPath(common)}
+
+	if out, err := Git(ctx, dir, "rev-parse", "--show-toplevel"); err == nil {
+		repo.Current = realPath(strings.TrimSpace(out))
+	}
+	repo.Bare = IsBare(ctx, dir)
+
+	switch {
+	case repo.Bare:
+		repo.Main = bareRoot(repo.CommonDir)
+	case filepath.Base(repo.CommonDir) == ".git":
+		repo.Main = filepath.Dir(repo.CommonDir)
+	case repo.Current != "" && gitDir(ctx, dir) == repo.CommonDir:
+		// --separate-git-dir などで .git が worktree の外にあるメインの worktree
+		repo.Main = repo.Current
+	default:
+		main, err := mainFromList(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		repo.Main = main
+	}
+	if repo.Main == "" {
+		return nil, errors.New("gitリポジトリではありません")
+	}
+	if repo.Current != "" && repo.Current != repo.Main {
+		util.Verbose("[verbose] linked worktree %s から実行されています。メインの worktree: %s", repo.Current, repo.Main)
+	}
+	return repo, nil
+}
+
+// IsBare reports whether the repository containing dir is bare
+func IsBare(ctx context.Context, dir string) bool {
+	out, err := Git(ctx, dir, "config", "--bool", "core.bare")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// bareRoot returns the directory holding a bare repository laid out as
+// <dir>/.bare with a <dir>/.git file pointing to it, otherwise commonDir itself
+func bareRoot(commonDir string) string {
+	parent := filepath.Dir(commonDir)
+	data, err := os.ReadFile(filepath.Join(parent, ".git"))
+	if err != nil {
+		return commonDir
+	}
+	p, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return commonDir
+	}
+	p = strings.TrimSpace(p)
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(parent, p)
+	}
+	if realPath(p) != commonDir {
+		return commonDir
+	}
+	return parent
+}
+
+// mainFromList returns the first entry of `git worktree list`, which is always the main worktree
+func mainFromList(ctx context.Context, dir string) (string, error) {
+	out, err := Git(ctx, dir, "worktree", "list", "--porcelain")
+	if err != nil {
+		return "", err
+	}
+	line, _, _ := strings.Cut(out, "\n")
+	p, ok := strings.CutPrefix(line, "worktree ")
+	if !ok {
+		return "", fmt.Errorf("git worktree list の出力を解釈できません: %q", line)
+	}
+	return realPath(p), nil
+}
+
+// gitDir returns the git directory of the worktree containing dir
+func gitDir(ctx context.Context, dir string) string {
+	out, err := Git(ctx, dir, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return ""
+	}
+	return realPath(strings.TrimSpace(out))
+}
+
+// realPath resolves symlinks in p when possible
+func realPath(p string) string {
+	if r, err := filepath.EvalSymlinks(p); err == nil {
+		return r
+	}
+	return filepath.Clean(p)
+}
+
+// GetRepoRoot returns the main worktree of the repository containing the working
+// directory, even when clove runs inside a linked worktree
+func GetRepoRoot(ctx context.Context) (string, error) {
+	repo, err := OpenRepository(ctx, "")
 	if err != nil {
 		return "", err
 	}
-	r := strings.TrimSpace(out)
-	if r == "" {
-		return "", errors.New("gitリポジトリではありません")
+	return repo.Main, nil
+}
+
+// Remotes returns the names of the configured remotes
+func Remotes(ctx context.Context, repoRoot string) ([]string, error) {
+	out, err := Git(ctx, repoRoot, "remote")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+// HasRemote reports whether remote is configured
+func HasRemote(ctx context.Context, repoRoot, remote string) bool {
+	remotes, _ := Remotes(ctx, repoRoot)
+	for _, r := range remotes {
+		if r == remote {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRemote returns preferred if given, otherwise checkout.defaultRemote,
+// origin, upstream, or the only configured remote. Falls back to "origin".
+func DefaultRemote(ctx context.Context, repoRoot, preferred string) string {
+	if preferred != "" {
+		return preferred
+	}
+	if out, err := Git(ctx, repoRoot, "config", "--get", "checkout.defaultRemote"); err == nil && strings.TrimSpace(out) != "" {
+		return strings.TrimSpace(out)
+	}
+	for _, name := range []string{"origin", "upstream"} {
+		if HasRemote(ctx, repoRoot, name) {
+			return name
+		}
+	}
+	if remotes, _ := Remotes(ctx, repoRoot); len(remotes) == 1 {
+		return remotes[0]
+	}
+	return "origin"
+}
+
+// BaseSource describes how a base ref was chosen
+type BaseSource string
+
+const (
+	// BaseExplicit is a ref given by --base or the add.base config key
+	BaseExplicit BaseSource = "explicit"
+	// BaseRemoteHead is the symbolic ref <remote>/HEAD
+	BaseRemoteHead BaseSource = "remote-head"
+	// BaseLsRemote is the HEAD reported by `git ls-remote --symref`
+	BaseLsRemote BaseSource = "ls-remote"
+	// BaseInitDefault is the init.defaultBranch setting
+	BaseInitDefault BaseSource = "init.defaultBranch"
+	// BaseCandidate is the first existing branch of DefaultBranchCandidates
+	BaseCandidate BaseSource = "candidate"
+)
+
+// DefaultBranchCandidates are tried in order when nothing names the default branch
+var DefaultBranchCandidates = []string{"main", "master", "develop", "trunk"}
+
+// RefExists reports whether ref resolves to a commit
+func RefExists(ctx context.Context, repoRoot, ref string) bool {
+	_, err := current.ResolveRef(ctx, repoRoot, ref)
+	return err == nil
+}
+
+// ResolveBase returns explicit if it exists, otherwise detects the default branch
+// of remote: <remote>/HEAD, `ls-remote --symref` (only when online; the answer is
+// cached as <remote>/HEAD), init.defaultBranch, then DefaultBranchCandidates.
+// Repositories without the remote fall back to local branches.
+// The returned ref always exists.
+func ResolveBase(ctx context.Context, repoRoot, explicit, remote string, online bool) (string, BaseSource, error) {
+	if explicit != "" {
+		if !RefExists(ctx, repoRoot, explicit) {
+			return "", "", fmt.Errorf("base ref が見つかりません: %s", explicit)
+		}
+		return explicit, BaseExplicit, nil
+	}
+
+	type candidate struct {
+		name   string
+		source BaseSource
 	}
-	return r, nil
+	var candidates []candidate
+	if out, err := Git(ctx, repoRoot, "config", "--get", "init.defaultBranch"); err == nil && strings.TrimSpace(out) != "" {
+		candidates = append(candidates, candidate{strings.TrimSpace(out), BaseInitDefault})
+	}
+	for _, name := range DefaultBranchCandidates {
+		candidates = append(candidates, candidate{name, BaseCandidate})
+	}
+
+	if HasRemote(ctx, repoRoot, remote) {
+		if out, err := Git(ctx, repoRoot, "symbolic-ref", "-q", "--short", "refs/remotes/"+remote+"/HEAD"); err == nil {
+			if ref := strings.TrimSpace(out); RefExists(ctx, repoRoot, ref) {
+				return ref, BaseRemoteHead, nil
+			}
+		}
+		if online {
+			if branch := LsRemoteHead(ctx, repoRoot, remote); branch != "" {
+				tracking := "refs/remotes/" + remote + "/" + branch
+				if RefExists(ctx, repoRoot, tracking) {
+					// git remote set-head -a と同じく、次回からは <remote>/HEAD で解決できるようにする
+					if _, err := Git(ctx, repoRoot, "symbolic-ref", "refs/remotes/"+remote+"/HEAD", tracking); err != nil {
+						util.Verbose("[verbose] %s/HEAD を保存できませんでした: %v", remote, err)
+					}
+					return remote + "/" + branch, BaseLsRemote, nil
+				}
+			}
+		}
+		for _, c := range candidates {
+			if ref := remote + "/" + c.name; RefExists(ctx, repoRoot, "refs/remotes/"+ref) {
+				return ref, c.source, nil
+			}
+		}
+	}
+
+	for _, c := range candidates {
+		if RefExists(ctx, repoRoot, "refs/heads/"+c.name) {
+			return c.name, c.source, nil
+		}
+	}
+	return "", "", fmt.Errorf("%s の既定ブランチを決定できません（--base または設定 add.base で指定してください）", remote)
 }
 
-// GetOriginHead returns the default branch (origin/HEAD)
-// Falls back to "origin/main" if origin/HEAD is not set
-func GetOriginHead(repoRoot string) (string, error) {
-	ref, err := Git(repoRoot, "symbolic-ref", "-q", "--short", "refs/remotes/origin/HEAD")
-	if err == nil {
-		return strings.TrimSpace(ref), nil
+// LsRemoteHead asks remote (a name or URL) for the branch its HEAD points to
+func LsRemoteHead(ctx context.Context, repoRoot, remote string) string {
+	out, err := Git(ctx, repoRoot, "ls-remote", "--symref", remote, "HEAD")
+	if err != nil {
+		util.Verbose("[verbose] git ls-remote %s に失敗しました: %v", remote, err)
+		return ""
+	}
+	// "ref: refs/heads/main\tHEAD"
+	for _, ln := range strings.Split(out, "\n") {
+		if rest, ok := strings.CutPrefix(ln, "ref: "); ok {
+			ref, _, _ := strings.Cut(rest, "\t")
+			return strings.TrimPrefix(ref, "refs/heads/")
+		}
 	}
-	// Fallback to origin/main
-	return "origin/main", nil
+	return ""
 }
@@ -0,0 +1,172 @@
+package hook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/util"
+)
+
+// Event is a lifecycle point at which hooks run
+type Event string
+
+const (
+	// PostAdd runs after a worktree is created
+	PostAdd Event = "post-add"
+	// PreRemove runs before a worktree is removed
+	PreRemove Event = "pre-remove"
+	// PostRemove runs after a worktree is removed
+	PostRemove Event = "post-remove"
+	// PostPrune runs after stale worktree references are pruned
+	PostPrune Event = "post-prune"
+)
+
+// Events lists every supported event
+var Events = []Event{PostAdd, PreRemove, PostRemove, PostPrune}
+
+// Policy decides what happens when a hook exits non-zero
+type Policy string
+
+const (
+	// PolicyAbort stops the operation with an error
+	PolicyAbort Policy = "abort"
+	// PolicyWarn prints a warning and continues
+	PolicyWarn Policy = "warn"
+)
+
+// ParsePolicy validates a policy name
+func ParsePolicy(s string) (Policy, error) {
+	switch Policy(s) {
+	case PolicyAbort, PolicyWarn:
+		return Policy(s), nil
+	}
+	return "", fmt.Errorf("hooks.on-error は abort か warn で指定してください: %s", s)
+}
+
+// DirName is the hook script directory relative to the repository root
+const DirName = ".clove/hooks"
+
+// Config describes which hooks run and how
+type Config struct {
+	// Disabled skips all hooks (--no-hooks)
+	Disabled bool
+	// Commands are shell commands from config, keyed by event
+	Commands map[Event]string
+	// Dir is the directory holding executable hook scripts named after events
+	Dir string
+	// Timeout limits each hook's run time (0: no limit)
+	Timeout time.Duration
+	// OnError is the non-zero exit policy
+	OnError Policy
+}
+
+// Env is the context passed to hooks as CLOVE_* environment variables
+type Env struct {
+	RepoRoot     string
+	WorktreePath string
+	Branch       string
+	BaseRef      string
+}
+
+// environ renders env as KEY=VALUE pairs appended to the current environment
+func (e Env) environ(ev Event) []string {
+	return append(os.Environ(),
+		"CLOVE_HOOK="+string(ev),
+		"CLOVE_REPO_ROOT="+e.RepoRoot,
+		"CLOVE_WORKTREE_PATH="+e.WorktreePath,
+		"CLOVE_BRANCH="+e.Branch,
+		"CLOVE_BASE_REF="+e.BaseRef,
+	)
+}
+
+// Plan returns the commands that would run for ev, for dry-run output
+func (c Config) Plan(ev Event) []string {
+	if c.Disabled {
+		return nil
+	}
+	var cmds []string
+	if s := c.Commands[ev]; s != "" {
+		cmds = append(cmds, s)
+	}
+	if p := c.script(ev); p != "" {
+		cmds = append(cmds, p)
+	}
+	return cmds
+}
+
+// script returns the path of the executable hook script for ev, or ""
+func (c Config) script(ev Event) string {
+	if c.Dir == "" {
+		return ""
+	}
+	p := filepath.Join(c.Dir, string(ev))
+	fi, err := os.Stat(p)
+	if err != nil || fi.IsDir() {
+		return ""
+	}
+	if fi.Mode().Perm()&0o111 == 0 {
+		util.Verbose("[verbose] 実行権限がないため hook をスキップします: %s", p)
+		return ""
+	}
+	return p
+}
+
+// Run executes the hooks for ev in dir.
+// Hooks stop when ctx is cancelled, whatever the OnError policy.
+func (c Config) Run(ctx context.Context, ev Event, env Env, dir string) error {
+	log := util.Log(ctx)
+	if c.Disabled {
+		log.Verbose("[verbose] --no-hooks のため %s hook をスキップします", ev)
+		return nil
+	}
+
+	var cmds [][]string
+	if s := c.Commands[ev]; s != "" {
+		cmds = append(cmds, []string{"sh", "-c", s})
+	}
+	if p := c.script(ev); p != "" {
+		cmds = append(cmds, []string{p})
+	}
+
+	for _, args := range cmds {
+		log.Verbose("[verbose] %s hook を実行中: %s", ev, util.ShellJoin(args))
+		if err := c.exec(ctx, ev, env, dir, args); err != nil {
+			if c.OnError == PolicyWarn && ctx.Err() == nil {
+				log.Printf("警告: %s hook が失敗しました: %v\n", ev, err)
+				continue
+			}
+			return fmt.Errorf("%s hook が失敗しました: %w", ev, err)
+		}
+	}
+	return nil
+}
+
+// exec runs a single hook command with the configured timeout
+func (c Config) exec(ctx context.Context, ev Event, env Env, dir string, args []string) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	cmd := git.Command(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = env.environ(ev)
+	cmd.Stdout = util.OutputOf(ctx)
+	cmd.Stderr = util.OutputOf(ctx)
+	err := cmd.Run()
+	switch {
+	case err == nil:
+	case errors.Is(ctx.Err(), context.DeadlineExceeded) && c.Timeout > 0:
+		// 呼び出し側が git.IsInterrupted で中断と同じく扱えるようにする
+		return fmt.Errorf("%s でタイムアウトしました: %w", c.Timeout, ctx.Err())
+	case ctx.Err() != nil:
+		return fmt.Errorf("中断されました: %w", ctx.Err())
+	}
+	return err
+}
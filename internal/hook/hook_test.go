@@ -0,0 +1,129 @@
+package hook
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun_CommandEnv(t *testing.T) {
+	dir := t.TempDir()
+	c := Config{
+		Commands: map[Event]string{PostAdd: `echo "$CLOVE_HOOK $CLOVE_BRANCH $CLOVE_BASE_REF $CLOVE_WORKTREE_PATH $CLOVE_REPO_ROOT" > out`},
+		OnError:  PolicyAbort,
+	}
+	env := Env{RepoRoot: "/repo", WorktreePath: dir, Branch: "feature/x", BaseRef: "origin/main"}
+
+	if err := c.Run(context.Background(), PostAdd, env, dir); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "out"))
+	if err != nil {
+		t.Fatalf("hook did not run in dir: %v", err)
+	}
+	expected := "post-add feature/x origin/main " + dir + " /repo"
+	if got := strings.TrimSpace(string(data)); got != expected {
+		t.Errorf("hook env = %q, want %q", got, expected)
+	}
+}
+
+func TestRun_Script(t *testing.T) {
+	dir := t.TempDir()
+	hooksDir := filepath.Join(dir, "hooks")
+	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\necho \"$CLOVE_HOOK\" > \"$CLOVE_REPO_ROOT/script-ran\"\n"
+	if err := os.WriteFile(filepath.Join(hooksDir, "pre-remove"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// 実行権限のないスクリプトは無視される
+	if err := os.WriteFile(filepath.Join(hooksDir, "post-remove"), []byte("#!/bin/sh\nexit 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := Config{Dir: hooksDir, OnError: PolicyAbort}
+	env := Env{RepoRoot: dir}
+
+	if got := c.Plan(PreRemove); len(got) != 1 {
+		t.Errorf("Plan(pre-remove) = %v, want 1 script", got)
+	}
+	if got := c.Plan(PostRemove); len(got) != 0 {
+		t.Errorf("Plan(post-remove) = %v, want none", got)
+	}
+
+	if err := c.Run(context.Background(), PreRemove, env, dir); err != nil {
+		t.Fatalf("Run(pre-remove) failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "script-ran")); err != nil {
+		t.Errorf("script did not run: %v", err)
+	}
+	if err := c.Run(context.Background(), PostRemove, env, dir); err != nil {
+		t.Errorf("Run(post-remove) should skip non-executable script: %v", err)
+	}
+}
+
+func TestRun_Policy(t *testing.T) {
+	dir := t.TempDir()
+	cmds := map[Event]string{PostPrune: "exit 3"}
+
+	abort := Config{Commands: cmds, OnError: PolicyAbort}
+	if err := abort.Run(context.Background(), PostPrune, Env{}, dir); err == nil {
+		t.Error("abort policy should return an error")
+	}
+
+	warn := Config{Commands: cmds, OnError: PolicyWarn}
+	if err := warn.Run(context.Background(), PostPrune, Env{}, dir); err != nil {
+		t.Errorf("warn policy should not return an error: %v", err)
+	}
+
+	disabled := Config{Disabled: true, Commands: cmds, OnError: PolicyAbort}
+	if err := disabled.Run(context.Background(), PostPrune, Env{}, dir); err != nil {
+		t.Errorf("disabled hooks should not run: %v", err)
+	}
+	if got := disabled.Plan(PostPrune); len(got) != 0 {
+		t.Errorf("disabled Plan = %v, want none", got)
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	c := Config{
+		Commands: map[Event]string{PostAdd: "sleep 2"},
+		Timeout:  100 * time.Millisecond,
+		OnError:  PolicyAbort,
+	}
+	start := time.Now()
+	err := c.Run(context.Background(), PostAdd, Env{}, t.TempDir())
+	if err == nil {
+		t.Fatal("Run should fail on timeout")
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("timeout was not enforced: %v", time.Since(start))
+	}
+}
+
+func TestRun_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// 中断は warn でも無視しない
+	c := Config{Commands: map[Event]string{PostAdd: "true"}, OnError: PolicyWarn}
+	if err := c.Run(ctx, PostAdd, Env{}, t.TempDir()); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run with a cancelled context = %v", err)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for _, s := range []string{"abort", "warn"} {
+		if _, err := ParsePolicy(s); err != nil {
+			t.Errorf("ParsePolicy(%q) failed: %v", s, err)
+		}
+	}
+	if _, err := ParsePolicy("ignore"); err == nil {
+		t.Error("ParsePolicy should fail for unknown policy")
+	}
+}
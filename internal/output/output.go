@@ -0,0 +1,102 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Version is the schema version of structured output documents
+const Version = 1
+
+// Format is the output format selected by --output
+type Format string
+
+const (
+	// FormatText is the default human-readable output
+	FormatText Format = "text"
+	// FormatJSON emits a single JSON document
+	FormatJSON Format = "json"
+	// FormatYAML emits a single YAML document
+	FormatYAML Format = "yaml"
+)
+
+var format = FormatText
+
+// ParseFormat validates a format name
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case FormatText, FormatJSON, FormatYAML:
+		return Format(s), nil
+	}
+	return "", fmt.Errorf("--output は text / json / yaml のいずれかで指定してください: %s", s)
+}
+
+// SetFormat sets the global output format
+func SetFormat(f Format) {
+	format = f
+}
+
+// IsStructured reports whether a machine-readable format is selected
+func IsStructured() bool {
+	return format == FormatJSON || format == FormatYAML
+}
+
+// Document is the envelope of every structured output
+type Document struct {
+	Version int         `json:"version"`
+	Command string      `json:"command"`
+	OK      bool        `json:"ok"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// reportedError marks an error that has already been written as a document
+type reportedError struct {
+	err error
+}
+
+func (e *reportedError) Error() string { return e.err.Error() }
+func (e *reportedError) Unwrap() error { return e.err }
+
+// IsReported reports whether err has already been written as a document
+func IsReported(err error) bool {
+	var r *reportedError
+	return errors.As(err, &r)
+}
+
+// Emit writes a document for command to stdout.
+// A non-nil err is recorded in the document and returned marked as reported.
+func Emit(command string, result interface{}, err error) error {
+	doc := Document{Version: Version, Command: command, OK: err == nil, Result: result}
+	if err != nil {
+		doc.Error = err.Error()
+	}
+	if werr := Write(os.Stdout, doc); werr != nil {
+		return werr
+	}
+	if err != nil {
+		return &reportedError{err}
+	}
+	return nil
+}
+
+// Write encodes v to w in the current format
+func Write(w io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if format == FormatYAML {
+		y, err := jsonToYAML(data)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, y)
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
@@ -0,0 +1,105 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestJSONToYAML(t *testing.T) {
+	input := `{
+  "version": 1,
+  "command": "add",
+  "ok": true,
+  "result": {
+    "path": "/tmp/repo-feature",
+    "dryRun": false,
+    "commands": [
+      {"args": ["git", "fetch"]},
+      {"args": ["git", "worktree", "add"], "error": "exit status 1"}
+    ],
+    "empty": [],
+    "none": {},
+    "nothing": null,
+    "nested": [[1, 2], ["a"]]
+  }
+}`
+	expected := `---
+version: 1
+command: "add"
+ok: true
+result:
+  path: "/tmp/repo-feature"
+  dryRun: false
+  commands:
+    - args:
+        - "git"
+        - "fetch"
+    - args:
+        - "git"
+        - "worktree"
+        - "add"
+      error: "exit status 1"
+  empty: []
+  none: {}
+  nothing: null
+  nested:
+    - - 1
+      - 2
+    - - "a"
+`
+	got, err := jsonToYAML([]byte(input))
+	if err != nil {
+		t.Fatalf("jsonToYAML failed: %v", err)
+	}
+	if got != expected {
+		t.Errorf("jsonToYAML =\n%s\nwant\n%s", got, expected)
+	}
+}
+
+func TestWrite_JSON(t *testing.T) {
+	SetFormat(FormatJSON)
+	defer SetFormat(FormatText)
+
+	var buf bytes.Buffer
+	doc := Document{Version: Version, Command: "remove", Error: "boom"}
+	if err := Write(&buf, doc); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if decoded["command"] != "remove" || decoded["ok"] != false || decoded["error"] != "boom" {
+		t.Errorf("unexpected document: %v", decoded)
+	}
+	if _, ok := decoded["result"]; ok {
+		t.Error("result should be omitted when nil")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, s := range []string{"text", "json", "yaml"} {
+		if _, err := ParseFormat(s); err != nil {
+			t.Errorf("ParseFormat(%q) failed: %v", s, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat should fail for unknown format")
+	}
+}
+
+func TestIsReported(t *testing.T) {
+	base := errors.New("failed")
+	if IsReported(base) {
+		t.Error("plain error should not be reported")
+	}
+	if !IsReported(&reportedError{base}) {
+		t.Error("reportedError should be reported")
+	}
+	if !errors.Is(&reportedError{base}, base) {
+		t.Error("reportedError should unwrap to the original error")
+	}
+}
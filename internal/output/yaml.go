@@ -0,0 +1,133 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// node is an order-preserving JSON value
+type node struct {
+	keys   []string
+	fields []*node
+	items  []*node
+	scalar string
+	kind   byte // 'o': object, 'a': array, 's': scalar
+}
+
+// jsonToYAML converts a JSON document to YAML, preserving key order
+func jsonToYAML(data []byte) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	n, err := decodeNode(dec)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("---\n")
+	writeYAML(&b, n, 0)
+	return b.String(), nil
+}
+
+// decodeNode reads the next value from dec
+func decodeNode(dec *json.Decoder) (*node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			n := &node{kind: 'o'}
+			for dec.More() {
+				kt, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key, ok := kt.(string)
+				if !ok {
+					return nil, fmt.Errorf("オブジェクトのキーが文字列ではありません: %v", kt)
+				}
+				v, err := decodeNode(dec)
+				if err != nil {
+					return nil, err
+				}
+				n.keys = append(n.keys, key)
+				n.fields = append(n.fields, v)
+			}
+			_, err := dec.Token()
+			return n, err
+		case '[':
+			n := &node{kind: 'a'}
+			for dec.More() {
+				v, err := decodeNode(dec)
+				if err != nil {
+					return nil, err
+				}
+				n.items = append(n.items, v)
+			}
+			_, err := dec.Token()
+			return n, err
+		}
+		return nil, fmt.Errorf("予期しない区切り文字です: %v", t)
+	case string:
+		return &node{kind: 's', scalar: strconv.Quote(t)}, nil
+	case json.Number:
+		return &node{kind: 's', scalar: t.String()}, nil
+	case bool:
+		return &node{kind: 's', scalar: strconv.FormatBool(t)}, nil
+	case nil:
+		return &node{kind: 's', scalar: "null"}, nil
+	}
+	return nil, fmt.Errorf("予期しないトークンです: %v", tok)
+}
+
+// inline returns the single-line form of empty containers and scalars
+func (n *node) inline() (string, bool) {
+	switch {
+	case n.kind == 's':
+		return n.scalar, true
+	case n.kind == 'o' && len(n.keys) == 0:
+		return "{}", true
+	case n.kind == 'a' && len(n.items) == 0:
+		return "[]", true
+	}
+	return "", false
+}
+
+// writeYAML writes a container node at the given indent level
+func writeYAML(b *strings.Builder, n *node, indent int) {
+	pad := strings.Repeat("  ", indent)
+	if s, ok := n.inline(); ok {
+		b.WriteString(pad + s + "\n")
+		return
+	}
+
+	if n.kind == 'o' {
+		for i, key := range n.keys {
+			v := n.fields[i]
+			if s, ok := v.inline(); ok {
+				fmt.Fprintf(b, "%s%s: %s\n", pad, key, s)
+				continue
+			}
+			fmt.Fprintf(b, "%s%s:\n", pad, key)
+			writeYAML(b, v, indent+1)
+		}
+		return
+	}
+
+	for _, item := range n.items {
+		if s, ok := item.inline(); ok {
+			fmt.Fprintf(b, "%s- %s\n", pad, s)
+			continue
+		}
+		// "- " の後に最初の行を続け、残りは同じ深さに揃える
+		var sub strings.Builder
+		writeYAML(&sub, item, indent+1)
+		text := strings.TrimPrefix(sub.String(), pad+"  ")
+		b.WriteString(pad + "- " + text)
+	}
+}
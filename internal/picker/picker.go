@@ -0,0 +1,366 @@
+package picker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrCancelled is returned when the user quits the picker without choosing
+var ErrCancelled = errors.New("選択がキャンセルされました")
+
+// Item is a single choice shown in the picker
+type Item struct {
+	// Label is the line shown in the list and matched against the query
+	Label string
+	// Preview is shown below the list while the item is under the cursor
+	Preview string
+}
+
+// Options controls the picker behaviour
+type Options struct {
+	Prompt string
+	// Multi allows selecting several items with Tab
+	Multi bool
+}
+
+// Available reports whether an interactive picker can be shown
+func Available() bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return false
+	}
+	tty.Close()
+	return true
+}
+
+// Run shows the picker on the controlling terminal and returns the indexes
+// of the chosen items. The terminal is used directly so that stdout can be
+// captured by a shell wrapper.
+func Run(items []Item, opts Options) ([]int, error) {
+	if len(items) == 0 {
+		return nil, errors.New("選択できる worktree がありません")
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("端末を開けません: %w", err)
+	}
+	defer tty.Close()
+
+	restore, err := makeRaw(tty.Fd())
+	if err != nil {
+		return nil, fmt.Errorf("端末を raw モードにできません: %w", err)
+	}
+	defer restore()
+
+	// 代替画面に切り替え、終了時に元の画面へ戻す
+	fmt.Fprint(tty, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(tty, "\x1b[?25h\x1b[?1049l")
+
+	m := newModel(items, opts)
+	buf := make([]byte, 64)
+	for {
+		width, height, err := termSize(tty.Fd())
+		if err != nil || width <= 0 || height <= 0 {
+			width, height = 80, 24
+		}
+		m.render(tty, width, height)
+
+		n, err := tty.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range decodeKeys(buf[:n]) {
+			if done := m.handle(k); done {
+				if m.cancelled {
+					return nil, ErrCancelled
+				}
+				return m.result(), nil
+			}
+		}
+	}
+}
+
+// keyKind classifies a decoded key press
+type keyKind int
+
+const (
+	keyRune keyKind = iota
+	keyEnter
+	keyCancel
+	keyBackspace
+	keyClear
+	keyUp
+	keyDown
+	keyTab
+	keyIgnore
+)
+
+type key struct {
+	kind keyKind
+	r    rune
+}
+
+// decodeKeys converts raw terminal input into key presses
+func decodeKeys(b []byte) []key {
+	var keys []key
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b:
+			// 矢印キーは ESC [ A / ESC O A の形で届く。単独の ESC はキャンセル
+			if len(b) >= 3 && (b[1] == '[' || b[1] == 'O') {
+				switch b[2] {
+				case 'A':
+					keys = append(keys, key{kind: keyUp})
+				case 'B':
+					keys = append(keys, key{kind: keyDown})
+				default:
+					keys = append(keys, key{kind: keyIgnore})
+				}
+				b = b[3:]
+				continue
+			}
+			if len(b) == 1 {
+				keys = append(keys, key{kind: keyCancel})
+			}
+			b = b[1:]
+		case c == '\r' || c == '\n':
+			keys = append(keys, key{kind: keyEnter})
+			b = b[1:]
+		case c == 0x03 || c == 0x07: // Ctrl-C, Ctrl-G
+			keys = append(keys, key{kind: keyCancel})
+			b = b[1:]
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, key{kind: keyBackspace})
+			b = b[1:]
+		case c == 0x15: // Ctrl-U
+			keys = append(keys, key{kind: keyClear})
+			b = b[1:]
+		case c == 0x10 || c == 0x0b: // Ctrl-P, Ctrl-K
+			keys = append(keys, key{kind: keyUp})
+			b = b[1:]
+		case c == 0x0e: // Ctrl-N (Ctrl-J は Enter と同じ '\n')
+			keys = append(keys, key{kind: keyDown})
+			b = b[1:]
+		case c == '\t':
+			keys = append(keys, key{kind: keyTab})
+			b = b[1:]
+		case c < 0x20:
+			keys = append(keys, key{kind: keyIgnore})
+			b = b[1:]
+		default:
+			r, size := utf8.DecodeRune(b)
+			keys = append(keys, key{kind: keyRune, r: r})
+			b = b[size:]
+		}
+	}
+	return keys
+}
+
+// model is the picker state, independent of the terminal
+type model struct {
+	items     []Item
+	opts      Options
+	query     []rune
+	matches   []int
+	cursor    int
+	selected  map[int]bool
+	cancelled bool
+}
+
+func newModel(items []Item, opts Options) *model {
+	m := &model{items: items, opts: opts, selected: map[int]bool{}}
+	m.filter()
+	return m
+}
+
+// handle applies a key press and reports whether the picker is finished
+func (m *model) handle(k key) bool {
+	switch k.kind {
+	case keyRune:
+		m.query = append(m.query, k.r)
+		m.filter()
+	case keyBackspace:
+		if len(m.query) > 0 {
+			m.query = m.query[:len(m.query)-1]
+			m.filter()
+		}
+	case keyClear:
+		m.query = nil
+		m.filter()
+	case keyUp:
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case keyDown:
+		if m.cursor < len(m.matches)-1 {
+			m.cursor++
+		}
+	case keyTab:
+		if m.opts.Multi && len(m.matches) > 0 {
+			idx := m.matches[m.cursor]
+			m.selected[idx] = !m.selected[idx]
+			if m.cursor < len(m.matches)-1 {
+				m.cursor++
+			}
+		}
+	case keyEnter:
+		return len(m.result()) > 0
+	case keyCancel:
+		m.cancelled = true
+		return true
+	}
+	return false
+}
+
+// result returns the selected items, or the item under the cursor if none are selected
+func (m *model) result() []int {
+	var picked []int
+	for idx, ok := range m.selected {
+		if ok {
+			picked = append(picked, idx)
+		}
+	}
+	if len(picked) == 0 && len(m.matches) > 0 {
+		picked = append(picked, m.matches[m.cursor])
+	}
+	sort.Ints(picked)
+	return picked
+}
+
+// filter recomputes the matching items for the current query
+func (m *model) filter() {
+	q := strings.ToLower(string(m.query))
+	type scored struct{ idx, score int }
+	var found []scored
+	for i, it := range m.items {
+		if s, ok := score(q, strings.ToLower(it.Label)); ok {
+			found = append(found, scored{i, s})
+		}
+	}
+	sort.SliceStable(found, func(a, b int) bool { return found[a].score < found[b].score })
+
+	m.matches = m.matches[:0]
+	for _, f := range found {
+		m.matches = append(m.matches, f.idx)
+	}
+	m.cursor = 0
+}
+
+// score matches q as a subsequence of s; lower is better.
+// Substring matches rank before scattered ones, earlier matches before later ones.
+func score(q, s string) (int, bool) {
+	if q == "" {
+		return 0, true
+	}
+	if i := strings.Index(s, q); i >= 0 {
+		return i, true
+	}
+	rs := []rune(s)
+	first, gaps, pos := -1, 0, 0
+	for _, r := range q {
+		start := pos
+		for pos < len(rs) && rs[pos] != r {
+			pos++
+		}
+		if pos == len(rs) {
+			return 0, false
+		}
+		if first < 0 {
+			first = pos
+		} else {
+			gaps += pos - start
+		}
+		pos++
+	}
+	return len(rs) + first + gaps*2, true
+}
+
+// render draws the prompt, the matching items and the preview of the current item
+func (m *model) render(w io.Writer, width, height int) {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+
+	prompt := m.opts.Prompt
+	if prompt == "" {
+		prompt = ">"
+	}
+	line(&b, width, fmt.Sprintf("%s %s", prompt, string(m.query)))
+	help := "↑↓: 移動  Enter: 決定  Esc: キャンセル"
+	if m.opts.Multi {
+		help = "↑↓: 移動  Tab: 選択  Enter: 決定  Esc: キャンセル"
+	}
+	line(&b, width, fmt.Sprintf("\x1b[2m%d/%d  %s\x1b[0m", len(m.matches), len(m.items), help))
+
+	listHeight := (height - 3) / 2
+	if listHeight < 1 {
+		listHeight = 1
+	}
+	top := 0
+	if m.cursor >= listHeight {
+		top = m.cursor - listHeight + 1
+	}
+	for i := top; i < len(m.matches) && i < top+listHeight; i++ {
+		idx := m.matches[i]
+		mark := "  "
+		if m.selected[idx] {
+			mark = "* "
+		}
+		label := mark + m.items[idx].Label
+		if i == m.cursor {
+			line(&b, width, "\x1b[7m"+truncate(label, width)+"\x1b[0m")
+		} else {
+			line(&b, width, label)
+		}
+	}
+
+	if len(m.matches) > 0 {
+		b.WriteString(strings.Repeat("─", width) + "\r\n")
+		rest := height - 3 - listHeight
+		for i, ln := range strings.Split(m.items[m.matches[m.cursor]].Preview, "\n") {
+			if i >= rest {
+				break
+			}
+			line(&b, width, ln)
+		}
+	}
+
+	io.WriteString(w, b.String())
+}
+
+// line writes s truncated to width followed by a raw-mode newline
+func line(b *strings.Builder, width int, s string) {
+	b.WriteString(truncate(s, width))
+	b.WriteString("\r\n")
+}
+
+// truncate cuts s to at most width runes, leaving escape sequences intact
+func truncate(s string, width int) string {
+	n := 0
+	inEsc := false
+	for i, r := range s {
+		switch {
+		case r == 0x1b:
+			inEsc = true
+		case inEsc:
+			if r >= '@' && r <= '~' && r != '[' {
+				inEsc = false
+			}
+		default:
+			n++
+			if n > width {
+				return s[:i] + "\x1b[0m"
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,119 @@
+package picker
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDecodeKeys(t *testing.T) {
+	keys := decodeKeys([]byte("aé\x1b[A\x1b[B\t\x7f\r\x15\x03\x1b"))
+	expected := []keyKind{keyRune, keyRune, keyUp, keyDown, keyTab, keyBackspace, keyEnter, keyClear, keyCancel, keyCancel}
+	if len(keys) != len(expected) {
+		t.Fatalf("expected %d keys, got %d: %+v", len(expected), len(keys), keys)
+	}
+	for i, k := range keys {
+		if k.kind != expected[i] {
+			t.Errorf("key %d = %v, want %v", i, k.kind, expected[i])
+		}
+	}
+	if keys[1].r != 'é' {
+		t.Errorf("expected multibyte rune, got %q", keys[1].r)
+	}
+}
+
+func TestScore(t *testing.T) {
+	if _, ok := score("xyz", "feature/update"); ok {
+		t.Error("xyz should not match")
+	}
+	sub, _ := score("update", "feature/update")
+	scattered, _ := score("fupd", "feature/update")
+	if sub >= scattered {
+		t.Errorf("substring match should rank first: %d vs %d", sub, scattered)
+	}
+}
+
+func typeQuery(m *model, q string) {
+	for _, r := range q {
+		m.handle(key{kind: keyRune, r: r})
+	}
+}
+
+func TestModel_Filter(t *testing.T) {
+	items := []Item{{Label: "main"}, {Label: "feature/update"}, {Label: "feature/upload"}, {Label: "fix/typo"}}
+	m := newModel(items, Options{})
+
+	typeQuery(m, "upda")
+	if len(m.matches) != 1 || m.matches[0] != 1 {
+		t.Errorf("matches for upda = %v", m.matches)
+	}
+
+	m.handle(key{kind: keyBackspace})
+	if len(m.matches) != 2 {
+		t.Errorf("matches for upd = %v", m.matches)
+	}
+
+	m.handle(key{kind: keyDown})
+	if done := m.handle(key{kind: keyEnter}); !done {
+		t.Fatal("enter should finish the picker")
+	}
+	if got := m.result(); len(got) != 1 || got[0] != 2 {
+		t.Errorf("result = %v, want [2]", got)
+	}
+
+	m.handle(key{kind: keyClear})
+	typeQuery(m, "zzz")
+	if done := m.handle(key{kind: keyEnter}); done {
+		t.Error("enter without matches should not finish")
+	}
+}
+
+func TestModel_MultiSelect(t *testing.T) {
+	items := []Item{{Label: "a"}, {Label: "b"}, {Label: "c"}}
+
+	m := newModel(items, Options{Multi: true})
+	m.handle(key{kind: keyTab})
+	m.handle(key{kind: keyDown})
+	m.handle(key{kind: keyTab})
+	if got := m.result(); len(got) != 2 || got[0] != 0 || got[1] != 2 {
+		t.Errorf("result = %v, want [0 2]", got)
+	}
+
+	single := newModel(items, Options{})
+	single.handle(key{kind: keyTab})
+	if got := single.result(); len(got) != 1 || got[0] != 0 {
+		t.Errorf("tab should not select without Multi, got %v", got)
+	}
+
+	m.handle(key{kind: keyCancel})
+	if !m.cancelled {
+		t.Error("cancel should mark the model as cancelled")
+	}
+}
+
+func TestModel_Render(t *testing.T) {
+	items := []Item{
+		{Label: "feature/update", Preview: "path: /repo-update\ndirty: 1"},
+		{Label: "main", Preview: "path: /repo"},
+	}
+	m := newModel(items, Options{Prompt: "rm>", Multi: true})
+	m.handle(key{kind: keyTab})
+
+	var buf bytes.Buffer
+	m.render(&buf, 40, 12)
+	out := buf.String()
+	for _, want := range []string{"rm> ", "2/2", "* feature/update", "path: /repo", "main"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("render output should contain %q:\n%q", want, out)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("abcdef", 3); got != "abc\x1b[0m" {
+		t.Errorf("truncate = %q", got)
+	}
+	if got := truncate("\x1b[7mab\x1b[0m", 2); got != "\x1b[7mab\x1b[0m" {
+		t.Errorf("escape sequences should not count: %q", got)
+	}
+}
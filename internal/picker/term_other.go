@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+
+package picker
+
+import "errors"
+
+// makeRaw is not supported on this platform
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errors.New("この環境では対話的な選択に対応していません")
+}
+
+// termSize is not supported on this platform
+func termSize(fd uintptr) (int, int, error) {
+	return 0, 0, errors.New("この環境では端末サイズを取得できません")
+}
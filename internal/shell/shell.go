@@ -0,0 +1,80 @@
+package shell
+
+import (
+	"fmt"
+	"sort"
+)
+
+// IntegrationEnv is set by the wrapper so clove can tell it is running inside it
+const IntegrationEnv = "CLOVE_SHELL_INTEGRATION"
+
+// posixInit is the wrapper for bash and zsh.
+// `clove switch` prints the target directory on stdout; anything else
+// (help, structured output) is passed through unchanged.
+const posixInit = `# clove shell integration (%[1]s)
+# 有効にするには ~/.%[1]src に次の行を追加してください:
+#   eval "$(clove shell-init %[1]s)"
+clove() {
+  case "$1" in
+    switch|cd)
+      local __clove_dir
+      __clove_dir="$(CLOVE_SHELL_INTEGRATION=1 command clove "$@")" || return $?
+      if [ -n "$__clove_dir" ] && [ -d "$__clove_dir" ]; then
+        builtin cd -- "$__clove_dir"
+      elif [ -n "$__clove_dir" ]; then
+        printf '%%s\n' "$__clove_dir"
+      fi
+      ;;
+    *)
+      command clove "$@"
+      ;;
+  esac
+}
+`
+
+const fishInit = `# clove shell integration (fish)
+# 有効にするには ~/.config/fish/config.fish に次の行を追加してください:
+#   clove shell-init fish | source
+function clove
+    switch "$argv[1]"
+        case switch cd
+            set -l __clove_dir (CLOVE_SHELL_INTEGRATION=1 command clove $argv)
+            set -l __clove_status $status
+            if test $__clove_status -ne 0
+                return $__clove_status
+            end
+            if test (count $__clove_dir) -eq 1; and test -d "$__clove_dir[1]"
+                builtin cd -- $__clove_dir[1]
+            else if test (count $__clove_dir) -gt 0
+                printf '%%s\n' $__clove_dir
+            end
+        case '*'
+            command clove $argv
+    end
+end
+`
+
+var scripts = map[string]string{
+	"bash": fmt.Sprintf(posixInit, "bash"),
+	"zsh":  fmt.Sprintf(posixInit, "zsh"),
+	"fish": fmt.Sprintf(fishInit),
+}
+
+// Names returns the supported shells
+func Names() []string {
+	var names []string
+	for name := range scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Init returns the wrapper script for the given shell
+func Init(name string) (string, error) {
+	s, ok := scripts[name]
+	if !ok {
+		return "", fmt.Errorf("未対応のシェルです: %s（bash / zsh / fish）", name)
+	}
+	return s, nil
+}
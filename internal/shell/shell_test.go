@@ -0,0 +1,58 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInit(t *testing.T) {
+	for _, name := range Names() {
+		s, err := Init(name)
+		if err != nil {
+			t.Fatalf("Init(%s) failed: %v", name, err)
+		}
+		if !strings.Contains(s, "CLOVE_SHELL_INTEGRATION=1 command clove") {
+			t.Errorf("%s wrapper should call the clove binary:\n%s", name, s)
+		}
+		if strings.Contains(s, "%!") {
+			t.Errorf("%s wrapper has a formatting error:\n%s", name, s)
+		}
+	}
+
+	if _, err := Init("powershell"); err == nil {
+		t.Error("Init should fail for unsupported shell")
+	}
+}
+
+// TestInit_Bash runs the bash wrapper against a fake clove binary
+func TestInit_Bash(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not found")
+	}
+
+	bin := t.TempDir()
+	target := t.TempDir()
+	fake := "#!/bin/sh\n" +
+		"if [ \"$1\" = switch ] && [ \"$CLOVE_SHELL_INTEGRATION\" = 1 ]; then echo \"" + target + "\"; else echo \"other $*\"; fi\n"
+	if err := os.WriteFile(filepath.Join(bin, "clove"), []byte(fake), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	script, _ := Init("bash")
+	script += "clove switch feature && pwd\nclove list\n"
+	cmd := exec.Command(bash, "--norc", "-c", script)
+	cmd.Env = append(os.Environ(), "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("bash failed: %v\n%s", err, out)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 2 || lines[0] != target || lines[1] != "other list" {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
@@ -1,12 +1,18 @@
 package util
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 )
 
 var verbose bool
 
+// out is where human-readable messages go.
+// It is switched to stderr when structured output owns stdout.
+var out io.Writer = os.Stdout
+
 // SetVerbose sets the verbose logging mode
 func SetVerbose(v bool) {
 	verbose = v
@@ -17,20 +23,88 @@ func IsVerbose() bool {
 	return verbose
 }
 
+// SetOutput sets the writer for human-readable messages
+func SetOutput(w io.Writer) {
+	out = w
+}
+
+// Output returns the writer for human-readable messages
+func Output() io.Writer {
+	return out
+}
+
+type outputKey struct{}
+
+// WithOutput returns a context whose human-readable messages go to w.
+// It lets concurrent operations buffer their output separately.
+func WithOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, outputKey{}, w)
+}
+
+// OutputOf returns the writer set by WithOutput, or Output()
+func OutputOf(ctx context.Context) io.Writer {
+	if w, ok := ctx.Value(outputKey{}).(io.Writer); ok {
+		return w
+	}
+	return out
+}
+
+// Logger prints human-readable messages to one writer
+type Logger struct {
+	w io.Writer
+}
+
+// Log returns a Logger writing to OutputOf(ctx)
+func Log(ctx context.Context) Logger {
+	return Logger{w: OutputOf(ctx)}
+}
+
 // Verbose prints a message only if verbose mode is enabled
-func Verbose(format string, args ...interface{}) {
+func (l Logger) Verbose(format string, args ...interface{}) {
 	if verbose {
-		fmt.Fprintf(os.Stdout, format, args...)
-		if len(format) > 0 && format[len(format)-1] != '\n' {
-			fmt.Fprintln(os.Stdout)
-		}
+		l.Info(format, args...)
 	}
 }
 
-// Info prints an informational message to stdout
-func Info(format string, args ...interface{}) {
-	fmt.Fprintf(os.Stdout, format, args...)
+// Info prints an informational message
+func (l Logger) Info(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, format, args...)
 	if len(format) > 0 && format[len(format)-1] != '\n' {
-		fmt.Fprintln(os.Stdout)
+		fmt.Fprintln(l.w)
 	}
 }
+
+// Printf prints a human-readable message as is
+func (l Logger) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, format, args...)
+}
+
+// Verbose prints a message only if verbose mode is enabled
+func Verbose(format string, args ...interface{}) {
+	Logger{w: out}.Verbose(format, args...)
+}
+
+// Info prints an informational message
+func Info(format string, args ...interface{}) {
+	Logger{w: out}.Info(format, args...)
+}
+
+// Printf prints a human-readable message as is
+func Printf(format string, args ...interface{}) {
+	Logger{w: out}.Printf(format, args...)
+}
+
+// IsTerminal reports whether f is connected to a terminal
+func IsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// OutputIsTerminal reports whether human-readable messages go to a terminal
+func OutputIsTerminal() bool {
+	f, ok := out.(*os.File)
+	return ok && IsTerminal(f)
+}
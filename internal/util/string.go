@@ -1,27 +1,106 @@
 package util
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"os"
 	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
-// Sanitize converts branch name to directory-safe string
+// HashLen is the length of the hash appended by Disambiguate
+const HashLen = 7
+
+var (
+	unsafeASCII   = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+	unsafeUnicode = regexp.MustCompile(`[^\p{L}\p{N}\p{M}._-]+`)
+)
+
+// Sanitize converts branch name to directory-safe ASCII string
 func Sanitize(branch string) string {
+	return SanitizeName(branch, false)
+}
+
+// SanitizeName converts branch name to a directory-safe string.
+// Non-ASCII letters and digits are kept when keepUnicode is true; otherwise they
+// are dropped and a hash of branch is appended so that e.g. two Japanese branch
+// names do not end up in the same directory.
+func SanitizeName(branch string, keepUnicode bool) string {
 	s := strings.TrimSpace(branch)
 	s = strings.ReplaceAll(s, " ", "-")
 	s = strings.ReplaceAll(s, string(os.PathSeparator), "-")
 	s = strings.ReplaceAll(s, ":", "-")
 	s = strings.ReplaceAll(s, "@", "-")
-	re := regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+	re := unsafeASCII
+	if keepUnicode {
+		re = unsafeUnicode
+	}
+	lossy := !keepUnicode && hasNonASCIIWord(s)
 	s = re.ReplaceAllString(s, "-")
 	s = strings.Trim(s, "-")
 	if s == "" {
 		s = "worktree"
 	}
+	if lossy {
+		s = Disambiguate(s, branch, 0)
+	}
 	return s
 }
 
+// hasNonASCIIWord reports whether s contains a non-ASCII letter or digit
+func hasNonASCIIWord(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShortHash returns a short, stable hex digest of s
+func ShortHash(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])[:HashLen]
+}
+
+// Disambiguate appends "-<hash of key>" to name, shortening name so that the
+// result fits in max bytes (0 means no limit)
+func Disambiguate(name, key string, max int) string {
+	suffix := "-" + ShortHash(key)
+	if max > 0 && len(name)+len(suffix) > max {
+		name = strings.TrimRight(cutBytes(name, max-len(suffix)), "-.")
+		if name == "" {
+			return suffix[1:]
+		}
+	}
+	return name + suffix
+}
+
+// TruncateName shortens name to at most max bytes, replacing the cut part with
+// a hash of the full name so that long names sharing a prefix stay distinct
+func TruncateName(name string, max int) string {
+	if max <= 0 || len(name) <= max {
+		return name
+	}
+	return Disambiguate(name, name, max)
+}
+
+// cutBytes returns the longest prefix of s within n bytes that does not split a rune
+func cutBytes(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
 // ShellJoin joins command arguments with proper quoting
 func ShellJoin(args []string) string {
 	var b []string
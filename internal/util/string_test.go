@@ -1,6 +1,9 @@
 package util
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestSanitize(t *testing.T) {
 	tests := []struct {
@@ -30,6 +33,86 @@ func TestSanitize(t *testing.T) {
 	}
 }
 
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		keepUnicode bool
+		expected    string
+	}{
+		{"ascii", "feat/a", false, "feat-a"},
+		{"ascii unicode", "feat/a", true, "feat-a"},
+		{"japanese", "機能/検索", false, "worktree-" + ShortHash("機能/検索")},
+		{"other japanese", "機能/一覧", false, "worktree-" + ShortHash("機能/一覧")},
+		{"mixed", "feat/検索", false, "feat-" + ShortHash("feat/検索")},
+		{"keep japanese", "機能/検索", true, "機能-検索"},
+		{"keep accents", "café:crème", true, "café-crème"},
+		{"keep combining mark", "cafe\u0301", true, "cafe\u0301"},
+		{"symbols only", "★☆", true, "worktree"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := SanitizeName(tt.input, tt.keepUnicode)
+			if result != tt.expected {
+				t.Errorf("SanitizeName(%q, %v) = %q, want %q", tt.input, tt.keepUnicode, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDisambiguate(t *testing.T) {
+	h := ShortHash("feat/a")
+	tests := []struct {
+		name     string
+		input    string
+		max      int
+		expected string
+	}{
+		{"no limit", "feat-a", 0, "feat-a-" + h},
+		{"fits", "feat-a", 14, "feat-a-" + h},
+		{"shortened", "feat-a", 12, "feat-" + h},
+		{"trailing dash trimmed", "feat-a", 13, "feat-" + h},
+		{"rune boundary", "機能-a", 11, "機-" + h},
+		{"only hash", "feat-a", 5, h},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Disambiguate(tt.input, "feat/a", tt.max)
+			if result != tt.expected {
+				t.Errorf("Disambiguate(%q, %d) = %q, want %q", tt.input, tt.max, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTruncateName(t *testing.T) {
+	long := strings.Repeat("a", 20)
+	tests := []struct {
+		name     string
+		input    string
+		max      int
+		expected string
+	}{
+		{"no limit", long, 0, long},
+		{"short enough", "feat-a", 10, "feat-a"},
+		{"exact", long, 20, long},
+		{"truncated", long, 12, "aaaa-" + ShortHash(long)},
+		{"distinct tails", long + "b", 12, "aaaa-" + ShortHash(long+"b")},
+		{"multibyte", "機能機能機能機能", 15, "機能-" + ShortHash("機能機能機能機能")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := TruncateName(tt.input, tt.max)
+			if result != tt.expected || (tt.max > 0 && len(result) > tt.max) {
+				t.Errorf("TruncateName(%q, %d) = %q, want %q", tt.input, tt.max, result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestShellJoin(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,95 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/manattan/clove/internal/git"
+)
+
+// BranchDeletion selects whether Remove also deletes the local branch
+type BranchDeletion string
+
+const (
+	// DeleteBranchNone keeps the branch
+	DeleteBranchNone BranchDeletion = ""
+	// DeleteBranchSafe deletes the branch with git branch -d
+	DeleteBranchSafe BranchDeletion = "safe"
+	// DeleteBranchForce deletes the branch with git branch -D
+	DeleteBranchForce BranchDeletion = "force"
+)
+
+// ParseBranchDeletion validates a --delete-branch value
+func ParseBranchDeletion(s string) (BranchDeletion, error) {
+	switch s {
+	case "", "false", "none":
+		return DeleteBranchNone, nil
+	case "true", "safe":
+		return DeleteBranchSafe, nil
+	case "force":
+		return DeleteBranchForce, nil
+	}
+	return "", fmt.Errorf("--delete-branch は safe / force のいずれかで指定してください: %s", s)
+}
+
+// Tracking selects the upstream of a branch created by Add
+type Tracking string
+
+const (
+	// TrackDefault leaves it to git, which tracks the base when it is a remote-tracking branch
+	TrackDefault Tracking = ""
+	// TrackRemote tracks the same-named branch on the chosen remote
+	TrackRemote Tracking = "remote"
+	// TrackNone sets no upstream
+	TrackNone Tracking = "none"
+)
+
+// ParseTracking validates an add.track value
+func ParseTracking(s string) (Tracking, error) {
+	switch s {
+	case "", "default":
+		return TrackDefault, nil
+	case "true", "remote":
+		return TrackRemote, nil
+	case "false", "none":
+		return TrackNone, nil
+	}
+	return "", fmt.Errorf("add.track は remote / none のいずれかで指定してください: %s", s)
+}
+
+// checkedOutElsewhere returns the path of another worktree that has branch checked out
+func checkedOutElsewhere(ctx context.Context, repoRoot, branch, path string) (string, error) {
+	worktrees, err := listWorktrees(ctx, repoRoot)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	for _, wt := range worktrees {
+		if wt.Branch == "refs/heads/"+branch && filepath.Clean(wt.Path) != abs {
+			return wt.Path, nil
+		}
+	}
+	return "", nil
+}
+
+// remoteBranch returns the remote and branch name branch is pushed to.
+// The configured upstream is used when set, otherwise the same name on the default remote.
+func remoteBranch(ctx context.Context, repoRoot, branch string) (string, string) {
+	remote := git.DefaultRemote(ctx, repoRoot, "")
+	name := branch
+	if out, err := git.Git(ctx, repoRoot, "config", "--get", "branch."+branch+".remote"); err == nil && strings.TrimSpace(out) != "." {
+		remote = strings.TrimSpace(out)
+		if out, err := git.Git(ctx, repoRoot, "config", "--get", "branch."+branch+".merge"); err == nil {
+			name = strings.TrimPrefix(strings.TrimSpace(out), "refs/heads/")
+		}
+	}
+	return remote, name
+}
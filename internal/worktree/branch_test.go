@@ -0,0 +1,140 @@
+package worktree
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/manattan/clove/internal/git"
+)
+
+func TestParseBranchDeletion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected BranchDeletion
+	}{
+		{"", DeleteBranchNone},
+		{"false", DeleteBranchNone},
+		{"safe", DeleteBranchSafe},
+		{"true", DeleteBranchSafe},
+		{"force", DeleteBranchForce},
+	}
+	for _, tt := range tests {
+		got, err := ParseBranchDeletion(tt.input)
+		if err != nil || got != tt.expected {
+			t.Errorf("ParseBranchDeletion(%q) = %q, %v", tt.input, got, err)
+		}
+	}
+	if _, err := ParseBranchDeletion("maybe"); err == nil {
+		t.Error("ParseBranchDeletion should fail for unknown value")
+	}
+}
+
+func TestParseTracking(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Tracking
+	}{
+		{"", TrackDefault},
+		{"remote", TrackRemote},
+		{"true", TrackRemote},
+		{"none", TrackNone},
+		{"false", TrackNone},
+	}
+	for _, tt := range tests {
+		got, err := ParseTracking(tt.input)
+		if err != nil || got != tt.expected {
+			t.Errorf("ParseTracking(%q) = %q, %v", tt.input, got, err)
+		}
+	}
+	if _, err := ParseTracking("base"); err == nil {
+		t.Error("ParseTracking should fail for unknown value")
+	}
+}
+
+// initRepoWithRemote creates a repository whose origin is a local bare repository
+func initRepoWithRemote(t *testing.T) (string, string) {
+	t.Helper()
+	repo := initRepo(t)
+	remote := filepath.Join(filepath.Dir(repo), "remote.git")
+	if _, err := git.Git(context.Background(), "", "init", "-q", "--bare", remote); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git.Git(context.Background(), repo, "remote", "add", "origin", remote); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git.Git(context.Background(), repo, "push", "-q", "-u", "origin", "main"); err != nil {
+		t.Fatal(err)
+	}
+	return repo, remote
+}
+
+func TestRemove_DeleteBranch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	repo, remote := initRepoWithRemote(t)
+	wt := addWorktree(t, repo, "feature")
+	commitFile(t, wt, "pushed.txt", "pushed\n")
+	if _, err := git.Git(context.Background(), wt, "push", "-q", "-u", "origin", "feature"); err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, wt, "local.txt", "local\n")
+
+	// 未プッシュのコミットがあるので git branch -d は失敗するが、worktree は削除される
+	res, err := Remove(context.Background(), repo, RemoveOptions{PathOrBranch: "feature", DeleteBranch: DeleteBranchSafe, IKnow: true})
+	if err == nil || !strings.Contains(err.Error(), "--delete-branch=force") {
+		t.Fatalf("expected safe deletion to fail, got %v", err)
+	}
+	if !res.Removed || res.BranchDeleted {
+		t.Errorf("result = %+v", res)
+	}
+	if len(res.Safety.Unpushed) != 1 || !strings.HasSuffix(res.Safety.Unpushed[0], "add local.txt") {
+		t.Errorf("unpushed = %v", res.Safety.Unpushed)
+	}
+
+	// ブランチを再び worktree に出して、強制削除とリモート削除を行う
+	if _, err := git.Git(context.Background(), repo, "worktree", "add", "-q", wt, "feature"); err != nil {
+		t.Fatal(err)
+	}
+	res, err = Remove(context.Background(), repo, RemoveOptions{PathOrBranch: wt, DeleteBranch: DeleteBranchForce, DeleteRemote: true, IKnow: true})
+	if err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if !res.BranchDeleted || !res.RemoteDeleted {
+		t.Errorf("result = %+v", res)
+	}
+	if git.GitOk(context.Background(), repo, "rev-parse", "--verify", "--quiet", "refs/heads/feature") {
+		t.Error("local branch should be deleted")
+	}
+	if git.GitOk(context.Background(), remote, "rev-parse", "--verify", "--quiet", "refs/heads/feature") {
+		t.Error("remote branch should be deleted")
+	}
+}
+
+func TestRemove_BranchCheckedOutElsewhere(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	repo := initRepo(t)
+	wt := addWorktree(t, repo, "shared")
+	other := filepath.Join(filepath.Dir(repo), "repo-shared-2")
+	if _, err := git.Git(context.Background(), repo, "worktree", "add", "-q", "--force", other, "shared"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Remove(context.Background(), repo, RemoveOptions{PathOrBranch: wt, DeleteBranch: DeleteBranchForce})
+	if err == nil || !strings.Contains(err.Error(), other) {
+		t.Fatalf("expected checked-out error, got %v", err)
+	}
+	if res.Removed {
+		t.Error("worktree should not be removed when the branch cannot be deleted")
+	}
+	if _, err := os.Stat(wt); err != nil {
+		t.Errorf("worktree should be kept: %v", err)
+	}
+}
@@ -0,0 +1,356 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/hook"
+	"github.com/manattan/clove/internal/util"
+)
+
+// GCReason explains why a worktree is a gc candidate
+type GCReason string
+
+const (
+	// ReasonMerged means the branch tip is reachable from the base
+	ReasonMerged GCReason = "merged"
+	// ReasonSquashed means the branch changes are already in the base as different commits
+	ReasonSquashed GCReason = "squash-merged"
+	// ReasonGone means the configured upstream branch no longer exists
+	ReasonGone GCReason = "upstream-gone"
+	// ReasonStale means there have been no commits for the configured number of days
+	ReasonStale GCReason = "stale"
+)
+
+// GCOptions contains options for PlanGC and RunGC
+type GCOptions struct {
+	BaseRef string
+	// Remote provides the default base (<remote>/HEAD) when BaseRef is empty
+	Remote string
+	// Merged, Gone and StaleDays select the criteria; StaleDays 0 disables the age check
+	Merged    bool
+	Gone      bool
+	StaleDays int
+	// DeleteBranch also deletes the local branch of merged worktrees
+	DeleteBranch bool
+	Force        bool
+	DryRun       bool
+	// IKnow removes worktrees even if the safety check finds work that would be lost
+	IKnow  bool
+	Safety SafetyOptions
+	Hooks  hook.Config
+	Layout Layout
+}
+
+// GCCandidate is a worktree matched by at least one gc criterion
+type GCCandidate struct {
+	Path          string       `json:"path"`
+	Branch        string       `json:"branch,omitempty"`
+	Reasons       []GCReason   `json:"reasons"`
+	Dirty         bool         `json:"dirty"`
+	Safety        SafetyReport `json:"safety"`
+	Skip          string       `json:"skip,omitempty"`
+	Removed       bool         `json:"removed"`
+	BranchDeleted bool         `json:"branchDeleted"`
+	Error         string       `json:"error,omitempty"`
+}
+
+// merged reports whether the branch content is already in the base
+func (c *GCCandidate) merged() bool {
+	for _, r := range c.Reasons {
+		if r == ReasonMerged || r == ReasonSquashed {
+			return true
+		}
+	}
+	return false
+}
+
+// safetyOptions returns the safety checks for the candidate.
+// Commits of merged branches are in the base, so they are not reported as unpushed.
+func (c *GCCandidate) safetyOptions(opts GCOptions) SafetyOptions {
+	s := opts.Safety
+	s.SkipUnpushed = s.SkipUnpushed || c.merged()
+	return s
+}
+
+// GCResult describes the plan and outcome of gc
+type GCResult struct {
+	Repo       string          `json:"repo"`
+	Base       string          `json:"base,omitempty"`
+	DryRun     bool            `json:"dryRun"`
+	Candidates []GCCandidate   `json:"candidates"`
+	Commands   []CommandResult `json:"commands"`
+}
+
+// Removable returns the number of candidates that will be removed
+func (r *GCResult) Removable() int {
+	n := 0
+	for _, c := range r.Candidates {
+		if c.Skip == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// PlanGC finds worktrees that are merged into the base, whose upstream is
+// gone, or which are stale. Nothing is modified.
+func PlanGC(ctx context.Context, repoRoot string, opts GCOptions) (*GCResult, error) {
+	res := &GCResult{Repo: repoRoot, DryRun: opts.DryRun, Candidates: []GCCandidate{}, Commands: []CommandResult{}}
+
+	remote := git.DefaultRemote(ctx, repoRoot, opts.Remote)
+	if opts.Merged {
+		base, src, err := git.ResolveBase(ctx, repoRoot, opts.BaseRef, remote, false)
+		if err != nil {
+			return res, err
+		}
+		res.Base = base
+		util.Verbose("[verbose] マージ判定の base ref: %s（%s）", base, src)
+	}
+
+	worktrees, err := listWorktrees(ctx, repoRoot)
+	if err != nil {
+		return res, err
+	}
+	var targets []WorktreeInfo
+	for i, wt := range worktrees {
+		// メインの worktree と、prune の対象になる消えた worktree は扱わない
+		if i == 0 || wt.Bare || wt.Prunable {
+			continue
+		}
+		targets = append(targets, wt)
+	}
+
+	util.Verbose("[verbose] %d 件の worktree を調べています...", len(targets))
+	now := time.Now()
+	for _, st := range CollectStatus(ctx, targets, StatusOptions{}) {
+		c := GCCandidate{Path: st.Path, Branch: strings.TrimPrefix(st.Branch, "refs/heads/"), Reasons: []GCReason{}}
+
+		if c.Branch != "" {
+			// base のブランチ自身（bare リポジトリの既定ブランチの worktree など）はマージ済みとみなさない
+			if opts.Merged && c.Branch != res.Base && remote+"/"+c.Branch != res.Base {
+				if r, ok := mergedInto(ctx, repoRoot, c.Branch, res.Base); ok {
+					c.Reasons = append(c.Reasons, r)
+				}
+			}
+			if opts.Gone && upstreamGone(ctx, repoRoot, c.Branch) {
+				c.Reasons = append(c.Reasons, ReasonGone)
+			}
+		}
+		if opts.StaleDays > 0 && !st.LastCommit.IsZero() && now.Sub(st.LastCommit) > time.Duration(opts.StaleDays)*24*time.Hour {
+			c.Reasons = append(c.Reasons, ReasonStale)
+		}
+		if len(c.Reasons) == 0 {
+			continue
+		}
+
+		c.Dirty = st.Dirty > 0 || st.Untracked > 0
+		switch {
+		case st.Error != "":
+			c.Skip = "状態を取得できません: " + strings.TrimSpace(st.Error)
+		case st.Locked:
+			c.Skip = "ロックされています"
+		case c.Dirty && !opts.Force:
+			c.Skip = "未コミットの変更があります（--force で削除）"
+		default:
+			c.Safety = checkSafety(ctx, repoRoot, c.Path, c.Branch, c.safetyOptions(opts))
+			if !c.Safety.Empty() && !opts.IKnow {
+				c.Skip = "削除すると失われる作業があります（--i-know で削除）"
+			}
+		}
+		res.Candidates = append(res.Candidates, c)
+	}
+
+	return res, nil
+}
+
+// mergedInto reports whether branch is merged into base, directly or by squash
+func mergedInto(ctx context.Context, repoRoot, branch, base string) (GCReason, bool) {
+	ref := "refs/heads/" + branch
+	if neverCommitted(ctx, repoRoot, ref, base) {
+		// 作成しただけのブランチは base の祖先になるが、マージ済みではない
+		return "", false
+	}
+	if git.GitOk(ctx, repoRoot, "merge-base", "--is-ancestor", ref, base) {
+		return ReasonMerged, true
+	}
+	if squashMerged(ctx, repoRoot, ref, base) {
+		return ReasonSquashed, true
+	}
+	return "", false
+}
+
+// neverCommitted reports whether the branch was created from base and never
+// advanced. Only the reflog can tell, so a branch whose reflog is missing or
+// records anything but its creation from base is checked as usual.
+func neverCommitted(ctx context.Context, repoRoot, ref, base string) bool {
+	out, err := git.Git(ctx, repoRoot, "reflog", "show", "--format=%H %gs", ref, "--")
+	if err != nil {
+		return false
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 1 {
+		return false
+	}
+	created, msg, _ := strings.Cut(lines[0], " ")
+	src, ok := strings.CutPrefix(msg, "branch: Created from ")
+	if !ok || fullRefName(ctx, repoRoot, src) != fullRefName(ctx, repoRoot, base) {
+		// origin/X などから作ったブランチは、マージされれば merged として扱う
+		return false
+	}
+	tip, err := git.Git(ctx, repoRoot, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	return err == nil && strings.TrimSpace(tip) == created
+}
+
+// fullRefName expands name to its full ref name, or returns it as is
+func fullRefName(ctx context.Context, repoRoot, name string) string {
+	out, err := git.Git(ctx, repoRoot, "rev-parse", "--symbolic-full-name", name)
+	if out = strings.TrimSpace(out); err != nil || out == "" {
+		return name
+	}
+	return out
+}
+
+// squashMerged detects a branch whose changes were merged as other commits.
+// The combined diff since the merge base is compared by patch-id against each
+// commit on the base, then the merge result is compared with the base tree.
+func squashMerged(ctx context.Context, repoRoot, ref, base string) bool {
+	out, err := git.Git(ctx, repoRoot, "merge-base", ref, base)
+	if err != nil {
+		return false
+	}
+	mb := strings.TrimSpace(out)
+
+	diff, err := git.Git(ctx, repoRoot, "diff", "--no-color", "--no-ext-diff", mb, ref)
+	if err != nil || strings.TrimSpace(diff) == "" {
+		return false
+	}
+	if id := patchIDs(ctx, repoRoot, diff); len(id) == 1 {
+		log, err := git.Git(ctx, repoRoot, "log", "-p", "--no-color", "--no-ext-diff", "--no-merges", mb+".."+base)
+		if err == nil {
+			for _, other := range patchIDs(ctx, repoRoot, log) {
+				if other == id[0] {
+					return true
+				}
+			}
+		}
+	}
+
+	// 複数のコミットに分けて取り込まれた場合: マージしても base の tree が変わらなければ取り込み済み
+	merged, err := git.Git(ctx, repoRoot, "merge-tree", "--write-tree", base, ref)
+	if err != nil {
+		// 衝突する場合や古い git では判定しない
+		return false
+	}
+	baseTree, err := git.Git(ctx, repoRoot, "rev-parse", base+"^{tree}")
+	if err != nil {
+		return false
+	}
+	return firstLine(merged) == strings.TrimSpace(baseTree)
+}
+
+// patchIDs returns the stable patch ids of the patches in input
+func patchIDs(ctx context.Context, repoRoot, input string) []string {
+	out, err := git.GitInput(ctx, repoRoot, input, "patch-id", "--stable")
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for _, ln := range strings.Split(out, "\n") {
+		if f := strings.Fields(ln); len(f) > 0 {
+			ids = append(ids, f[0])
+		}
+	}
+	return ids
+}
+
+// upstreamGone reports whether the branch has an upstream that no longer exists
+func upstreamGone(ctx context.Context, repoRoot, branch string) bool {
+	out, err := git.Git(ctx, repoRoot, "for-each-ref", "--format=%(upstream:track)", "refs/heads/"+branch)
+	return err == nil && strings.Contains(out, "[gone]")
+}
+
+// firstLine returns the first line of s without surrounding spaces
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return strings.TrimSpace(line)
+}
+
+// PrintPlan writes the gc plan as a table
+func (r *GCResult) PrintPlan() {
+	if len(r.Candidates) == 0 {
+		util.Info("削除対象の worktree はありません")
+		return
+	}
+	tw := tabwriter.NewWriter(util.Output(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACTION\tBRANCH\tREASONS\tPATH")
+	for _, c := range r.Candidates {
+		action := "remove"
+		if c.Skip != "" {
+			action = "skip"
+		}
+		reasons := make([]string, len(c.Reasons))
+		for i, reason := range c.Reasons {
+			reasons[i] = string(reason)
+		}
+		line := fmt.Sprintf("%s\t%s\t%s\t%s", action, orDash(c.Branch), strings.Join(reasons, ","), c.Path)
+		if c.Skip != "" {
+			line += "\t(" + c.Skip + ")"
+		}
+		fmt.Fprintln(tw, line)
+	}
+	tw.Flush()
+}
+
+// RunGC removes the candidates of a plan that are not skipped
+func RunGC(ctx context.Context, repoRoot string, res *GCResult, opts GCOptions) error {
+	failed := 0
+	for i := range res.Candidates {
+		c := &res.Candidates[i]
+		if c.Skip != "" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("gc は中断されました: %w", err)
+		}
+
+		rmOpts := RemoveOptions{
+			PathOrBranch: c.Path,
+			Force:        c.Dirty,
+			DryRun:       opts.DryRun,
+			IKnow:        opts.IKnow,
+			Safety:       c.safetyOptions(opts),
+			Hooks:        opts.Hooks,
+			Layout:       opts.Layout,
+		}
+		if opts.DeleteBranch && c.Branch != "" {
+			if c.merged() {
+				// squash マージされたブランチは git branch -d では削除できないため -D を使う
+				rmOpts.DeleteBranch = DeleteBranchForce
+			} else {
+				util.Info("ブランチ %s はマージされていないため残します", c.Branch)
+			}
+		}
+
+		rm, err := Remove(ctx, repoRoot, rmOpts)
+		if rm != nil {
+			res.Commands = append(res.Commands, rm.Commands...)
+			c.Removed = rm.Removed
+			c.BranchDeleted = rm.BranchDeleted
+		}
+		if err != nil {
+			c.Error = err.Error()
+			util.Printf("警告: %s の削除に失敗しました: %v\n", c.Path, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d 件の worktree の削除に失敗しました", failed)
+	}
+	return nil
+}
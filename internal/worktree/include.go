@@ -0,0 +1,134 @@
+package worktree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/manattan/clove/internal/clone"
+	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/util"
+)
+
+// CopyMode is how include files are carried into a new worktree
+type CopyMode string
+
+const (
+	// CopyModeCopy copies the files
+	CopyModeCopy CopyMode = "copy"
+	// CopyModeSymlink symlinks the files to the original checkout
+	CopyModeSymlink CopyMode = "symlink"
+)
+
+// ParseCopyMode validates a copy mode name
+func ParseCopyMode(s string) (CopyMode, error) {
+	switch CopyMode(s) {
+	case CopyModeCopy, CopyModeSymlink:
+		return CopyMode(s), nil
+	}
+	return "", fmt.Errorf("copy-mode は copy か symlink で指定してください: %s", s)
+}
+
+// matchIncludes expands glob patterns relative to repoRoot into relative paths.
+// Patterns that are absolute or match outside repoRoot are rejected.
+// Tracked paths and paths that already exist in target are skipped; it runs
+// before the checkout, so copyIncludes checks target again.
+func matchIncludes(ctx context.Context, repoRoot, target string, patterns []string) ([]string, error) {
+	seen := map[string]bool{}
+	var paths []string
+	for _, pat := range patterns {
+		if filepath.IsAbs(pat) {
+			return nil, fmt.Errorf("copy にはリポジトリからの相対パスを指定してください: %s", pat)
+		}
+		matches, err := filepath.Glob(filepath.Join(repoRoot, pat))
+		if err != nil {
+			return nil, fmt.Errorf("copy のパターンが不正です: %s: %w", pat, err)
+		}
+		for _, m := range matches {
+			rel, err := filepath.Rel(repoRoot, m)
+			if err != nil {
+				return nil, err
+			}
+			if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				// ../secrets/* のようにリポジトリの外を指すパターンは持ち込まない
+				return nil, fmt.Errorf("copy のパターンがリポジトリの外を指しています: %s", pat)
+			}
+			if rel == ".git" || seen[rel] {
+				continue
+			}
+			if _, err := os.Lstat(filepath.Join(target, rel)); err == nil {
+				util.Verbose("[verbose] worktree に既に存在するためスキップします: %s", rel)
+				continue
+			}
+			if git.GitOk(ctx, repoRoot, "ls-files", "--error-unmatch", "--", rel) {
+				util.Verbose("[verbose] git 管理下のためスキップします: %s", rel)
+				continue
+			}
+			seen[rel] = true
+			paths = append(paths, rel)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// copyIncludes copies or symlinks the matched paths from repoRoot into target
+// and returns the paths carried over. Paths that the checkout already created
+// in target (files tracked on the new branch) are skipped, and a failure on one
+// path does not stop the others; the failures are returned joined. It stops
+// when ctx is cancelled.
+func copyIncludes(ctx context.Context, repoRoot, target string, paths []string, mode CopyMode) ([]string, error) {
+	var copied []string
+	var errs []error
+	for _, rel := range paths {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		dst := filepath.Join(target, rel)
+		if _, err := os.Lstat(dst); err == nil {
+			util.Verbose("[verbose] worktree に既に存在するためスキップします: %s", rel)
+			continue
+		}
+		if err := copyInclude(ctx, filepath.Join(repoRoot, rel), dst, mode); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", rel, err))
+			continue
+		}
+		copied = append(copied, rel)
+	}
+	return copied, errors.Join(errs...)
+}
+
+// copyInclude copies or symlinks one path
+func copyInclude(ctx context.Context, src, dst string, mode CopyMode) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	util.Verbose("[verbose] %s: %s -> %s", mode, src, dst)
+	if mode == CopyModeSymlink {
+		return os.Symlink(src, dst)
+	}
+
+	fi, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case fi.IsDir():
+		_, err := clone.Tree(ctx, src, dst, clone.ModeCopy, clone.Options{})
+		return err
+	case fi.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, dst)
+	default:
+		return clone.File(src, dst)
+	}
+}
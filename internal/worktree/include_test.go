@@ -0,0 +1,129 @@
+package worktree
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/manattan/clove/internal/git"
+)
+
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, n := range names {
+		p := filepath.Join(dir, n)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(n), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMatchIncludes(t *testing.T) {
+	repo := t.TempDir()
+	if _, err := git.Git(context.Background(), repo, "init", "-q"); err != nil {
+		t.Skipf("git init failed: %v", err)
+	}
+	writeFiles(t, repo, ".env", ".env.local", ".envrc", "config/app.local.yml", "config/app.yml", "README.md")
+	if _, err := git.Git(context.Background(), repo, "add", "config/app.yml", "README.md", ".envrc"); err != nil {
+		t.Fatal(err)
+	}
+
+	target := t.TempDir()
+	writeFiles(t, target, ".env.local")
+
+	got, err := matchIncludes(context.Background(), repo, target, []string{".env*", "config/*.local.*", "config/*.yml", "missing/*"})
+	if err != nil {
+		t.Fatalf("matchIncludes failed: %v", err)
+	}
+	expected := []string{".env", "config/app.local.yml"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("matchIncludes = %v, want %v", got, expected)
+	}
+
+	if _, err := matchIncludes(context.Background(), repo, target, []string{"/etc/passwd"}); err == nil {
+		t.Error("matchIncludes should reject absolute patterns")
+	}
+}
+
+func TestMatchIncludes_OutsideRepo(t *testing.T) {
+	parent := t.TempDir()
+	repo := filepath.Join(parent, "repo")
+	if _, err := git.Git(context.Background(), "", "init", "-q", repo); err != nil {
+		t.Skipf("git init failed: %v", err)
+	}
+	writeFiles(t, parent, "secrets/a.key")
+	writeFiles(t, repo, "config/app.local.yml")
+
+	for _, pat := range []string{"../secrets/*", "config/../../secrets/a.key", ".."} {
+		if got, err := matchIncludes(context.Background(), repo, t.TempDir(), []string{pat}); err == nil {
+			t.Errorf("matchIncludes(%s) = %v; should reject paths outside the repository", pat, got)
+		}
+	}
+	// リポジトリの中に戻るパターンは使える
+	got, err := matchIncludes(context.Background(), repo, t.TempDir(), []string{"config/../config/*.yml"})
+	if err != nil || !reflect.DeepEqual(got, []string{filepath.Join("config", "app.local.yml")}) {
+		t.Errorf("matchIncludes = %v, %v", got, err)
+	}
+}
+
+func TestCopyIncludes(t *testing.T) {
+	for _, mode := range []CopyMode{CopyModeCopy, CopyModeSymlink} {
+		t.Run(string(mode), func(t *testing.T) {
+			repo := t.TempDir()
+			target := t.TempDir()
+			writeFiles(t, repo, ".env", "config/app.local.yml", "secrets/a.key")
+
+			// チェックアウトで作られたファイルはスキップし、失敗したパスがあっても残りは続ける
+			writeFiles(t, target, "tracked.txt")
+			writeFiles(t, repo, "tracked.txt")
+			paths := []string{".env", "config/app.local.yml", "missing", "secrets", "tracked.txt"}
+			copied, err := copyIncludes(context.Background(), repo, target, paths, mode)
+			want := []string{".env", "config/app.local.yml", "secrets"}
+			if mode == CopyModeSymlink {
+				// リンク切れのシンボリックリンクは作れる
+				want = []string{".env", "config/app.local.yml", "missing", "secrets"}
+			} else if err == nil || !strings.Contains(err.Error(), "missing") {
+				t.Errorf("copyIncludes should report the missing path, got %v", err)
+			}
+			if !reflect.DeepEqual(copied, want) {
+				t.Errorf("copied = %v, want %v", copied, want)
+			}
+			if data, _ := os.ReadFile(filepath.Join(target, "tracked.txt")); string(data) != "tracked.txt" {
+				t.Errorf("tracked.txt should be kept: %q", data)
+			}
+
+			for _, p := range []string{".env", "config/app.local.yml", "secrets/a.key"} {
+				data, err := os.ReadFile(filepath.Join(target, p))
+				if err != nil || string(data) != p {
+					t.Errorf("%s = %q, %v", p, data, err)
+				}
+			}
+
+			fi, err := os.Lstat(filepath.Join(target, ".env"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isLink := fi.Mode()&os.ModeSymlink != 0; isLink != (mode == CopyModeSymlink) {
+				t.Errorf("mode %s: .env symlink = %v", mode, isLink)
+			}
+		})
+	}
+}
+
+func TestParseCopyMode(t *testing.T) {
+	if _, err := ParseCopyMode("copy"); err != nil {
+		t.Errorf("ParseCopyMode(copy) failed: %v", err)
+	}
+	if _, err := ParseCopyMode("symlink"); err != nil {
+		t.Errorf("ParseCopyMode(symlink) failed: %v", err)
+	}
+	if _, err := ParseCopyMode("move"); err == nil {
+		t.Error("ParseCopyMode should fail for unknown mode")
+	}
+}
@@ -0,0 +1,268 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/util"
+)
+
+// DefaultLayout places worktrees next to the repository as <repo>-<branch>
+const DefaultLayout = "{repoParent}/{prefix}-{sanitized}{suffix}"
+
+// LayoutVars lists the variables available in layout templates
+var LayoutVars = []string{"repo", "repoParent", "branch", "sanitized", "user", "date", "remote", "prefix", "suffix"}
+
+// stableVars do not depend on the branch, so directories made only of them form the layout root
+var stableVars = map[string]bool{"repo": true, "repoParent": true, "user": true, "remote": true}
+
+var layoutVar = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// Layout decides where worktrees are created from a path template such as
+// "~/worktrees/{repo}/{branch}". Relative templates are resolved against the repository.
+type Layout struct {
+	// Template is the path template; "" uses DefaultLayout
+	Template string
+	// Prefix replaces {prefix} (default: the repository name)
+	Prefix string
+	// Suffix replaces {suffix}
+	Suffix string
+	// Unicode keeps non-ASCII letters and digits in {sanitized}
+	Unicode bool
+	// MaxLength limits each directory name in bytes; longer names are cut and
+	// end in a hash (0: no limit)
+	MaxLength int
+}
+
+// ValidateLayout checks that template only uses known variables
+func ValidateLayout(template string) error {
+	for _, m := range layoutVar.FindAllStringSubmatch(template, -1) {
+		if !isLayoutVar(m[1]) {
+			return fmt.Errorf("レイアウトに未知の変数があります: {%s}（使用可能: %s）", m[1], strings.Join(LayoutVars, ", "))
+		}
+	}
+	return nil
+}
+
+func isLayoutVar(name string) bool {
+	for _, v := range LayoutVars {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (l Layout) template() string {
+	if l.Template == "" {
+		return DefaultLayout
+	}
+	return l.Template
+}
+
+// layoutEnv holds the values substituted into a template
+type layoutEnv struct {
+	repoRoot string
+	branch   string
+	remote   string
+	now      time.Time
+}
+
+func (e layoutEnv) value(l Layout, name string) string {
+	switch name {
+	case "repo":
+		return filepath.Base(e.repoRoot)
+	case "repoParent":
+		return filepath.Dir(e.repoRoot)
+	case "branch":
+		return e.branch
+	case "sanitized":
+		return util.SanitizeName(e.branch, l.Unicode)
+	case "user":
+		return currentUser()
+	case "date":
+		return e.now.Format("2006-01-02")
+	case "remote":
+		return e.remote
+	case "prefix":
+		if l.Prefix != "" {
+			return l.Prefix
+		}
+		return filepath.Base(e.repoRoot)
+	case "suffix":
+		return l.Suffix
+	}
+	return ""
+}
+
+// currentUser returns the login name, falling back to $USER
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		// Windows では DOMAIN\name になる
+		return util.Sanitize(filepath.Base(u.Username))
+	}
+	return util.Sanitize(os.Getenv("USER"))
+}
+
+// expand substitutes the variables in s
+func (l Layout) expand(s string, env layoutEnv) string {
+	return layoutVar.ReplaceAllStringFunc(s, func(m string) string {
+		return env.value(l, m[1:len(m)-1])
+	})
+}
+
+// expandPath expands the template, applying MaxLength to the directory names
+// produced by segments that depend on the branch
+func (l Layout) expandPath(env layoutEnv) string {
+	segs := strings.Split(filepath.ToSlash(l.template()), "/")
+	for i, seg := range segs {
+		expanded := l.expand(seg, env)
+		if l.MaxLength > 0 && !isStableSegment(seg) {
+			parts := strings.Split(expanded, "/")
+			for j, part := range parts {
+				parts[j] = util.TruncateName(part, l.MaxLength)
+			}
+			expanded = strings.Join(parts, "/")
+		}
+		segs[i] = expanded
+	}
+	return strings.Join(segs, "/")
+}
+
+// isStableSegment reports whether a template segment only uses stableVars
+func isStableSegment(seg string) bool {
+	for _, m := range layoutVar.FindAllStringSubmatch(seg, -1) {
+		if !stableVars[m[1]] {
+			return false
+		}
+	}
+	return true
+}
+
+// absLayoutPath turns an expanded template into an absolute path
+func absLayoutPath(repoRoot, p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = filepath.Join(home, p[1:])
+		}
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(repoRoot, p)
+	}
+	return filepath.Clean(p)
+}
+
+// Path returns the directory of the worktree for branch
+func (l Layout) Path(repoRoot, branch, remote string, now time.Time) (string, error) {
+	if err := ValidateLayout(l.template()); err != nil {
+		return "", err
+	}
+	env := layoutEnv{repoRoot: repoRoot, branch: branch, remote: remote, now: now}
+	p := absLayoutPath(repoRoot, l.expandPath(env))
+	if p == repoRoot || strings.HasPrefix(repoRoot, p+string(filepath.Separator)) {
+		return "", fmt.Errorf("レイアウト %s の作成先がリポジトリ自身かその親です: %s", l.template(), p)
+	}
+	return p, nil
+}
+
+// Root returns the directory under which the layout places every worktree:
+// the leading directories of the template that do not depend on the branch
+func (l Layout) Root(repoRoot, remote string) string {
+	env := layoutEnv{repoRoot: repoRoot, remote: remote}
+	segs := strings.Split(filepath.ToSlash(l.template()), "/")
+	var root []string
+	for _, seg := range segs[:len(segs)-1] {
+		if !isStableSegment(seg) {
+			break
+		}
+		root = append(root, seg)
+	}
+	if len(root) == 0 {
+		return repoRoot
+	}
+	if len(root) == 1 && root[0] == "" {
+		// "/{branch}" のような絶対パス
+		return string(filepath.Separator)
+	}
+	return absLayoutPath(repoRoot, l.expand(strings.Join(root, "/"), env))
+}
+
+// layoutRoot returns the layout root using the default remote of repoRoot
+func layoutRoot(ctx context.Context, repoRoot string, l Layout) string {
+	return l.Root(repoRoot, git.DefaultRemote(ctx, repoRoot, ""))
+}
+
+// layoutName returns path relative to root, or "" when path is outside root
+func layoutName(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// excludeEntry returns the info/exclude pattern hiding target when it is inside
+// the repository, e.g. "/.worktrees/"
+func excludeEntry(repoRoot, target string) string {
+	name := layoutName(repoRoot, target)
+	if name == "" {
+		return ""
+	}
+	top, _, _ := strings.Cut(name, "/")
+	return "/" + top + "/"
+}
+
+// addExclude appends entry to the repository's info/exclude unless it is already listed
+func addExclude(ctx context.Context, repoRoot, entry string) error {
+	out, err := git.Git(ctx, repoRoot, "rev-parse", "--git-path", "info/exclude")
+	if err != nil {
+		return err
+	}
+	p := strings.TrimSpace(out)
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(repoRoot, p)
+	}
+	data, err := os.ReadFile(p)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, ln := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(ln) == entry {
+			return nil
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		entry = "\n" + entry
+	}
+	_, err = fmt.Fprintln(f, entry)
+	return err
+}
+
+// removeEmptyParents removes the now empty directories between path and root
+func removeEmptyParents(ctx context.Context, path, root string) {
+	if layoutName(root, path) == "" {
+		return
+	}
+	for dir := filepath.Dir(path); dir != root && layoutName(root, dir) != ""; dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			// 空でなければ os.Remove は失敗する
+			return
+		}
+		util.Log(ctx).Verbose("[verbose] 空になったディレクトリを削除しました: %s", dir)
+	}
+}
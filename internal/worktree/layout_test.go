@@ -0,0 +1,144 @@
+package worktree
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/manattan/clove/internal/util"
+)
+
+func TestLayoutPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	now := time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)
+	repo := "/src/app"
+
+	tests := []struct {
+		layout   Layout
+		wantPath string
+		wantRoot string
+	}{
+		{Layout{}, "/src/app-feature-x", "/src"},
+		{Layout{Prefix: "wt", Suffix: "-tmp"}, "/src/wt-feature-x-tmp", "/src"},
+		{Layout{Template: "~/worktrees/{repo}/{branch}"}, filepath.Join(home, "worktrees/app/feature/x"), filepath.Join(home, "worktrees/app")},
+		{Layout{Template: "{repoParent}/{repo}.worktrees/{branch}"}, "/src/app.worktrees/feature/x", "/src/app.worktrees"},
+		{Layout{Template: ".worktrees/{sanitized}"}, "/src/app/.worktrees/feature-x", "/src/app/.worktrees"},
+		{Layout{Template: "/wt/{remote}/{date}-{sanitized}"}, "/wt/origin/2025-03-04-feature-x", "/wt/origin"},
+		{Layout{MaxLength: 12}, "/src/app-" + util.ShortHash("app-feature-x"), "/src"},
+		{Layout{Template: "/wt/{repo}/{branch}", MaxLength: 5}, "/wt/app/" + util.ShortHash("feature") + "/x", "/wt/app"},
+	}
+	for _, tt := range tests {
+		got, err := tt.layout.Path(repo, "feature/x", "origin", now)
+		if err != nil || got != tt.wantPath {
+			t.Errorf("Path(%q) = %q, %v; want %q", tt.layout.Template, got, err, tt.wantPath)
+		}
+		if root := tt.layout.Root(repo, "origin"); root != tt.wantRoot {
+			t.Errorf("Root(%q) = %q, want %q", tt.layout.Template, root, tt.wantRoot)
+		}
+	}
+
+	if _, err := (Layout{Template: "{repoParent}/{nope}"}).Path(repo, "x", "origin", now); err == nil {
+		t.Error("Path should fail for an unknown variable")
+	}
+	if _, err := (Layout{Template: "{repoParent}"}).Path(repo, "x", "origin", now); err == nil {
+		t.Error("Path should fail when the worktree would contain the repository")
+	}
+}
+
+func TestLayoutName(t *testing.T) {
+	tests := []struct {
+		root, path, want string
+	}{
+		{"/wt/app", "/wt/app/feature/x", "feature/x"},
+		{"/wt/app", "/wt/app", ""},
+		{"/wt/app", "/wt/app-x", ""},
+		{"/wt/app", "/src/app", ""},
+	}
+	for _, tt := range tests {
+		if got := layoutName(tt.root, tt.path); got != tt.want {
+			t.Errorf("layoutName(%q, %q) = %q, want %q", tt.root, tt.path, got, tt.want)
+		}
+	}
+	if got := excludeEntry("/src/app", "/src/app/.worktrees/feature/x"); got != "/.worktrees/" {
+		t.Errorf("excludeEntry = %q", got)
+	}
+	if got := excludeEntry("/src/app", "/src/app-x"); got != "" {
+		t.Errorf("excludeEntry outside the repository = %q", got)
+	}
+}
+
+func TestAdd_InRepoLayout(t *testing.T) {
+	repo := initRepo(t)
+	ctx := context.Background()
+	layout := Layout{Template: ".worktrees/{branch}"}
+
+	res, err := Add(ctx, repo, AddOptions{Branch: "feature/x", BaseRef: "main", NoFetch: true, Layout: layout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(repo, ".worktrees", "feature", "x"); res.Path != want {
+		t.Errorf("path = %q, want %q", res.Path, want)
+	}
+	data, err := os.ReadFile(filepath.Join(repo, ".git", "info", "exclude"))
+	if err != nil || strings.Count(string(data), "/.worktrees/\n") != 1 {
+		t.Errorf("info/exclude = %q, %v", data, err)
+	}
+	if st, err := collectStatusOf(ctx, repo); err != nil || st.Untracked != 0 {
+		t.Errorf("the worktree should not show up as untracked: %+v, %v", st, err)
+	}
+
+	// 2 回目は exclude を重複して追加しない
+	if _, err := Add(ctx, repo, AddOptions{Branch: "feature/y", BaseRef: "main", NoFetch: true, Layout: layout}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(filepath.Join(repo, ".git", "info", "exclude"))
+	if strings.Count(string(data), "/.worktrees/") != 1 {
+		t.Errorf("info/exclude = %q", data)
+	}
+
+	list, err := List(ctx, repo, ListOptions{Layout: layout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, st := range list.Worktrees {
+		names = append(names, st.Name)
+	}
+	if got := strings.Join(names, ","); got != ",feature/x,feature/y" {
+		t.Errorf("names = %q", got)
+	}
+
+	if wt, err := Resolve(ctx, repo, "feature/x", layout); err != nil || wt.Path != res.Path {
+		t.Errorf("Resolve = %v, %v", wt.Path, err)
+	}
+
+	// 削除すると空になった feature/ も片付ける
+	if _, err := Remove(ctx, repo, RemoveOptions{PathOrBranch: "feature/x", Layout: layout}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".worktrees", "feature")); err != nil {
+		t.Errorf("feature/ still holds feature/y: %v", err)
+	}
+	if _, err := Remove(ctx, repo, RemoveOptions{PathOrBranch: "feature/y", Layout: layout}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".worktrees", "feature")); !os.IsNotExist(err) {
+		t.Errorf("empty feature/ should be removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".worktrees")); err != nil {
+		t.Errorf("the layout root should be kept: %v", err)
+	}
+}
+
+// collectStatusOf returns the status of the worktree at dir
+func collectStatusOf(ctx context.Context, dir string) (Status, error) {
+	worktrees, err := listWorktrees(ctx, dir)
+	if err != nil {
+		return Status{}, err
+	}
+	return CollectStatus(ctx, worktrees[:1], StatusOptions{})[0], nil
+}
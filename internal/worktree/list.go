@@ -0,0 +1,239 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manattan/clove/internal/clone"
+	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/util"
+)
+
+// ListOptions contains options for List operation
+type ListOptions struct {
+	Columns []string
+	BaseRef string
+	// Remote provides the default base (<remote>/HEAD) when BaseRef is empty
+	Remote string
+	// Layout gives the root the name column is relative to
+	Layout Layout
+	// Current is the worktree clove runs in, flagged as "current"
+	Current string
+}
+
+// column is a single column of the `clove list` table
+type column struct {
+	name   string
+	header string
+	value  func(st Status, now time.Time) string
+}
+
+// columns lists every available column in display order
+var columns = []column{
+	{"branch", "BRANCH", func(st Status, _ time.Time) string {
+		switch {
+		case st.Bare:
+			return "(bare)"
+		case st.Branch == "":
+			return "(detached)"
+		}
+		branch := strings.TrimPrefix(st.Branch, "refs/heads/")
+		if st.PR > 0 {
+			return fmt.Sprintf("%s (#%d)", branch, st.PR)
+		}
+		return branch
+	}},
+	{"head", "HEAD", func(st Status, _ time.Time) string {
+		if len(st.Head) > 7 {
+			return st.Head[:7]
+		}
+		return orDash(st.Head)
+	}},
+	{"upstream", "UPSTREAM", func(st Status, _ time.Time) string {
+		if st.Upstream == "" {
+			return "-"
+		}
+		return fmt.Sprintf("%s +%d/-%d", st.Upstream, st.UpstreamAhead, st.UpstreamBehind)
+	}},
+	{"base", "BASE", func(st Status, _ time.Time) string {
+		if !st.HasBase {
+			return "-"
+		}
+		return fmt.Sprintf("+%d/-%d", st.BaseAhead, st.BaseBehind)
+	}},
+	{"dirty", "DIRTY", func(st Status, _ time.Time) string {
+		return fmt.Sprintf("%d", st.Dirty)
+	}},
+	{"untracked", "UNTRACKED", func(st Status, _ time.Time) string {
+		return fmt.Sprintf("%d", st.Untracked)
+	}},
+	{"age", "AGE", func(st Status, now time.Time) string {
+		return FormatAge(st.LastCommit, now)
+	}},
+	{"flags", "FLAGS", func(st Status, _ time.Time) string {
+		var flags []string
+		if st.Current {
+			flags = append(flags, "current")
+		}
+		if st.Locked {
+			flags = append(flags, "locked")
+		}
+		if st.Prunable {
+			flags = append(flags, "prunable")
+		}
+		if st.Error != "" {
+			flags = append(flags, "error")
+		}
+		return orDash(strings.Join(flags, ","))
+	}},
+	{"size", "SIZE", func(st Status, _ time.Time) string {
+		if st.Prunable {
+			return "-"
+		}
+		return clone.FormatBytes(st.Size)
+	}},
+	{"name", "NAME", func(st Status, _ time.Time) string {
+		return orDash(st.Name)
+	}},
+	{"path", "PATH", func(st Status, _ time.Time) string {
+		return st.Path
+	}},
+}
+
+// DefaultColumns are shown when --columns is not given (size is opt-in since it walks the tree)
+var DefaultColumns = []string{"branch", "head", "upstream", "base", "dirty", "untracked", "age", "flags", "path"}
+
+// ColumnNames returns the names of all available columns
+func ColumnNames() []string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// selectColumns resolves column names into column definitions
+func selectColumns(names []string) ([]column, error) {
+	if len(names) == 0 {
+		names = DefaultColumns
+	}
+	var selected []column
+	for _, n := range names {
+		found := false
+		for _, c := range columns {
+			if c.name == n {
+				selected = append(selected, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("未知の列です: %s（使用可能: %s）", n, strings.Join(ColumnNames(), ", "))
+		}
+	}
+	return selected, nil
+}
+
+// ListResult describes the outcome of List
+type ListResult struct {
+	Repo      string   `json:"repo"`
+	Base      string   `json:"base,omitempty"`
+	Worktrees []Status `json:"worktrees"`
+
+	columns []column
+}
+
+// List collects the status of every worktree
+func List(ctx context.Context, repoRoot string, opts ListOptions) (*ListResult, error) {
+	util.Verbose("[verbose] worktree の一覧を取得します")
+
+	cols, err := selectColumns(opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	worktrees, err := listWorktrees(ctx, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	base, _, err := git.ResolveBase(ctx, repoRoot, opts.BaseRef, git.DefaultRemote(ctx, repoRoot, opts.Remote), false)
+	if err != nil {
+		util.Verbose("[verbose] %v。比較をスキップします", err)
+	}
+
+	statusOpts := StatusOptions{BaseRef: base}
+	for _, c := range cols {
+		if c.name == "size" {
+			statusOpts.Size = true
+		}
+	}
+	util.Verbose("[verbose] %d 件の worktree の状態を取得中...", len(worktrees))
+	statuses := CollectStatus(ctx, worktrees, statusOpts)
+	prs := prNumbers(ctx, repoRoot)
+	root := layoutRoot(ctx, repoRoot, opts.Layout)
+	for i := range statuses {
+		statuses[i].PR = prs[strings.TrimPrefix(statuses[i].Branch, "refs/heads/")]
+		statuses[i].Name = layoutName(root, statuses[i].Path)
+		statuses[i].Current = opts.Current != "" && filepath.Clean(statuses[i].Path) == opts.Current
+	}
+
+	return &ListResult{Repo: repoRoot, Base: base, Worktrees: statuses, columns: cols}, nil
+}
+
+// Render writes the result as a table
+func (r *ListResult) Render(w io.Writer) {
+	renderTable(w, r.columns, r.Worktrees, time.Now())
+}
+
+// ListPorcelain prints `git worktree list --porcelain` as is
+func ListPorcelain(ctx context.Context, repoRoot string) error {
+	util.Verbose("[verbose] porcelain モードで出力します")
+	return git.Run(ctx, "git", "-C", repoRoot, "worktree", "list", "--porcelain")
+}
+
+// renderTable writes statuses as an aligned table
+func renderTable(w io.Writer, cols []column, statuses []Status, now time.Time) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	headers := make([]string, len(cols))
+	for i, c := range cols {
+		headers[i] = c.header
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	for _, st := range statuses {
+		values := make([]string, len(cols))
+		for i, c := range cols {
+			values[i] = c.value(st, now)
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+	tw.Flush()
+}
+
+// Preview renders every column of st as "HEADER  value" lines for the picker
+func Preview(st Status, now time.Time) string {
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, c := range columns {
+		if (c.name == "size" && st.Size == 0) || (c.name == "name" && st.Name == "") {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", c.header, c.value(st, now))
+	}
+	tw.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// orDash returns s, or "-" when s is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
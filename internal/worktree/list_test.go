@@ -0,0 +1,218 @@
+package worktree
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/manattan/clove/internal/git"
+)
+
+// initRepo creates a repository with a single commit for integration tests
+func initRepo(t *testing.T) string {
+	t.Helper()
+	t.Setenv("GIT_AUTHOR_NAME", "clove")
+	t.Setenv("GIT_AUTHOR_EMAIL", "clove@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "clove")
+	t.Setenv("GIT_COMMITTER_EMAIL", "clove@example.com")
+
+	repo := filepath.Join(t.TempDir(), "repo")
+	if _, err := git.Git(context.Background(), "", "init", "-q", "-b", "main", repo); err != nil {
+		t.Skipf("git init failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "README.md"), []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git.Git(context.Background(), repo, "add", "README.md"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git.Git(context.Background(), repo, "commit", "-q", "-m", "initial"); err != nil {
+		t.Fatal(err)
+	}
+	// macOS の /var -> /private/var のようなシンボリックリンクを解決しておく
+	if resolved, err := filepath.EvalSymlinks(repo); err == nil {
+		repo = resolved
+	}
+	return repo
+}
+
+func TestSelectColumns(t *testing.T) {
+	cols, err := selectColumns(nil)
+	if err != nil {
+		t.Fatalf("selectColumns failed: %v", err)
+	}
+	if len(cols) != len(DefaultColumns) {
+		t.Errorf("expected %d default columns, got %d", len(DefaultColumns), len(cols))
+	}
+
+	cols, err = selectColumns([]string{"path", "branch"})
+	if err != nil {
+		t.Fatalf("selectColumns failed: %v", err)
+	}
+	if cols[0].name != "path" || cols[1].name != "branch" {
+		t.Errorf("columns should keep the requested order, got %s, %s", cols[0].name, cols[1].name)
+	}
+
+	if _, err := selectColumns([]string{"nope"}); err == nil {
+		t.Error("selectColumns should fail for unknown column")
+	}
+}
+
+func TestRenderTable(t *testing.T) {
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	statuses := []Status{
+		{
+			WorktreeInfo:  WorktreeInfo{Path: "/repo", Branch: "refs/heads/main", Head: "1234567890abcdef"},
+			Upstream:      "origin/main",
+			UpstreamAhead: 2,
+			LastCommit:    now.Add(-3 * time.Hour),
+		},
+		{
+			WorktreeInfo: WorktreeInfo{Path: "/repo-x", Head: "fedcba0987654321", Detached: true, Locked: true},
+			Dirty:        3,
+			LastCommit:   now.Add(-48 * time.Hour),
+		},
+	}
+	cols, err := selectColumns([]string{"branch", "head", "upstream", "dirty", "age", "flags"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	renderTable(&buf, cols, statuses, now)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d:\n%s", len(lines), buf.String())
+	}
+
+	expected := [][]string{
+		{"BRANCH", "HEAD", "UPSTREAM", "DIRTY", "AGE", "FLAGS"},
+		{"main", "1234567", "origin/main", "+2/-0", "0", "3h", "-"},
+		{"(detached)", "fedcba0", "-", "3", "2d", "locked"},
+	}
+	for i, want := range expected {
+		if got := strings.Fields(lines[i]); strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("line %d = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestPreview(t *testing.T) {
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	st := Status{
+		WorktreeInfo: WorktreeInfo{Path: "/repo-x", Branch: "refs/heads/feature/x", Head: "1234567890abcdef", Locked: true},
+		Dirty:        2,
+		LastCommit:   now.Add(-5 * time.Hour),
+	}
+
+	lines := strings.Split(Preview(st, now), "\n")
+	if len(lines) != len(columns)-2 {
+		t.Fatalf("expected %d lines without size and name, got %d:\n%s", len(columns)-2, len(lines), strings.Join(lines, "\n"))
+	}
+	for _, want := range []string{"BRANCH feature/x", "DIRTY 2", "AGE 5h", "FLAGS locked", "PATH /repo-x"} {
+		found := false
+		for _, ln := range lines {
+			if strings.Join(strings.Fields(ln), " ") == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("preview should contain %q:\n%s", want, strings.Join(lines, "\n"))
+		}
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		t        time.Time
+		expected string
+	}{
+		{time.Time{}, "-"},
+		{now.Add(-10 * time.Second), "now"},
+		{now.Add(-5 * time.Minute), "5m"},
+		{now.Add(-5 * time.Hour), "5h"},
+		{now.Add(-5 * 24 * time.Hour), "5d"},
+		{now.Add(-65 * 24 * time.Hour), "2mo"},
+		{now.Add(-800 * 24 * time.Hour), "2y"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatAge(tt.t, now); got != tt.expected {
+			t.Errorf("FormatAge(%v) = %q, want %q", tt.t, got, tt.expected)
+		}
+	}
+}
+
+func TestCollectStatus_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	repo := initRepo(t)
+	wtPath := filepath.Join(filepath.Dir(repo), "repo-feature")
+	if _, err := git.Git(context.Background(), repo, "worktree", "add", "-q", "-b", "feature", wtPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(wtPath, "README.md"), []byte("changed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(wtPath, "new.txt"), []byte("new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git.Git(context.Background(), repo, "worktree", "lock", wtPath); err != nil {
+		t.Fatal(err)
+	}
+
+	worktrees, err := listWorktrees(context.Background(), repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := CollectStatus(context.Background(), worktrees, StatusOptions{BaseRef: "main", Size: true})
+	if len(statuses) != 2 {
+		t.Fatalf("expected 2 statuses, got %d", len(statuses))
+	}
+
+	main, feature := statuses[0], statuses[1]
+	if main.Dirty != 0 || main.Untracked != 0 || main.Locked {
+		t.Errorf("main status = %+v", main)
+	}
+	if feature.Dirty != 1 || feature.Untracked != 1 {
+		t.Errorf("feature dirty/untracked = %d/%d, want 1/1", feature.Dirty, feature.Untracked)
+	}
+	if !feature.Locked {
+		t.Error("feature worktree should be locked")
+	}
+	if !feature.HasBase || feature.BaseAhead != 0 || feature.BaseBehind != 0 {
+		t.Errorf("feature base = %v +%d/-%d", feature.HasBase, feature.BaseAhead, feature.BaseBehind)
+	}
+	if feature.Size == 0 || feature.LastCommit.IsZero() {
+		t.Errorf("feature size/age not computed: %+v", feature)
+	}
+}
+
+func TestList_Current(t *testing.T) {
+	repo := initRepo(t)
+	wtPath := filepath.Join(filepath.Dir(repo), "repo-feature")
+	if _, err := git.Git(context.Background(), repo, "worktree", "add", "-q", "-b", "feature", wtPath); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := List(context.Background(), repo, ListOptions{Columns: []string{"branch", "flags"}, Current: wtPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Worktrees[0].Current || !res.Worktrees[1].Current {
+		t.Errorf("only the feature worktree should be current: %+v", res.Worktrees)
+	}
+	var b bytes.Buffer
+	res.Render(&b)
+	if !strings.Contains(b.String(), "feature  current") {
+		t.Errorf("flags should show current:\n%s", b.String())
+	}
+}
@@ -0,0 +1,204 @@
+package worktree
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/manattan/clove/internal/util"
+)
+
+// IsPattern reports whether s contains glob metacharacters
+func IsPattern(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// ResolveTargets resolves branch names, paths and glob patterns to worktrees.
+// With allExcept, every linked worktree not matching one of its entries is
+// returned instead. The main worktree is never a target.
+func ResolveTargets(ctx context.Context, repoRoot string, args, allExcept []string) ([]WorktreeInfo, error) {
+	worktrees, err := listWorktrees(ctx, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	if len(worktrees) == 0 {
+		return nil, errors.New("worktree が見つかりません")
+	}
+	main, linked := worktrees[0], worktrees[1:]
+
+	if allExcept != nil {
+		var targets []WorktreeInfo
+		for _, wt := range linked {
+			keep := wt.Bare
+			for _, a := range allExcept {
+				if matchTarget(a, wt) {
+					keep = true
+					break
+				}
+			}
+			if !keep {
+				targets = append(targets, wt)
+			}
+		}
+		return targets, nil
+	}
+
+	var targets []WorktreeInfo
+	seen := map[string]bool{}
+	for _, a := range args {
+		if !IsPattern(a) && matchTarget(a, main) {
+			return nil, fmt.Errorf("メインの worktree は削除できません: %s", a)
+		}
+		found := false
+		for _, wt := range linked {
+			if wt.Bare || !matchTarget(a, wt) {
+				continue
+			}
+			found = true
+			if !seen[wt.Path] {
+				seen[wt.Path] = true
+				targets = append(targets, wt)
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("パスでもブランチでも見つかりませんでした: %s", a)
+		}
+	}
+	return targets, nil
+}
+
+// matchTarget reports whether arg names wt as a branch, a path, or a glob over either
+func matchTarget(arg string, wt WorktreeInfo) bool {
+	branch := strings.TrimPrefix(wt.Branch, "refs/heads/")
+	p := filepath.Clean(wt.Path)
+
+	if IsPattern(arg) {
+		if ok, _ := path.Match(arg, branch); ok && branch != "" {
+			return true
+		}
+		if ok, _ := filepath.Match(arg, filepath.Base(p)); ok {
+			return true
+		}
+		abs, err := filepath.Abs(arg)
+		if err != nil {
+			return false
+		}
+		ok, _ := filepath.Match(abs, p)
+		return ok
+	}
+
+	if branch != "" && arg == branch {
+		return true
+	}
+	if _, err := os.Stat(arg); err != nil {
+		return false
+	}
+	abs, err := filepath.Abs(arg)
+	if err != nil {
+		return false
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	return abs == p
+}
+
+// RemoveMany removes the given worktrees in parallel with at most jobs at a time.
+// Results are in the order of targets; the error reports how many failed.
+// When removals run in parallel, the messages of each one (hooks, safety
+// reports) are buffered and written by PrintRemoveReport.
+func RemoveMany(ctx context.Context, repoRoot string, targets []WorktreeInfo, opts RemoveOptions, jobs int) ([]*RemoveResult, error) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	results := make([]*RemoveResult, len(targets))
+	buffered := jobs > 1 && len(targets) > 1
+
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, wt := range targets {
+		wg.Add(1)
+		go func(i int, wt WorktreeInfo) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			o := opts
+			o.PathOrBranch = wt.Path
+			ctx := ctx
+			var buf bytes.Buffer
+			if buffered {
+				// 並列に実行すると出力が混ざるため、worktree ごとにためておく
+				ctx = util.WithOutput(ctx, &buf)
+			}
+			res, err := Remove(ctx, repoRoot, o)
+			if res == nil {
+				res = &RemoveResult{Repo: repoRoot, Target: wt.Path, Path: wt.Path, Commands: []CommandResult{}}
+			}
+			if err != nil {
+				res.Error = err.Error()
+			}
+			res.output = buf.String()
+			results[i] = res
+		}(i, wt)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, r := range results {
+		if r.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("%d 件中 %d 件の worktree の削除に失敗しました", len(results), failed)
+	}
+	return results, nil
+}
+
+// PrintTargets writes the worktrees about to be removed
+func PrintTargets(targets []WorktreeInfo) {
+	tw := tabwriter.NewWriter(util.Output(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BRANCH\tPATH")
+	for _, wt := range targets {
+		fmt.Fprintf(tw, "%s\t%s\n", displayName(wt), wt.Path)
+	}
+	tw.Flush()
+}
+
+// PrintRemoveReport writes the buffered messages of each removal in order,
+// then one line per removal with its outcome
+func PrintRemoveReport(results []*RemoveResult) {
+	for _, r := range results {
+		if r.output != "" {
+			util.Printf("==> %s\n%s", r.Path, r.output)
+			if !strings.HasSuffix(r.output, "\n") {
+				util.Printf("\n")
+			}
+		}
+	}
+	tw := tabwriter.NewWriter(util.Output(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESULT\tBRANCH\tPATH")
+	for _, r := range results {
+		status := "removed"
+		switch {
+		case r.Error != "":
+			status = "failed"
+		case r.DryRun:
+			status = "dry-run"
+		}
+		line := fmt.Sprintf("%s\t%s\t%s", status, orDash(r.Branch), r.Path)
+		if r.Error != "" {
+			line += "\t" + firstLine(r.Error)
+		}
+		fmt.Fprintln(tw, line)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,179 @@
+package worktree
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/hook"
+	"github.com/manattan/clove/internal/util"
+)
+
+func TestMatchTarget(t *testing.T) {
+	wt := WorktreeInfo{Path: "/src/app-feature-JIRA-123", Branch: "refs/heads/feature/JIRA-123"}
+	tests := []struct {
+		arg      string
+		expected bool
+	}{
+		{"feature/JIRA-123", true},
+		{"feature/JIRA-12*", true},
+		{"feature/*", true},
+		{"*", true},
+		{"app-feature-*", true},
+		{"/src/app-*", true},
+		{"feature/JIRA-124", false},
+		{"bugfix/*", false},
+	}
+	for _, tt := range tests {
+		if got := matchTarget(tt.arg, wt); got != tt.expected {
+			t.Errorf("matchTarget(%q) = %v, want %v", tt.arg, got, tt.expected)
+		}
+	}
+}
+
+// targetBranches returns the sorted short branch names of targets
+func targetBranches(targets []WorktreeInfo) string {
+	var names []string
+	for _, wt := range targets {
+		names = append(names, strings.TrimPrefix(wt.Branch, "refs/heads/"))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func TestResolveTargets_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	repo := initRepo(t)
+	for _, b := range []string{"a1", "a2", "b1"} {
+		addWorktree(t, repo, b)
+	}
+
+	tests := []struct {
+		args      []string
+		allExcept []string
+		expected  string
+	}{
+		{[]string{"a*"}, nil, "a1,a2"},
+		{[]string{"a1", "a*", "b1"}, nil, "a1,a2,b1"},
+		{nil, []string{"a1"}, "a2,b1"},
+		{nil, []string{"a*", "b*"}, ""},
+		{nil, []string{}, "a1,a2,b1"},
+	}
+	for _, tt := range tests {
+		targets, err := ResolveTargets(context.Background(), repo, tt.args, tt.allExcept)
+		if err != nil {
+			t.Errorf("ResolveTargets(%v, %v) failed: %v", tt.args, tt.allExcept, err)
+			continue
+		}
+		if got := targetBranches(targets); got != tt.expected {
+			t.Errorf("ResolveTargets(%v, %v) = %s, want %s", tt.args, tt.allExcept, got, tt.expected)
+		}
+	}
+
+	if _, err := ResolveTargets(context.Background(), repo, []string{"c*"}, nil); err == nil {
+		t.Error("unmatched pattern should fail")
+	}
+	if _, err := ResolveTargets(context.Background(), repo, []string{"main"}, nil); err == nil || !strings.Contains(err.Error(), "メイン") {
+		t.Errorf("main worktree should be refused, got %v", err)
+	}
+}
+
+func TestRemoveMany_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	repo := initRepo(t)
+	var paths []string
+	for _, b := range []string{"x1", "x2", "x3", "x4"} {
+		paths = append(paths, addWorktree(t, repo, b))
+	}
+	// 未コミットの変更があると --force なしでは削除に失敗する
+	if err := os.WriteFile(filepath.Join(paths[3], "README.md"), []byte("dirty\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	targets, err := ResolveTargets(context.Background(), repo, []string{"x*"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := RemoveMany(context.Background(), repo, targets, RemoveOptions{DeleteBranch: DeleteBranchForce}, 4)
+	if err == nil || !strings.Contains(err.Error(), "4 件中 1 件") {
+		t.Fatalf("expected one failure, got %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	for i, r := range results[:3] {
+		if !r.Removed || !r.BranchDeleted || r.Error != "" {
+			t.Errorf("result %d = %+v", i, r)
+		}
+	}
+	if results[3].Removed || results[3].Error == "" {
+		t.Errorf("dirty worktree result = %+v", results[3])
+	}
+	if _, err := os.Stat(paths[3]); err != nil {
+		t.Errorf("dirty worktree should be kept: %v", err)
+	}
+	if out, _ := git.Git(context.Background(), repo, "branch", "--list", "--format=%(refname:short)", "x*"); strings.TrimSpace(out) != "x4" {
+		t.Errorf("remaining branches = %q", out)
+	}
+}
+
+func TestRemoveMany_BufferedOutput(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	repo := initRepo(t)
+	var branches []string
+	for _, b := range []string{"y1", "y2", "y3"} {
+		addWorktree(t, repo, b)
+		branches = append(branches, b)
+	}
+	targets, err := ResolveTargets(context.Background(), repo, []string{"y*"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	util.SetOutput(&out)
+	defer util.SetOutput(os.Stdout)
+
+	hooks := hook.Config{Commands: map[hook.Event]string{
+		hook.PreRemove:  `echo "pre $CLOVE_BRANCH"; sleep 0.1; echo "pre-done $CLOVE_BRANCH"`,
+		hook.PostRemove: `echo "post $CLOVE_BRANCH"`,
+	}}
+	results, err := RemoveMany(context.Background(), repo, targets, RemoveOptions{Hooks: hooks}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("parallel removals should not write before the report: %q", out.String())
+	}
+
+	PrintRemoveReport(results)
+	// worktree ごとの出力がまとまって、対象の順に並ぶ
+	var want []string
+	for _, b := range branches {
+		want = append(want, "pre "+b, "pre-done "+b, "post "+b)
+	}
+	var got []string
+	for _, ln := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(ln, "pre") || strings.HasPrefix(ln, "post") {
+			got = append(got, ln)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hook output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,110 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"github.com/manattan/clove/internal/util"
+)
+
+// uniqueTarget returns target, or target with a hash of branch appended when the
+// directory already exists or belongs to another worktree. Different branches
+// such as feat/a and feat-a sanitize to the same name, so the first one keeps it
+// and later ones are told apart by the hash.
+func uniqueTarget(ctx context.Context, repoRoot, target, branch string, maxLen int) (string, error) {
+	worktrees, err := listWorktrees(ctx, repoRoot)
+	if err != nil {
+		return "", err
+	}
+	fold := caseInsensitiveFS(filepath.Dir(target))
+	util.Verbose("[verbose] 大文字小文字を区別しないファイルシステム: %v", fold)
+
+	for _, wt := range worktrees {
+		if wt.Branch == "refs/heads/"+branch && samePath(wt.Path, target, fold) {
+			return "", fmt.Errorf("ブランチ %s の worktree は既に存在します: %s", branch, wt.Path)
+		}
+	}
+	if !pathTaken(target, worktrees, fold) {
+		return target, nil
+	}
+	alt := filepath.Join(filepath.Dir(target), util.Disambiguate(filepath.Base(target), branch, maxLen))
+	if pathTaken(alt, worktrees, fold) {
+		return "", fmt.Errorf("作成先ディレクトリが既に存在します: %s, %s", target, alt)
+	}
+	util.Info("%s は既に使われているため、%s に作成します", target, alt)
+	return alt, nil
+}
+
+// pathTaken reports whether path exists, is registered as a worktree (even if its
+// directory is missing) or, on case-insensitive filesystems, differs from one
+// of those only in case
+func pathTaken(path string, worktrees []WorktreeInfo, fold bool) bool {
+	if _, err := os.Lstat(path); err == nil {
+		return true
+	}
+	for _, wt := range worktrees {
+		if samePath(wt.Path, path, fold) {
+			return true
+		}
+	}
+	if fold {
+		entries, _ := os.ReadDir(filepath.Dir(path))
+		for _, e := range entries {
+			if strings.EqualFold(e.Name(), filepath.Base(path)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// samePath compares two paths after resolving symlinks in their parents
+func samePath(a, b string, fold bool) bool {
+	a, b = resolveParent(a), resolveParent(b)
+	if fold {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// resolveParent resolves symlinks in the directory part of path, which may not exist yet
+func resolveParent(path string) string {
+	path = filepath.Clean(path)
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		return filepath.Join(dir, filepath.Base(path))
+	}
+	return path
+}
+
+// caseInsensitiveFS reports whether dir is on a case-insensitive filesystem by
+// looking up its nearest existing ancestor with the case of its name swapped
+func caseInsensitiveFS(dir string) bool {
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if base := filepath.Base(d); swapCase(base) != base {
+			if fi, err := os.Stat(d); err == nil {
+				swapped, err := os.Stat(filepath.Join(filepath.Dir(d), swapCase(base)))
+				return err == nil && os.SameFile(fi, swapped)
+			}
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	// 判定できない場合は OS の既定に従う
+	return runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+}
+
+// swapCase turns upper case letters into lower case and vice versa
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
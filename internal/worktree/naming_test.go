@@ -0,0 +1,108 @@
+package worktree
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/manattan/clove/internal/util"
+)
+
+func TestPathTaken(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "app-feat-a"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	worktrees := []WorktreeInfo{{Path: filepath.Join(dir, "app-missing")}}
+
+	tests := []struct {
+		name string
+		path string
+		fold bool
+		want bool
+	}{
+		{"existing directory", "app-feat-a", false, true},
+		{"free", "app-feat-b", false, false},
+		{"registered but missing", "app-missing", false, true},
+		{"case differs", "App-Feat-A", false, false},
+		{"case differs, case-insensitive", "App-Feat-A", true, true},
+		{"registered, case-insensitive", "APP-MISSING", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pathTaken(filepath.Join(dir, tt.path), worktrees, tt.fold); got != tt.want {
+				t.Errorf("pathTaken(%q, fold=%v) = %v, want %v", tt.path, tt.fold, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSwapCase(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"App", "aPP"},
+		{"tmp", "TMP"},
+		{"123", "123"},
+		{"機能", "機能"},
+	}
+	for _, tt := range tests {
+		if got := swapCase(tt.in); got != tt.want {
+			t.Errorf("swapCase(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAdd_NameCollision(t *testing.T) {
+	repo := initRepo(t)
+	ctx := context.Background()
+	parent := filepath.Dir(repo)
+	name := filepath.Base(repo)
+
+	tests := []struct {
+		branch string
+		layout Layout
+		want   string
+	}{
+		{"feat/a", Layout{}, name + "-feat-a"},
+		// feat-a と feat@a は feat/a と同じ名前になるためハッシュを付ける
+		{"feat-a", Layout{}, name + "-feat-a-" + util.ShortHash("feat-a")},
+		{"feat@a", Layout{}, name + "-feat-a-" + util.ShortHash("feat@a")},
+		{"機能/検索", Layout{}, name + "-worktree-" + util.ShortHash("機能/検索")},
+		{"機能/一覧", Layout{}, name + "-worktree-" + util.ShortHash("機能/一覧")},
+		{"機能/設定", Layout{Unicode: true}, name + "-機能-設定"},
+		{"feat/" + strings.Repeat("x", 40), Layout{MaxLength: 24}, util.TruncateName(name+"-feat-"+strings.Repeat("x", 40), 24)},
+	}
+	for _, tt := range tests {
+		res, err := Add(ctx, repo, AddOptions{Branch: tt.branch, BaseRef: "main", NoFetch: true, Layout: tt.layout})
+		if err != nil {
+			t.Fatalf("Add(%q): %v", tt.branch, err)
+		}
+		if want := filepath.Join(parent, tt.want); res.Path != want {
+			t.Errorf("Add(%q) path = %q, want %q", tt.branch, res.Path, want)
+		}
+	}
+
+	// worktree ではないディレクトリも避ける
+	if err := os.Mkdir(filepath.Join(parent, name+"-fix"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	res, err := Add(ctx, repo, AddOptions{Branch: "fix", BaseRef: "main", NoFetch: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(parent, name+"-fix-"+util.ShortHash("fix")); res.Path != want {
+		t.Errorf("path = %q, want %q", res.Path, want)
+	}
+
+	// ハッシュ付きの名前も使われていればエラー
+	if err := os.Mkdir(filepath.Join(parent, name+"-hotfix"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(parent, name+"-hotfix-"+util.ShortHash("hotfix")), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Add(ctx, repo, AddOptions{Branch: "hotfix", BaseRef: "main", NoFetch: true}); err == nil {
+		t.Error("Add should fail when the disambiguated directory exists too")
+	}
+}
@@ -1,66 +1,136 @@
 package worktree
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/manattan/clove/internal/git"
 )
 
+// ErrNotFound is returned when no worktree has the requested branch
+var ErrNotFound = errors.New("branch not found")
+
 // WorktreeInfo represents a worktree entry
 type WorktreeInfo struct {
-	Path   string
-	Branch string
-	Head   string
+	Path           string `json:"path"`
+	Branch         string `json:"branch"`
+	Head           string `json:"head"`
+	Bare           bool   `json:"bare"`
+	Detached       bool   `json:"detached"`
+	Locked         bool   `json:"locked"`
+	LockReason     string `json:"lockReason,omitempty"`
+	Prunable       bool   `json:"prunable"`
+	PrunableReason string `json:"prunableReason,omitempty"`
 }
 
 // ParseWorktreeList parses git worktree list --porcelain output
 func ParseWorktreeList(output string) ([]WorktreeInfo, error) {
+	return parseRecords(strings.Split(output, "\n"), false)
+}
+
+// ParseWorktreeListZ parses git worktree list --porcelain -z output
+func ParseWorktreeListZ(output string) ([]WorktreeInfo, error) {
+	return parseRecords(strings.Split(output, "\x00"), true)
+}
+
+// parseRecords parses porcelain lines into worktree entries.
+// Records start with a "worktree <path>" line and end with an empty line.
+func parseRecords(lines []string, nul bool) ([]WorktreeInfo, error) {
 	var worktrees []WorktreeInfo
-	blocks := strings.Split(output, "\nworktree ")
+	var cur *WorktreeInfo
 
-	for i, b := range blocks {
-		b = strings.TrimSpace(b)
-		if b == "" {
+	for i, ln := range lines {
+		lineNo := i + 1
+		if !nul {
+			ln = strings.TrimSuffix(ln, "\r")
+		}
+		if ln == "" {
+			if cur != nil {
+				worktrees = append(worktrees, *cur)
+				cur = nil
+			}
 			continue
 		}
 
-		// 最初のブロックは "worktree " プレフィックスがあるので削除
-		if i == 0 && strings.HasPrefix(b, "worktree ") {
-			b = strings.TrimPrefix(b, "worktree ")
+		key, value, _ := strings.Cut(ln, " ")
+		if key == "worktree" {
+			if cur != nil {
+				return nil, fmt.Errorf("%d 行目: 前の worktree の区切りがありません", lineNo)
+			}
+			if value == "" {
+				return nil, fmt.Errorf("%d 行目: worktree のパスが空です", lineNo)
+			}
+			cur = &WorktreeInfo{Path: value}
+			continue
+		}
+		if cur == nil {
+			return nil, fmt.Errorf("%d 行目: worktree 行より前に属性があります: %q", lineNo, ln)
 		}
 
-		lines := strings.Split(b, "\n")
-		wtPath := strings.TrimSpace(lines[0])
-
-		var br, head string
-		for _, ln := range lines[1:] {
-			ln = strings.TrimSpace(ln)
-			if strings.HasPrefix(ln, "branch ") {
-				br = strings.TrimSpace(strings.TrimPrefix(ln, "branch "))
-			} else if strings.HasPrefix(ln, "HEAD ") {
-				head = strings.TrimSpace(strings.TrimPrefix(ln, "HEAD "))
+		switch key {
+		case "HEAD":
+			if value == "" {
+				return nil, fmt.Errorf("%d 行目: HEAD の値が空です", lineNo)
 			}
+			cur.Head = value
+		case "branch":
+			if value == "" {
+				return nil, fmt.Errorf("%d 行目: branch の値が空です", lineNo)
+			}
+			cur.Branch = value
+		case "bare":
+			cur.Bare = true
+		case "detached":
+			cur.Detached = true
+		case "locked":
+			cur.Locked = true
+			cur.LockReason = unquoteReason(value, nul)
+		case "prunable":
+			cur.Prunable = true
+			cur.PrunableReason = unquoteReason(value, nul)
+		default:
+			// 将来の git で追加される属性は無視する
 		}
-
-		worktrees = append(worktrees, WorktreeInfo{
-			Path:   wtPath,
-			Branch: br,
-			Head:   head,
-		})
 	}
 
+	if cur != nil {
+		worktrees = append(worktrees, *cur)
+	}
 	return worktrees, nil
 }
 
-// FindPathByBranch finds worktree path by branch name
-func FindPathByBranch(repoRoot, branch string) (string, error) {
-	out, err := git.Git(repoRoot, "worktree", "list", "--porcelain")
+// unquoteReason decodes a C-style quoted reason, which git uses without -z
+// when the reason contains special characters
+func unquoteReason(s string, nul bool) string {
+	if nul || !strings.HasPrefix(s, `"`) {
+		return s
+	}
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+	return s
+}
+
+// listWorktrees runs git worktree list and parses it, preferring -z when supported
+func listWorktrees(ctx context.Context, repoRoot string) ([]WorktreeInfo, error) {
+	if out, err := git.Git(ctx, repoRoot, "worktree", "list", "--porcelain", "-z"); err == nil {
+		return ParseWorktreeListZ(out)
+	}
+	// -z は git 2.36 以降のみ対応
+	out, err := git.Git(ctx, repoRoot, "worktree", "list", "--porcelain")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return ParseWorktreeList(out)
+}
 
-	worktrees, err := ParseWorktreeList(out)
+// FindPathByBranch finds worktree path by branch name
+func FindPathByBranch(ctx context.Context, repoRoot, branch string) (string, error) {
+	worktrees, err := listWorktrees(ctx, repoRoot)
 	if err != nil {
 		return "", err
 	}
@@ -72,5 +142,27 @@ func FindPathByBranch(repoRoot, branch string) (string, error) {
 		}
 	}
 
-	return "", errors.New("branch not found")
+	return "", ErrNotFound
+}
+
+// branchOf returns the short branch name checked out at path, or "" if unknown
+func branchOf(ctx context.Context, repoRoot, path string) string {
+	worktrees, err := listWorktrees(ctx, repoRoot)
+	if err != nil {
+		return ""
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	for _, wt := range worktrees {
+		if filepath.Clean(wt.Path) == abs {
+			return strings.TrimPrefix(wt.Branch, "refs/heads/")
+		}
+	}
+	return ""
 }
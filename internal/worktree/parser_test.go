@@ -0,0 +1,150 @@
+package worktree
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseWorktreeList_Attributes(t *testing.T) {
+	input := `worktree /path/to/bare
+bare
+
+worktree /path/to/locked
+HEAD 1234567890abcdef
+branch refs/heads/locked
+locked "reason with\ttab"
+
+worktree /path/to/gone
+HEAD abcdef1234567890
+detached
+locked
+prunable gitdir file points to non-existent location
+future-attribute value
+`
+
+	result, err := ParseWorktreeList(input)
+	if err != nil {
+		t.Fatalf("ParseWorktreeList failed: %v", err)
+	}
+	if len(result) != 3 {
+		t.Fatalf("expected 3 worktrees, got %d", len(result))
+	}
+
+	if !result[0].Bare || result[0].Head != "" {
+		t.Errorf("bare worktree = %+v", result[0])
+	}
+	if !result[1].Locked || result[1].LockReason != "reason with\ttab" {
+		t.Errorf("locked worktree = %+v", result[1])
+	}
+	gone := result[2]
+	if !gone.Detached || !gone.Locked || gone.LockReason != "" {
+		t.Errorf("detached worktree = %+v", gone)
+	}
+	if !gone.Prunable || gone.PrunableReason != "gitdir file points to non-existent location" {
+		t.Errorf("prunable worktree = %+v", gone)
+	}
+}
+
+func TestParseWorktreeListZ(t *testing.T) {
+	input := strings.Join([]string{
+		"worktree /path/with\nnewline",
+		"HEAD 1234567890abcdef",
+		"branch refs/heads/main",
+		"locked \"not quoted\"",
+		"",
+		"worktree /path/to/other",
+		"HEAD abcdef1234567890",
+		"detached",
+		"",
+		"",
+	}, "\x00")
+
+	result, err := ParseWorktreeListZ(input)
+	if err != nil {
+		t.Fatalf("ParseWorktreeListZ failed: %v", err)
+	}
+	if len(result) != 2 {
+		t.Fatalf("expected 2 worktrees, got %d", len(result))
+	}
+	if result[0].Path != "/path/with\nnewline" {
+		t.Errorf("path = %q", result[0].Path)
+	}
+	if result[0].LockReason != `"not quoted"` {
+		t.Errorf("lock reason should be kept verbatim with -z, got %q", result[0].LockReason)
+	}
+	if !result[1].Detached || result[1].Head != "abcdef1234567890" {
+		t.Errorf("second worktree = %+v", result[1])
+	}
+}
+
+func TestParseWorktreeList_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"attribute before worktree", "HEAD 1234\n"},
+		{"missing separator", "worktree /a\nHEAD 1234\nworktree /b\n"},
+		{"empty path", "worktree \n"},
+		{"empty HEAD", "worktree /a\nHEAD\n"},
+		{"empty branch", "worktree /a\nbranch \n"},
+	}
+
+	for _, tt := range tests {
+		if _, err := ParseWorktreeList(tt.input); err == nil {
+			t.Errorf("%s: expected error for %q", tt.name, tt.input)
+		}
+	}
+}
+
+func TestListWorktrees_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	repo := initRepo(t)
+	worktrees, err := listWorktrees(context.Background(), repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(worktrees) != 1 || worktrees[0].Path != repo || worktrees[0].Branch != "refs/heads/main" {
+		t.Errorf("listWorktrees = %+v", worktrees)
+	}
+}
+
+func FuzzParseWorktreeList(f *testing.F) {
+	f.Add("worktree /a\nHEAD 1234\nbranch refs/heads/main\n\nworktree /b\nbare\n")
+	f.Add("worktree /a\nHEAD 1234\ndetached\nlocked \"x\\ty\"\nprunable gone\n")
+	f.Add("HEAD 1234\n")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		result, err := ParseWorktreeList(input)
+		if err != nil {
+			return
+		}
+		for _, wt := range result {
+			if wt.Path == "" {
+				t.Errorf("parsed worktree without path from %q", input)
+			}
+		}
+	})
+}
+
+func FuzzParseWorktreeListZ(f *testing.F) {
+	f.Add("worktree /a\x00HEAD 1234\x00branch refs/heads/main\x00\x00")
+	f.Add("worktree /a\nb\x00bare\x00\x00worktree /c\x00locked\x00\x00")
+	f.Add("\x00\x00")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		result, err := ParseWorktreeListZ(input)
+		if err != nil {
+			return
+		}
+		for _, wt := range result {
+			if wt.Path == "" {
+				t.Errorf("parsed worktree without path from %q", input)
+			}
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/manattan/clove/internal/git"
+)
+
+// FetchSpec describes a ref fetched into the new branch before Add creates the worktree
+type FetchSpec struct {
+	Remote string
+	Ref    string
+	// PR is recorded in branch.<name>.clove-pr so that `clove list` can show it
+	PR int
+}
+
+// prConfigKey is the branch config key holding the pull request number
+const prConfigKey = "clove-pr"
+
+// prProviders maps a hosting service to its pull request head refspec
+var prProviders = map[string]string{
+	"github": "refs/pull/{number}/head",
+	"gitlab": "refs/merge-requests/{number}/head",
+}
+
+// PRBranch returns the local branch name used for pull request n
+func PRBranch(n int) string {
+	return fmt.Sprintf("pr/%d", n)
+}
+
+// ParsePRNumber parses a pull request number, accepting an optional leading '#'
+func ParsePRNumber(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "#"))
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("PR 番号は正の整数で指定してください: %s", s)
+	}
+	return n, nil
+}
+
+// PRRef returns the ref of pull request n. refspec may contain {number};
+// when empty, the refspec of provider is used ("" detects it from the remote URL).
+func PRRef(ctx context.Context, repoRoot, remote, provider, refspec string, n int) (string, error) {
+	if refspec == "" {
+		if provider == "" {
+			provider = detectProvider(ctx, repoRoot, remote)
+		}
+		spec, ok := prProviders[provider]
+		if !ok {
+			return "", fmt.Errorf("未知のプロバイダです: %s（github / gitlab、または refspec を指定してください）", provider)
+		}
+		refspec = spec
+	}
+	if !strings.Contains(refspec, "{number}") {
+		return "", fmt.Errorf("refspec に {number} が含まれていません: %s", refspec)
+	}
+	return strings.ReplaceAll(refspec, "{number}", strconv.Itoa(n)), nil
+}
+
+// detectProvider guesses the hosting service from the remote URL, defaulting to github
+func detectProvider(ctx context.Context, repoRoot, remote string) string {
+	out, err := git.Git(ctx, repoRoot, "remote", "get-url", remote)
+	if err == nil && strings.Contains(strings.ToLower(out), "gitlab") {
+		return "gitlab"
+	}
+	return "github"
+}
+
+// prNumbers returns the pull request number recorded for each local branch
+func prNumbers(ctx context.Context, repoRoot string) map[string]int {
+	prs := map[string]int{}
+	out, err := git.Git(ctx, repoRoot, "config", "--get-regexp", `^branch\..*\.`+prConfigKey+`$`)
+	if err != nil {
+		return prs
+	}
+	for _, ln := range nonEmptyLines(out) {
+		key, value, ok := strings.Cut(ln, " ")
+		if !ok {
+			continue
+		}
+		branch := strings.TrimSuffix(strings.TrimPrefix(key, "branch."), "."+prConfigKey)
+		if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+			prs[branch] = n
+		}
+	}
+	return prs
+}
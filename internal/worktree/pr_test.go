@@ -0,0 +1,106 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/manattan/clove/internal/git"
+)
+
+func TestParsePRNumber(t *testing.T) {
+	for _, s := range []string{"12", "#12"} {
+		if n, err := ParsePRNumber(s); err != nil || n != 12 {
+			t.Errorf("ParsePRNumber(%q) = %d, %v", s, n, err)
+		}
+	}
+	for _, s := range []string{"", "0", "-1", "abc"} {
+		if _, err := ParsePRNumber(s); err == nil {
+			t.Errorf("ParsePRNumber(%q) should fail", s)
+		}
+	}
+}
+
+func TestPRRef(t *testing.T) {
+	tests := []struct {
+		provider, refspec string
+		expected          string
+	}{
+		{"github", "", "refs/pull/5/head"},
+		{"gitlab", "", "refs/merge-requests/5/head"},
+		{"", "refs/changes/{number}/head", "refs/changes/5/head"},
+		{"gitlab", "refs/pr/{number}", "refs/pr/5"},
+	}
+	for _, tt := range tests {
+		got, err := PRRef(context.Background(), "", "origin", tt.provider, tt.refspec, 5)
+		if err != nil || got != tt.expected {
+			t.Errorf("PRRef(%q, %q) = %q, %v; want %q", tt.provider, tt.refspec, got, err, tt.expected)
+		}
+	}
+	if _, err := PRRef(context.Background(), "", "origin", "bitbucket", "", 5); err == nil {
+		t.Error("PRRef should fail for unknown provider")
+	}
+	if _, err := PRRef(context.Background(), "", "origin", "", "refs/pull/head", 5); err == nil {
+		t.Error("PRRef should fail for refspec without {number}")
+	}
+}
+
+func TestAddPR_Integration(t *testing.T) {
+	repo, _ := initRepoWithRemote(t)
+
+	// PR の head だけがリモートにある状態を作る
+	if _, err := git.Git(context.Background(), repo, "checkout", "-q", "-b", "contrib"); err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, repo, "pr.txt", "pr\n")
+	head, _ := git.Git(context.Background(), repo, "rev-parse", "HEAD")
+	if _, err := git.Git(context.Background(), repo, "push", "-q", "origin", "contrib:refs/pull/7/head", "contrib:refs/merge-requests/8/head"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git.Git(context.Background(), repo, "checkout", "-q", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git.Git(context.Background(), repo, "branch", "-q", "-D", "contrib"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		provider string
+		n        int
+	}{{"github", 7}, {"gitlab", 8}} {
+		ref, err := PRRef(context.Background(), repo, "origin", tt.provider, "", tt.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := Add(context.Background(), repo, AddOptions{Branch: PRBranch(tt.n), Fetch: &FetchSpec{Remote: "origin", Ref: ref, PR: tt.n}})
+		if err != nil {
+			t.Fatalf("Add(pr %d) failed: %v", tt.n, err)
+		}
+		if want := filepath.Join(filepath.Dir(repo), fmt.Sprintf("repo-pr-%d", tt.n)); res.Path != want {
+			t.Errorf("path = %q, want %q", res.Path, want)
+		}
+		got, _ := git.Git(context.Background(), res.Path, "rev-parse", "HEAD")
+		if strings.TrimSpace(got) != strings.TrimSpace(head) {
+			t.Errorf("pr %d HEAD = %q, want %q", tt.n, got, head)
+		}
+	}
+
+	list, err := List(context.Background(), repo, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prs := map[string]int{}
+	for _, st := range list.Worktrees {
+		prs[strings.TrimPrefix(st.Branch, "refs/heads/")] = st.PR
+	}
+	if prs["pr/7"] != 7 || prs["pr/8"] != 8 || prs["main"] != 0 {
+		t.Errorf("PR numbers = %v", prs)
+	}
+
+	// worktree があるブランチは上書きしない
+	if _, err := Add(context.Background(), repo, AddOptions{Branch: PRBranch(7), ForceName: "again", Fetch: &FetchSpec{Remote: "origin", Ref: "refs/pull/7/head", PR: 7}}); err == nil {
+		t.Error("Add should fail when the PR branch is checked out")
+	}
+}
@@ -0,0 +1,46 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/manattan/clove/internal/git"
+)
+
+// findRemoteBranch returns the remote that has branch as a remote-tracking branch,
+// or "" if none does. Only preferred is searched when given. When several remotes
+// have the branch, checkout.defaultRemote decides as in git checkout; otherwise
+// the caller has to choose one.
+func findRemoteBranch(ctx context.Context, repoRoot, branch, preferred string) (string, error) {
+	remotes := []string{preferred}
+	if preferred == "" {
+		var err error
+		if remotes, err = git.Remotes(ctx, repoRoot); err != nil {
+			return "", err
+		}
+	}
+
+	var found []string
+	for _, r := range remotes {
+		if git.RefExists(ctx, repoRoot, "refs/remotes/"+r+"/"+branch) {
+			found = append(found, r)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return found[0], nil
+	}
+
+	if out, err := git.Git(ctx, repoRoot, "config", "--get", "checkout.defaultRemote"); err == nil {
+		def := strings.TrimSpace(out)
+		for _, r := range found {
+			if r == def {
+				return r, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("ブランチ %s は複数のリモートにあります: %s（--remote で指定してください）", branch, strings.Join(found, ", "))
+}
@@ -0,0 +1,199 @@
+package worktree
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/manattan/clove/internal/git"
+)
+
+// initForkRepo creates a repository with "upstream" and "fork" remotes that both have main
+func initForkRepo(t *testing.T) string {
+	t.Helper()
+	repo := initRepo(t)
+	for _, name := range []string{"upstream", "fork"} {
+		remote := filepath.Join(filepath.Dir(repo), name+".git")
+		if _, err := git.Git(context.Background(), "", "init", "-q", "--bare", remote); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := git.Git(context.Background(), repo, "remote", "add", name, remote); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := git.Git(context.Background(), repo, "push", "-q", name, "main"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := git.Git(context.Background(), repo, "fetch", "-q", name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func TestFindRemoteBranch_Integration(t *testing.T) {
+	repo := initForkRepo(t)
+	if _, err := git.Git(context.Background(), repo, "push", "-q", "fork", "main:only-fork"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git.Git(context.Background(), repo, "fetch", "-q", "fork"); err != nil {
+		t.Fatal(err)
+	}
+
+	if r, err := findRemoteBranch(context.Background(), repo, "only-fork", ""); err != nil || r != "fork" {
+		t.Errorf("findRemoteBranch(only-fork) = %q, %v; want fork", r, err)
+	}
+	if r, err := findRemoteBranch(context.Background(), repo, "only-fork", "upstream"); err != nil || r != "" {
+		t.Errorf("findRemoteBranch(only-fork, upstream) = %q, %v; want none", r, err)
+	}
+	if r, err := findRemoteBranch(context.Background(), repo, "missing", ""); err != nil || r != "" {
+		t.Errorf("findRemoteBranch(missing) = %q, %v; want none", r, err)
+	}
+
+	_, err := findRemoteBranch(context.Background(), repo, "main", "")
+	if err == nil || !strings.Contains(err.Error(), "fork, upstream") {
+		t.Errorf("findRemoteBranch(main) should be ambiguous, got %v", err)
+	}
+	if _, err := git.Git(context.Background(), repo, "config", "checkout.defaultRemote", "upstream"); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := findRemoteBranch(context.Background(), repo, "main", ""); err != nil || r != "upstream" {
+		t.Errorf("findRemoteBranch(main) with checkout.defaultRemote = %q, %v; want upstream", r, err)
+	}
+}
+
+func TestAdd_Remote(t *testing.T) {
+	repo := initForkRepo(t)
+	if _, err := git.Git(context.Background(), repo, "push", "-q", "fork", "main:topic"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git.Git(context.Background(), repo, "fetch", "-q", "fork"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Add(context.Background(), repo, AddOptions{Branch: "topic", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cmds []string
+	for _, c := range res.Commands {
+		cmds = append(cmds, strings.Join(c.Args, " "))
+	}
+	joined := strings.Join(cmds, "\n")
+	if !strings.Contains(joined, "fetch --prune --all") || !strings.Contains(joined, "-b topic fork/topic") {
+		t.Errorf("commands = %s", joined)
+	}
+
+	// origin がなければ upstream の既定ブランチを起点にする
+	if res.Base != "upstream/main" {
+		t.Errorf("base = %q, want upstream/main", res.Base)
+	}
+
+	res, err = Add(context.Background(), repo, AddOptions{Branch: "new", Remote: "fork", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Base != "fork/main" || res.BaseSource != git.BaseCandidate {
+		t.Errorf("base = %q (%s), want fork/main", res.Base, res.BaseSource)
+	}
+	if got := strings.Join(res.Commands[0].Args, " "); !strings.HasSuffix(got, "fetch --prune fork") {
+		t.Errorf("fetch = %s", got)
+	}
+
+	if _, err := Add(context.Background(), repo, AddOptions{Branch: "new", Remote: "nope", DryRun: true}); err == nil {
+		t.Error("Add should fail for an unknown remote")
+	}
+	// fetch しない場合、存在しない base ref では worktree add を組み立てる前に失敗する
+	res, err = Add(context.Background(), repo, AddOptions{Branch: "new", BaseRef: "fork/develop", NoFetch: true, DryRun: true})
+	if err == nil || len(res.Commands) != 0 {
+		t.Errorf("Add with a missing base = %v, %v; want an error", res.Commands, err)
+	}
+	// fetch する場合は fetch の後で確認し、worktree add は実行しない
+	res, err = Add(context.Background(), repo, AddOptions{Branch: "new", BaseRef: "fork/develop"})
+	if err == nil || len(res.Commands) != 1 {
+		t.Errorf("Add with a missing base = %v, %v; want an error after the fetch", res.Commands, err)
+	}
+}
+
+func TestAdd_BaseAfterFetch(t *testing.T) {
+	repo := initForkRepo(t)
+	// fetch するまでローカルにはない base
+	fork := filepath.Join(filepath.Dir(repo), "fork.git")
+	if _, err := git.Git(context.Background(), fork, "branch", "release", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if git.RefExists(context.Background(), repo, "refs/remotes/fork/release") {
+		t.Fatal("fork/release should not be fetched yet")
+	}
+
+	res, err := Add(context.Background(), repo, AddOptions{Branch: "feat", BaseRef: "fork/release", Remote: "fork"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Base != "fork/release" || res.BaseSource != git.BaseExplicit {
+		t.Errorf("base = %q (%s), want fork/release", res.Base, res.BaseSource)
+	}
+	if !git.RefExists(context.Background(), repo, "refs/heads/feat") {
+		t.Error("feat should be created from fork/release")
+	}
+}
+
+func TestAdd_DryRunOffline(t *testing.T) {
+	repo := initForkRepo(t)
+	if _, err := git.Git(context.Background(), repo, "remote", "rename", "fork", "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git.Git(context.Background(), filepath.Join(filepath.Dir(repo), "fork.git"), "symbolic-ref", "HEAD", "refs/heads/main"); err != nil {
+		t.Fatal(err)
+	}
+
+	// dry-run では ls-remote の結果を origin/HEAD として保存しない
+	if _, err := Add(context.Background(), repo, AddOptions{Branch: "feat", Remote: "origin", DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+	if git.RefExists(context.Background(), repo, "refs/remotes/origin/HEAD") {
+		t.Error("dry-run should not write origin/HEAD")
+	}
+}
+
+func TestAdd_Tracking(t *testing.T) {
+	repo, remote := initRepoWithRemote(t)
+	if _, err := git.Git(context.Background(), repo, "fetch", "-q", "origin"); err != nil {
+		t.Fatal(err)
+	}
+
+	upstream := func(branch string) string {
+		r, _ := git.Git(context.Background(), repo, "config", "--get", "branch."+branch+".remote")
+		m, _ := git.Git(context.Background(), repo, "config", "--get", "branch."+branch+".merge")
+		return strings.TrimSpace(r) + " " + strings.TrimSpace(m)
+	}
+
+	tests := []struct {
+		branch   string
+		opts     AddOptions
+		expected string
+	}{
+		{"default", AddOptions{}, "origin refs/heads/main"},
+		{"track", AddOptions{Track: TrackRemote}, "origin refs/heads/track"},
+		{"none", AddOptions{Track: TrackNone}, " "},
+		{"pushed", AddOptions{PushUpstream: true}, "origin refs/heads/pushed"},
+	}
+	for _, tt := range tests {
+		opts := tt.opts
+		opts.Branch = tt.branch
+		opts.NoFetch = true
+		if _, err := Add(context.Background(), repo, opts); err != nil {
+			t.Fatalf("Add(%s) failed: %v", tt.branch, err)
+		}
+		if got := upstream(tt.branch); got != tt.expected {
+			t.Errorf("upstream of %s = %q, want %q", tt.branch, got, tt.expected)
+		}
+	}
+
+	if !git.GitOk(context.Background(), remote, "show-ref", "--verify", "--quiet", "refs/heads/pushed") {
+		t.Error("--push-upstream should create the branch on the remote")
+	}
+	if git.GitOk(context.Background(), remote, "show-ref", "--verify", "--quiet", "refs/heads/track") {
+		t.Error("--track should not push the branch")
+	}
+}
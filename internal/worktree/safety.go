@@ -0,0 +1,198 @@
+package worktree
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/manattan/clove/internal/bootstrap"
+	"github.com/manattan/clove/internal/clone"
+	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/util"
+)
+
+// IgnoredFile is an ignored file that would be deleted with its worktree
+type IgnoredFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// SafetyReport lists work that only exists in a worktree about to be removed
+type SafetyReport struct {
+	Unpushed []string      `json:"unpushed,omitempty"`
+	Stashes  []string      `json:"stashes,omitempty"`
+	Ignored  []IgnoredFile `json:"ignored,omitempty"`
+}
+
+// Empty reports whether nothing would be lost
+func (r SafetyReport) Empty() bool {
+	return len(r.Unpushed) == 0 && len(r.Stashes) == 0 && len(r.Ignored) == 0
+}
+
+// Print writes the report for the worktree at path to log
+func (r SafetyReport) Print(log util.Logger, path string) {
+	log.Printf("警告: %s を削除すると次の作業が失われる可能性があります:\n", path)
+	if len(r.Unpushed) > 0 {
+		log.Printf("  どのリモートにもないコミット (%d 件):\n", len(r.Unpushed))
+		for _, c := range r.Unpushed {
+			log.Printf("    %s\n", c)
+		}
+	}
+	if len(r.Stashes) > 0 {
+		log.Printf("  この worktree で作られた stash (%d 件):\n", len(r.Stashes))
+		for _, s := range r.Stashes {
+			log.Printf("    %s\n", s)
+		}
+	}
+	if len(r.Ignored) > 0 {
+		log.Printf("  大きな ignore 対象のファイル (%d 件):\n", len(r.Ignored))
+		for _, f := range r.Ignored {
+			log.Printf("    %s (%s)\n", f.Path, clone.FormatBytes(f.Size))
+		}
+	}
+}
+
+// SafetyOptions controls which checks checkSafety runs
+type SafetyOptions struct {
+	// SkipUnpushed disables the commit check, e.g. when the branch is known to be merged
+	SkipUnpushed bool
+	// IgnoredThreshold is the minimum size of reported ignored files; 0 disables the check
+	IgnoredThreshold int64
+}
+
+// checkSafety collects commits, stashes and large ignored files that would be lost
+// by removing the worktree at path with the given branch
+func checkSafety(ctx context.Context, repoRoot, path, branch string, opts SafetyOptions) SafetyReport {
+	var r SafetyReport
+	if !opts.SkipUnpushed {
+		r.Unpushed = commitsNotOnRemotes(ctx, repoRoot, path)
+	}
+	r.Stashes = stashesOf(ctx, repoRoot, branch)
+	if opts.IgnoredThreshold > 0 {
+		r.Ignored = largeIgnoredFiles(ctx, path, opts.IgnoredThreshold)
+	}
+	return r
+}
+
+// commitsNotOnRemotes lists commits of HEAD at path not reachable from any remote ref.
+// Without remotes every commit would match, so nothing is reported.
+func commitsNotOnRemotes(ctx context.Context, repoRoot, path string) []string {
+	out, err := git.Git(ctx, repoRoot, "remote")
+	if err != nil || strings.TrimSpace(out) == "" {
+		util.Log(ctx).Verbose("[verbose] リモートがないため、未プッシュのコミットの確認をスキップします")
+		return nil
+	}
+	out, err = git.Git(ctx, path, "log", "--format=%h %s", "HEAD", "--not", "--remotes")
+	if err != nil {
+		return nil
+	}
+	return nonEmptyLines(out)
+}
+
+// stashesOf lists stashes created on branch ("" for detached HEAD).
+// Stashes are shared by all worktrees, so the branch recorded in the stash message is used.
+func stashesOf(ctx context.Context, repoRoot, branch string) []string {
+	out, err := git.Git(ctx, repoRoot, "stash", "list", "--format=%gd %gs")
+	if err != nil {
+		return nil
+	}
+	name := branch
+	if name == "" {
+		name = "(no branch)"
+	}
+	var stashes []string
+	for _, ln := range nonEmptyLines(out) {
+		_, subject, _ := strings.Cut(ln, " ")
+		// "WIP on <branch>: ..." または "On <branch>: ..."
+		rest, ok := strings.CutPrefix(subject, "WIP on ")
+		if !ok {
+			rest, ok = strings.CutPrefix(subject, "On ")
+		}
+		if ok && strings.HasPrefix(rest, name+":") {
+			stashes = append(stashes, ln)
+		}
+	}
+	return stashes
+}
+
+// largeIgnoredFiles lists ignored files in path of at least threshold bytes, largest first.
+// Ignored directories are listed as a whole and walked here, skipping the
+// dependency directories that bootstrap recreates (node_modules and so on).
+func largeIgnoredFiles(ctx context.Context, path string, threshold int64) []IgnoredFile {
+	out, err := git.Git(ctx, path, "ls-files", "-z", "--others", "--ignored", "--exclude-standard", "--directory")
+	if err != nil {
+		return nil
+	}
+	deps := bootstrap.DependencyPaths()
+	var files []IgnoredFile
+	add := func(name string, info fs.FileInfo) {
+		if info.Mode().IsRegular() && info.Size() >= threshold {
+			files = append(files, IgnoredFile{Path: name, Size: info.Size()})
+		}
+	}
+	for _, name := range strings.Split(out, "\x00") {
+		if name == "" {
+			continue
+		}
+		if !strings.HasSuffix(name, "/") {
+			if info, err := os.Lstat(filepath.Join(path, name)); err == nil {
+				add(name, info)
+			}
+			continue
+		}
+		if isDependencyDir(strings.TrimSuffix(name, "/"), deps) {
+			util.Verbose("[verbose] bootstrap で作り直せるため確認しません: %s", name)
+			continue
+		}
+		_ = filepath.WalkDir(filepath.Join(path, name), func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			rel, err := filepath.Rel(path, p)
+			if err != nil {
+				return nil
+			}
+			rel = filepath.ToSlash(rel)
+			if d.IsDir() {
+				if d.Name() == ".git" || isDependencyDir(rel, deps) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				add(rel, info)
+			}
+			return nil
+		})
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].Size > files[j].Size })
+	return files
+}
+
+// isDependencyDir reports whether rel is one of deps, at the top or nested
+// in a subdirectory as in monorepos
+func isDependencyDir(rel string, deps []string) bool {
+	for _, d := range deps {
+		if rel == d || strings.HasSuffix(rel, "/"+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// nonEmptyLines splits s into lines, dropping empty ones
+func nonEmptyLines(s string) []string {
+	var lines []string
+	for _, ln := range strings.Split(s, "\n") {
+		if ln = strings.TrimRight(ln, "\r"); ln != "" {
+			lines = append(lines, ln)
+		}
+	}
+	return lines
+}
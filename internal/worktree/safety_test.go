@@ -0,0 +1,139 @@
+package worktree
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/manattan/clove/internal/git"
+)
+
+func TestStashesOf_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	repo := initRepo(t)
+	wt := addWorktree(t, repo, "feature")
+	for _, dir := range []string{repo, wt} {
+		if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("stash me\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := git.Git(context.Background(), wt, "stash", "push", "-q", "-m", "feature work"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git.Git(context.Background(), repo, "stash", "push", "-q"); err != nil {
+		t.Fatal(err)
+	}
+
+	stashes := stashesOf(context.Background(), repo, "feature")
+	if len(stashes) != 1 || !strings.Contains(stashes[0], "On feature: feature work") {
+		t.Errorf("stashesOf(feature) = %v", stashes)
+	}
+	if got := stashesOf(context.Background(), repo, "main"); len(got) != 1 || !strings.Contains(got[0], "WIP on main:") {
+		t.Errorf("stashesOf(main) = %v", got)
+	}
+}
+
+func TestRemove_SafetyCheck(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	repo, _ := initRepoWithRemote(t)
+	wt := addWorktree(t, repo, "feature")
+	commitFile(t, wt, ".gitignore", "*.db\n")
+	if err := os.WriteFile(filepath.Join(wt, "big.db"), make([]byte, 2048), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(wt, "small.db"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := RemoveOptions{PathOrBranch: wt, Safety: SafetyOptions{IgnoredThreshold: 1024}}
+	res, err := Remove(context.Background(), repo, opts)
+	if err == nil || !strings.Contains(err.Error(), "--i-know") {
+		t.Fatalf("expected safety check to block, got %v", err)
+	}
+	if res.Removed {
+		t.Error("worktree should not be removed")
+	}
+	if len(res.Safety.Unpushed) != 1 {
+		t.Errorf("unpushed = %v", res.Safety.Unpushed)
+	}
+	if len(res.Safety.Ignored) != 1 || res.Safety.Ignored[0].Path != "big.db" || res.Safety.Ignored[0].Size != 2048 {
+		t.Errorf("ignored = %+v", res.Safety.Ignored)
+	}
+
+	opts.DryRun = true
+	if _, err := Remove(context.Background(), repo, opts); err != nil {
+		t.Errorf("dry-run should only report: %v", err)
+	}
+
+	opts.DryRun = false
+	opts.IKnow = true
+	res, err = Remove(context.Background(), repo, opts)
+	if err != nil || !res.Removed {
+		t.Errorf("--i-know should remove the worktree: %+v, %v", res, err)
+	}
+}
+
+func TestRemove_NoRemote(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	// リモートがなければ未プッシュのコミットは報告しない
+	repo := initRepo(t)
+	wt := addWorktree(t, repo, "local")
+	commitFile(t, wt, "local.txt", "local\n")
+
+	res, err := Remove(context.Background(), repo, RemoveOptions{PathOrBranch: wt})
+	if err != nil || !res.Removed || !res.Safety.Empty() {
+		t.Errorf("Remove = %+v, %v", res, err)
+	}
+}
+
+func TestRemove_SafetySkipsDependencies(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	// bootstrap で持ち込んだ node_modules の大きなファイルは削除を止めない
+	repo := initRepo(t)
+	commitFile(t, repo, ".gitignore", "node_modules/\ndata/\n")
+	commitFile(t, repo, "package.json", "{}\n")
+	commitFile(t, repo, "package-lock.json", "{}\n")
+	writeFiles(t, repo, "node_modules/native/build/addon.node")
+	if err := os.WriteFile(filepath.Join(repo, "node_modules", "native", "build", "addon.node"), make([]byte, 2048), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Add(context.Background(), repo, AddOptions{Branch: "feature", BaseRef: "main", NoFetch: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(res.Path, "node_modules", "native", "build", "addon.node")); err != nil {
+		t.Fatalf("node_modules should be bootstrapped: %v", err)
+	}
+	// 無視されたディレクトリの中の大きなファイルは報告する
+	writeFiles(t, res.Path, "data/dump.sql")
+	if err := os.WriteFile(filepath.Join(res.Path, "data", "dump.sql"), make([]byte, 2048), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := largeIgnoredFiles(context.Background(), res.Path, 1024)
+	if len(got) != 1 || got[0].Path != "data/dump.sql" {
+		t.Errorf("ignored = %+v, want only data/dump.sql", got)
+	}
+	if err := os.Remove(filepath.Join(res.Path, "data", "dump.sql")); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := Remove(context.Background(), repo, RemoveOptions{PathOrBranch: res.Path, Safety: SafetyOptions{IgnoredThreshold: 1024}})
+	if err != nil || !removed.Removed {
+		t.Errorf("Remove = %+v, %v", removed, err)
+	}
+}
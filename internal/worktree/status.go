@@ -0,0 +1,141 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/manattan/clove/internal/git"
+)
+
+// Status is the computed state of a single worktree for `clove list`
+type Status struct {
+	WorktreeInfo
+
+	Upstream       string    `json:"upstream,omitempty"`
+	UpstreamAhead  int       `json:"upstreamAhead"`
+	UpstreamBehind int       `json:"upstreamBehind"`
+	BaseAhead      int       `json:"baseAhead"`
+	BaseBehind     int       `json:"baseBehind"`
+	HasBase        bool      `json:"hasBase"`
+	Dirty          int       `json:"dirty"`
+	Untracked      int       `json:"untracked"`
+	LastCommit     time.Time `json:"lastCommit"`
+	Size           int64     `json:"size,omitempty"`
+	PR             int       `json:"pr,omitempty"`
+	Name           string    `json:"name,omitempty"`
+	Current        bool      `json:"current,omitempty"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// StatusOptions controls which expensive fields are computed
+type StatusOptions struct {
+	// BaseRef is compared against for ahead/behind counts
+	BaseRef string
+	// Size enables walking each worktree to compute its disk usage
+	Size bool
+}
+
+// CollectStatus computes the status of each worktree concurrently
+func CollectStatus(ctx context.Context, worktrees []WorktreeInfo, opts StatusOptions) []Status {
+	statuses := make([]Status, len(worktrees))
+
+	sem := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+	for i, wt := range worktrees {
+		wg.Add(1)
+		go func(i int, wt WorktreeInfo) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			statuses[i] = collectOne(ctx, wt, opts)
+		}(i, wt)
+	}
+	wg.Wait()
+
+	return statuses
+}
+
+// collectOne computes the status of a single worktree
+func collectOne(ctx context.Context, wt WorktreeInfo, opts StatusOptions) Status {
+	st := Status{WorktreeInfo: wt}
+
+	if wt.Bare {
+		return st
+	}
+	if _, err := os.Stat(wt.Path); err != nil {
+		st.Prunable = true
+		return st
+	}
+
+	if upstream, err := git.Upstream(ctx, wt.Path); err == nil {
+		st.Upstream = upstream
+		st.UpstreamAhead, st.UpstreamBehind, _ = git.AheadBehind(ctx, wt.Path, "HEAD", upstream)
+	}
+
+	if opts.BaseRef != "" {
+		var err error
+		st.BaseAhead, st.BaseBehind, err = git.AheadBehind(ctx, wt.Path, "HEAD", opts.BaseRef)
+		st.HasBase = err == nil
+	}
+
+	counts, err := git.Status(ctx, wt.Path)
+	if err != nil {
+		st.Error = err.Error()
+		return st
+	}
+	st.Dirty, st.Untracked = counts.Dirty, counts.Untracked
+
+	if t, err := git.LastCommit(ctx, wt.Path); err == nil {
+		st.LastCommit = t
+	}
+
+	if opts.Size {
+		st.Size = dirSize(wt.Path)
+	}
+
+	return st
+}
+
+// dirSize sums the sizes of regular files under dir
+func dirSize(dir string) int64 {
+	var total int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+// FormatAge renders the time since t as a compact relative age
+func FormatAge(t time.Time, now time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo", int(d.Hours()/24/30))
+	}
+	return fmt.Sprintf("%dy", int(d.Hours()/24/365))
+}
@@ -0,0 +1,161 @@
+package worktree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SwitchResult describes the worktree chosen by `clove switch`
+type SwitchResult struct {
+	Repo    string `json:"repo"`
+	Query   string `json:"query"`
+	Path    string `json:"path"`
+	Branch  string `json:"branch,omitempty"`
+	Created bool   `json:"created"`
+}
+
+// AmbiguousError is returned when a fuzzy query matches several worktrees
+type AmbiguousError struct {
+	Query      string
+	Candidates []WorktreeInfo
+}
+
+func (e *AmbiguousError) Error() string {
+	var names []string
+	for _, wt := range e.Candidates {
+		names = append(names, displayName(wt))
+	}
+	return fmt.Sprintf("%q に一致する worktree が複数あります: %s", e.Query, strings.Join(names, ", "))
+}
+
+// Resolve finds the worktree for query.
+// An exact branch name or worktree path wins; otherwise the query is matched
+// fuzzily against branch names, directory names and paths relative to the
+// layout root. ErrNotFound is returned when nothing matches.
+func Resolve(ctx context.Context, repoRoot, query string, layout Layout) (WorktreeInfo, error) {
+	if wt, err := ResolveExact(ctx, repoRoot, query); !errors.Is(err, ErrNotFound) {
+		return wt, err
+	}
+
+	worktrees, err := listWorktrees(ctx, repoRoot)
+	if err != nil {
+		return WorktreeInfo{}, err
+	}
+	matches := fuzzyMatch(query, worktrees, layoutRoot(ctx, repoRoot, layout))
+	switch len(matches) {
+	case 0:
+		return WorktreeInfo{}, ErrNotFound
+	case 1:
+		return matches[0], nil
+	}
+	return WorktreeInfo{}, &AmbiguousError{Query: query, Candidates: matches}
+}
+
+// ResolveExact finds the worktree whose branch is query or whose path is query.
+// Unlike Resolve it never matches fuzzily, so a miss can be created as a new branch.
+func ResolveExact(ctx context.Context, repoRoot, query string) (WorktreeInfo, error) {
+	path, err := FindPathByBranch(ctx, repoRoot, query)
+	if err == nil {
+		return WorktreeInfo{Path: path, Branch: "refs/heads/" + query}, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return WorktreeInfo{}, err
+	}
+
+	abs, err := filepath.Abs(query)
+	if err != nil {
+		return WorktreeInfo{}, ErrNotFound
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return WorktreeInfo{}, ErrNotFound
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	worktrees, err := listWorktrees(ctx, repoRoot)
+	if err != nil {
+		return WorktreeInfo{}, err
+	}
+	for _, wt := range worktrees {
+		if filepath.Clean(wt.Path) == abs {
+			return wt, nil
+		}
+	}
+	return WorktreeInfo{}, ErrNotFound
+}
+
+// fuzzyMatch returns the worktrees in the best matching tier for query.
+// Tiers are, in order: case-insensitive exact name, substring, subsequence.
+func fuzzyMatch(query string, worktrees []WorktreeInfo, root string) []WorktreeInfo {
+	q := strings.ToLower(query)
+	tiers := make([][]WorktreeInfo, 3)
+	for _, wt := range worktrees {
+		if wt.Bare {
+			continue
+		}
+		best := -1
+		for _, name := range matchNames(wt, root) {
+			tier := -1
+			switch {
+			case name == q:
+				tier = 0
+			case strings.Contains(name, q):
+				tier = 1
+			case isSubsequence(q, name):
+				tier = 2
+			}
+			if tier >= 0 && (best < 0 || tier < best) {
+				best = tier
+			}
+		}
+		if best >= 0 {
+			tiers[best] = append(tiers[best], wt)
+		}
+	}
+	for _, t := range tiers {
+		if len(t) > 0 {
+			return t
+		}
+	}
+	return nil
+}
+
+// matchNames returns the lower-cased names a worktree can be matched by
+func matchNames(wt WorktreeInfo, root string) []string {
+	names := []string{strings.ToLower(filepath.Base(wt.Path))}
+	if n := layoutName(root, wt.Path); n != "" && strings.Contains(n, "/") {
+		names = append(names, strings.ToLower(n))
+	}
+	if b := strings.TrimPrefix(wt.Branch, "refs/heads/"); b != "" {
+		names = append(names, strings.ToLower(b))
+	}
+	return names
+}
+
+// isSubsequence reports whether every rune of q appears in s in order
+func isSubsequence(q, s string) bool {
+	rs := []rune(s)
+	i := 0
+	for _, r := range q {
+		for i < len(rs) && rs[i] != r {
+			i++
+		}
+		if i == len(rs) {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+// displayName returns the branch name of wt, or its directory name if detached
+func displayName(wt WorktreeInfo) string {
+	if b := strings.TrimPrefix(wt.Branch, "refs/heads/"); b != "" {
+		return b
+	}
+	return filepath.Base(wt.Path)
+}
@@ -0,0 +1,93 @@
+package worktree
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/manattan/clove/internal/git"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	worktrees := []WorktreeInfo{
+		{Path: "/src/app", Branch: "refs/heads/main"},
+		{Path: "/src/app-feature-update", Branch: "refs/heads/feature/update"},
+		{Path: "/src/app-feature-upload", Branch: "refs/heads/feature/upload"},
+		{Path: "/src/app-fix", Head: "abc", Detached: true},
+		{Path: "/src/bare", Bare: true},
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"MAIN", []string{"/src/app"}},
+		{"update", []string{"/src/app-feature-update"}},
+		{"feature/up", []string{"/src/app-feature-update", "/src/app-feature-upload"}},
+		{"app-fix", []string{"/src/app-fix"}},
+		{"fupdt", []string{"/src/app-feature-update"}},
+		{"bare", nil},
+		{"zzz", nil},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, wt := range fuzzyMatch(tt.query, worktrees, "/src") {
+			got = append(got, wt.Path)
+		}
+		if len(got) != len(tt.expected) {
+			t.Errorf("fuzzyMatch(%q) = %v, want %v", tt.query, got, tt.expected)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.expected[i] {
+				t.Errorf("fuzzyMatch(%q) = %v, want %v", tt.query, got, tt.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestResolve_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	repo := initRepo(t)
+	for _, b := range []string{"feature/update", "feature/upload"} {
+		p := filepath.Join(filepath.Dir(repo), "repo-"+b[len("feature/"):])
+		if _, err := git.Git(context.Background(), repo, "worktree", "add", "-q", "-b", b, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wt, err := Resolve(context.Background(), repo, "feature/update", Layout{})
+	if err != nil || wt.Path != filepath.Join(filepath.Dir(repo), "repo-update") {
+		t.Errorf("exact branch: %+v, %v", wt, err)
+	}
+
+	wt, err = Resolve(context.Background(), repo, filepath.Join(filepath.Dir(repo), "repo-upload"), Layout{})
+	if err != nil || wt.Branch != "refs/heads/feature/upload" {
+		t.Errorf("path: %+v, %v", wt, err)
+	}
+
+	var amb *AmbiguousError
+	if _, err := Resolve(context.Background(), repo, "feature/up", Layout{}); !errors.As(err, &amb) || len(amb.Candidates) != 2 {
+		t.Errorf("expected ambiguous error, got %v", err)
+	}
+
+	if _, err := Resolve(context.Background(), repo, "nothing", Layout{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	// 完全一致だけを見る場合、部分一致する worktree は選ばない
+	if wt, err := ResolveExact(context.Background(), repo, "feature/upd"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ResolveExact(feature/upd) = %+v, %v; want ErrNotFound", wt, err)
+	}
+	if wt, err := ResolveExact(context.Background(), repo, "feature/update"); err != nil || wt.Path != filepath.Join(filepath.Dir(repo), "repo-update") {
+		t.Errorf("ResolveExact(feature/update) = %+v, %v", wt, err)
+	}
+	if wt, err := ResolveExact(context.Background(), repo, filepath.Join(filepath.Dir(repo), "repo-upload")); err != nil || wt.Branch != "refs/heads/feature/upload" {
+		t.Errorf("ResolveExact(path) = %+v, %v", wt, err)
+	}
+}
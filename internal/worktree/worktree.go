@@ -1,25 +1,45 @@
 package worktree
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/manattan/clove/internal/bootstrap"
 	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/hook"
 	"github.com/manattan/clove/internal/util"
 )
 
 // AddOptions contains options for Add operation
 type AddOptions struct {
-	Branch    string
-	BaseRef   string
-	Prefix    string
-	Suffix    string
-	ForceName string
-	OpenCmd   string
-	DryRun    bool
-	NoFetch   bool
+	Branch  string
+	BaseRef string
+	// Remote is searched for the branch and used for the default base; "" searches all remotes
+	Remote string
+	// Track and PushUpstream configure the upstream of a newly created branch
+	Track        Tracking
+	PushUpstream bool
+	ForceName    string
+	OpenCmd      string
+	DryRun       bool
+	NoFetch      bool
+	Copy         []string
+	CopyMode     CopyMode
+	Bootstrap    bootstrap.Options
+	Hooks        hook.Config
+	// Source is the worktree that Copy files and bootstrap directories come from.
+	// "" uses repoRoot, or the default branch worktree of a bare repository.
+	Source string
+	// Fetch, if set, fetches a ref into Branch instead of creating it from BaseRef
+	Fetch *FetchSpec
+	// Layout decides the worktree directory; ForceName places it directly under the layout root
+	Layout Layout
 }
 
 // RemoveOptions contains options for Remove operation
@@ -27,205 +47,681 @@ type RemoveOptions struct {
 	PathOrBranch string
 	Force        bool
 	DryRun       bool
+	DeleteBranch BranchDeletion
+	DeleteRemote bool
+	// IKnow removes the worktree even if the safety check finds work that would be lost
+	IKnow  bool
+	Safety SafetyOptions
+	Hooks  hook.Config
+	// Layout tells which emptied directories to clean up after removal
+	Layout Layout
 }
 
 // PruneOptions contains options for Prune operation
 type PruneOptions struct {
 	DryRun  bool
 	Verbose bool
+	Hooks   hook.Config
+}
+
+// CommandResult records a planned or executed command
+type CommandResult struct {
+	Args  []string `json:"args"`
+	Error string   `json:"error,omitempty"`
+}
+
+// BootstrapResult records a bootstrap step
+type BootstrapResult struct {
+	Name     string   `json:"name"`
+	Strategy string   `json:"strategy"`
+	Paths    []string `json:"paths,omitempty"`
+	Command  string   `json:"command,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// AddResult describes the outcome of Add
+type AddResult struct {
+	Repo   string `json:"repo"`
+	Branch string `json:"branch"`
+	Base   string `json:"base"`
+	// BaseSource tells how Base was chosen
+	BaseSource git.BaseSource    `json:"baseSource,omitempty"`
+	Path       string            `json:"path"`
+	PR         int               `json:"pr,omitempty"`
+	DryRun     bool              `json:"dryRun"`
+	Commands   []CommandResult   `json:"commands"`
+	Copied     []string          `json:"copied,omitempty"`
+	Bootstrap  []BootstrapResult `json:"bootstrap,omitempty"`
+	Warnings   []string          `json:"warnings,omitempty"`
+	// RolledBack reports that a cancelled Add removed what it had created
+	RolledBack bool `json:"rolledBack,omitempty"`
+}
+
+// RemoveResult describes the outcome of Remove
+type RemoveResult struct {
+	Repo     string          `json:"repo"`
+	Target   string          `json:"target"`
+	Path     string          `json:"path"`
+	Branch   string          `json:"branch,omitempty"`
+	DryRun   bool            `json:"dryRun"`
+	Commands []CommandResult `json:"commands"`
+	Removed  bool            `json:"removed"`
+
+	BranchDeleted bool         `json:"branchDeleted"`
+	RemoteDeleted bool         `json:"remoteDeleted"`
+	Safety        SafetyReport `json:"safety"`
+	Error         string       `json:"error,omitempty"`
+
+	// output holds the messages of a parallel removal until PrintRemoveReport
+	output string
 }
 
-// ListOptions contains options for List operation
-type ListOptions struct {
-	Porcelain bool
+// PruneResult describes the outcome of Prune
+type PruneResult struct {
+	Repo     string          `json:"repo"`
+	DryRun   bool            `json:"dryRun"`
+	Commands []CommandResult `json:"commands"`
+	// Pruned lists the paths of the pruned worktrees (the admin directory
+	// worktrees/<name> when the path is unknown)
+	Pruned []string `json:"pruned"`
+}
+
+// refMu serializes branch deletions of concurrent Remove calls
+var refMu sync.Mutex
+
+// runCommand executes args and records the result
+func runCommand(ctx context.Context, args []string) (CommandResult, error) {
+	res := CommandResult{Args: args}
+	log := util.Log(ctx)
+	log.Verbose("[verbose] 実行中: %s", util.ShellJoin(args))
+	if err := git.Run(ctx, args[0], args[1:]...); err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+	log.Verbose("[verbose] 完了: %s", util.ShellJoin(args))
+	return res, nil
 }
 
 // Add creates a new worktree
-func Add(repoRoot string, opts AddOptions) error {
-	parent := filepath.Dir(repoRoot)
-	repoName := filepath.Base(repoRoot)
-
-	dirName := opts.ForceName
-	if dirName == "" {
-		p := opts.Prefix
-		if p == "" {
-			p = repoName
-		}
-		dirName = fmt.Sprintf("%s-%s%s", p, util.Sanitize(opts.Branch), opts.Suffix)
-	}
-	target := filepath.Join(parent, dirName)
-
-	base := opts.BaseRef
-	if base == "" {
-		util.Verbose("[verbose] base ref が未指定のため自動検出中...")
-		if ref, err := git.Git(repoRoot, "symbolic-ref", "-q", "--short", "refs/remotes/origin/HEAD"); err == nil {
-			base = strings.TrimSpace(ref)
-			util.Verbose("[verbose] origin/HEAD から base ref を検出: %s", base)
-		} else {
-			base = "origin/main"
-			util.Verbose("[verbose] origin/HEAD が見つからないため、デフォルトの base ref を使用: %s", base)
-		}
-	} else {
-		util.Verbose("[verbose] base ref として %s を使用", base)
+func Add(ctx context.Context, repoRoot string, opts AddOptions) (*AddResult, error) {
+	remote := git.DefaultRemote(ctx, repoRoot, opts.Remote)
+
+	res := &AddResult{Repo: repoRoot, Branch: opts.Branch, DryRun: opts.DryRun, Commands: []CommandResult{}}
+	if opts.Fetch != nil {
+		res.PR = opts.Fetch.PR
 	}
 
+	target, err := opts.Layout.Path(repoRoot, opts.Branch, remote, time.Now())
+	if err != nil {
+		return res, err
+	}
+	if opts.ForceName != "" {
+		target = filepath.Join(opts.Layout.Root(repoRoot, remote), opts.ForceName)
+	} else if target, err = uniqueTarget(ctx, repoRoot, target, opts.Branch, opts.Layout.MaxLength); err != nil {
+		return res, err
+	}
+	res.Path = target
+
+	if opts.Remote != "" && !git.HasRemote(ctx, repoRoot, opts.Remote) {
+		return res, fmt.Errorf("リモートが見つかりません: %s", opts.Remote)
+	}
+	if (opts.Track == TrackRemote || opts.PushUpstream) && !git.HasRemote(ctx, repoRoot, remote) {
+		return res, fmt.Errorf("upstream に設定するリモート %s がありません（--remote で指定してください）", remote)
+	}
 	if _, err := os.Stat(target); err == nil {
-		return fmt.Errorf("作成先ディレクトリが既に存在します: %s", target)
+		return res, fmt.Errorf("作成先ディレクトリが既に存在します: %s", target)
 	}
 
 	util.Verbose("[verbose] ブランチの存在を確認中...")
-	existsLocal := git.GitOk(repoRoot, "show-ref", "--verify", "--quiet", "refs/heads/"+opts.Branch)
-	existsRemote := git.GitOk(repoRoot, "show-ref", "--verify", "--quiet", "refs/remotes/origin/"+opts.Branch)
+	existsLocal := git.RefExists(ctx, repoRoot, "refs/heads/"+opts.Branch)
 	util.Verbose("[verbose] ローカルブランチ %s: %v", opts.Branch, existsLocal)
-	util.Verbose("[verbose] リモートブランチ origin/%s: %v", opts.Branch, existsRemote)
+	var trackRemote string
+	if !existsLocal && opts.Fetch == nil {
+		var err error
+		if trackRemote, err = findRemoteBranch(ctx, repoRoot, opts.Branch, opts.Remote); err != nil {
+			return res, err
+		}
+		util.Verbose("[verbose] リモートブランチ: %s", orDash(trackRemote))
+	}
+
+	fetching := !opts.NoFetch && opts.Fetch == nil
+	// fetch して初めて現れる base もあるため、明示された base の存在は fetch の後で確認する
+	verifyBase := false
+	switch {
+	case opts.Fetch != nil:
+		res.Base = opts.Fetch.Remote + " " + opts.Fetch.Ref
+	case fetching && opts.BaseRef != "" && !existsLocal && trackRemote == "":
+		res.Base, res.BaseSource = opts.BaseRef, git.BaseExplicit
+		verifyBase = true
+	default:
+		util.Verbose("[verbose] base ref を決定中（リモート: %s）...", remote)
+		// dry-run では ls-remote も <remote>/HEAD の保存もしない
+		base, src, err := git.ResolveBase(ctx, repoRoot, opts.BaseRef, remote, fetching && !opts.DryRun)
+		switch {
+		case err == nil:
+			res.Base, res.BaseSource = base, src
+			util.Verbose("[verbose] base ref: %s（%s）", base, describeBase(src, remote))
+		case existsLocal || trackRemote != "":
+			// 既存のブランチをチェックアウトするだけなので base ref は使わない
+			util.Verbose("[verbose] %v", err)
+		default:
+			return res, err
+		}
+	}
+	base := res.Base
 
 	var actions [][]string
-	if !opts.NoFetch {
-		actions = append(actions, []string{"git", "-C", repoRoot, "fetch", "--prune", "origin"})
+	if fetching {
+		fetch := []string{"git", "-C", repoRoot, "fetch", "--prune", remote}
+		if remotes, _ := git.Remotes(ctx, repoRoot); opts.Remote == "" && len(remotes) > 1 {
+			// どのリモートのブランチを使うか分からないため、すべて取得する
+			fetch = []string{"git", "-C", repoRoot, "fetch", "--prune", "--all"}
+		}
+		actions = append(actions, fetch)
 	}
 
 	var wtCmd []string
+	created := false
 	switch {
+	case opts.Fetch != nil:
+		// PR は force push されることがあるため、ローカルブランチを上書きで更新する
+		if path, err := checkedOutElsewhere(ctx, repoRoot, opts.Branch, target); err != nil {
+			return res, err
+		} else if path != "" {
+			return res, fmt.Errorf("ブランチ %s は既に %s でチェックアウトされています", opts.Branch, path)
+		}
+		actions = append(actions, []string{"git", "-C", repoRoot, "fetch", opts.Fetch.Remote, "+" + opts.Fetch.Ref + ":refs/heads/" + opts.Branch})
+		wtCmd = []string{"git", "-C", repoRoot, "worktree", "add", target, opts.Branch}
 	case existsLocal:
 		wtCmd = []string{"git", "-C", repoRoot, "worktree", "add", target, opts.Branch}
-	case existsRemote:
-		wtCmd = []string{"git", "-C", repoRoot, "worktree", "add", target, "-b", opts.Branch, "origin/" + opts.Branch}
+	case trackRemote != "":
+		wtCmd = []string{"git", "-C", repoRoot, "worktree", "add", target, "-b", opts.Branch, trackRemote + "/" + opts.Branch}
 	default:
+		created = true
 		wtCmd = []string{"git", "-C", repoRoot, "worktree", "add", target, "-b", opts.Branch, base}
+		if opts.Track != TrackDefault || opts.PushUpstream {
+			// base を upstream にしない
+			wtCmd = []string{"git", "-C", repoRoot, "worktree", "add", "--no-track", target, "-b", opts.Branch, base}
+		}
 	}
 	actions = append(actions, wtCmd)
+	if created {
+		actions = append(actions, upstreamActions(repoRoot, target, remote, opts)...)
+	}
+	if opts.Fetch != nil && opts.Fetch.PR > 0 {
+		actions = append(actions, []string{"git", "-C", repoRoot, "config", "branch." + opts.Branch + "." + prConfigKey, strconv.Itoa(opts.Fetch.PR)})
+	}
+
+	exclude := excludeEntry(repoRoot, target)
+	if exclude != "" && git.IsBare(ctx, repoRoot) {
+		// bare リポジトリには未追跡ファイルがないので不要
+		exclude = ""
+	}
 
-	fmt.Printf("repo:   %s\n", repoRoot)
-	fmt.Printf("base:   %s\n", base)
-	fmt.Printf("branch: %s\n", opts.Branch)
-	fmt.Printf("dir:    %s\n", target)
+	source := sourceRoot(ctx, repoRoot, opts.Source)
+	includes, err := matchIncludes(ctx, source, target, opts.Copy)
+	if err != nil {
+		return res, err
+	}
+
+	steps, err := bootstrap.Plan(source, opts.Bootstrap)
+	if err != nil {
+		return res, err
+	}
+
+	util.Printf("repo:   %s\n", repoRoot)
+	if res.BaseSource != "" {
+		util.Printf("base:   %s（%s）\n", base, describeBase(res.BaseSource, remote))
+	} else {
+		util.Printf("base:   %s\n", orDash(base))
+	}
+	util.Printf("branch: %s\n", opts.Branch)
+	util.Printf("dir:    %s\n", target)
 
 	if opts.DryRun {
-		fmt.Println("\n(dry-run) 実行予定コマンド:")
+		if exclude != "" {
+			util.Printf("\n(dry-run) %s を .git/info/exclude に追加します\n", exclude)
+		}
+		util.Printf("\n(dry-run) 実行予定コマンド:\n")
 		for _, a := range actions {
-			fmt.Println("  " + util.ShellJoin(a))
+			util.Printf("  %s\n", util.ShellJoin(a))
+			res.Commands = append(res.Commands, CommandResult{Args: a})
+		}
+		if len(includes) > 0 {
+			util.Printf("\n(dry-run) %s 予定のファイル:\n", opts.CopyMode)
+			for _, rel := range includes {
+				util.Printf("  %s -> %s\n", filepath.Join(source, rel), filepath.Join(target, rel))
+			}
+			res.Copied = includes
 		}
-		return nil
+		if len(steps) > 0 {
+			util.Printf("\n(dry-run) bootstrap 予定:\n")
+			for _, st := range steps {
+				util.Printf("  %s\n", st.Describe())
+				res.Bootstrap = append(res.Bootstrap, bootstrapResult(st))
+			}
+		}
+		printHookPlan(ctx, opts.Hooks, hook.PostAdd)
+		return res, nil
 	}
 
-	for _, a := range actions {
-		util.Verbose("[verbose] 実行中: %s", util.ShellJoin(a))
-		if err := git.Run(a[0], a[1:]...); err != nil {
-			return err
+	// 中断されたら、作りかけの worktree と作成したブランチを片付ける
+	abort := func(err error) (*AddResult, error) {
+		// --timeout などで git や hook だけが止まった場合も、ctx は生きていても片付ける
+		if ctx.Err() != nil || git.IsInterrupted(err) {
+			res.rollback(repoRoot, target, opts.Branch, existsLocal)
 		}
-		util.Verbose("[verbose] 完了: %s", util.ShellJoin(a))
+		return res, err
 	}
 
-	// TypeScriptプロジェクトの場合、node_modulesをコピー
-	if err := copyNodeModulesIfExists(repoRoot, target); err != nil {
-		fmt.Printf("警告: node_modules のコピーに失敗しました: %v\n", err)
+	if exclude != "" {
+		// リポジトリ内に作る worktree が未追跡ファイルとして見えないようにする
+		util.Verbose("[verbose] %s を info/exclude に追加します", exclude)
+		if err := addExclude(ctx, repoRoot, exclude); err != nil {
+			return res, fmt.Errorf("info/exclude を更新できませんでした: %w", err)
+		}
+	}
+
+	for i, a := range actions {
+		cr, err := runCommand(ctx, a)
+		res.Commands = append(res.Commands, cr)
+		if err != nil {
+			return abort(err)
+		}
+		if i == 0 && verifyBase && !git.RefExists(ctx, repoRoot, base) {
+			return res, fmt.Errorf("base ref が見つかりません: %s", base)
+		}
+	}
+
+	if len(includes) > 0 {
+		util.Printf("\n未追跡ファイルを %s します: %s\n", opts.CopyMode, strings.Join(includes, ", "))
+		copied, err := copyIncludes(ctx, source, target, includes, opts.CopyMode)
+		res.Copied = copied
+		if err != nil {
+			res.warn("ファイルの %s に失敗しました: %v", opts.CopyMode, err)
+		}
+	}
+
+	runBootstrap(ctx, res, steps, source, target)
+	if ctx.Err() != nil {
+		return abort(fmt.Errorf("worktree の作成は中断されました: %w", ctx.Err()))
+	}
+
+	env := hook.Env{RepoRoot: repoRoot, WorktreePath: target, Branch: opts.Branch, BaseRef: base}
+	if err := opts.Hooks.Run(ctx, hook.PostAdd, env, target); err != nil {
+		return abort(err)
 	}
 
 	if opts.OpenCmd != "" {
 		util.Verbose("[verbose] エディタを開きます: %s %s", opts.OpenCmd, target)
-		_ = git.Run(opts.OpenCmd, target)
+		_ = git.Run(ctx, opts.OpenCmd, target)
 	}
 
-	return nil
+	return res, nil
 }
 
-// copyNodeModulesIfExists copies node_modules from source to target if it exists
-func copyNodeModulesIfExists(repoRoot, target string) error {
-	packageJSON := filepath.Join(repoRoot, "package.json")
-	util.Verbose("[verbose] package.json の存在を確認中: %s", packageJSON)
-	if _, err := os.Stat(packageJSON); err != nil {
-		util.Verbose("[verbose] package.json が見つかりません。node_modules のコピーをスキップします")
-		// package.jsonがなければスキップ
-		return nil
+// sourceRoot returns the worktree that include files and bootstrap directories
+// are carried from. The container of a bare repository has no checkout, so the
+// worktree of the default branch (HEAD of the bare repository) is used instead.
+func sourceRoot(ctx context.Context, repoRoot, source string) string {
+	if source != "" {
+		return source
 	}
-
-	nodeModules := filepath.Join(repoRoot, "node_modules")
-	util.Verbose("[verbose] node_modules の存在を確認中: %s", nodeModules)
-	if _, err := os.Stat(nodeModules); err != nil {
-		util.Verbose("[verbose] node_modules が見つかりません。コピーをスキップします")
-		// node_modulesがなければスキップ
-		return nil
+	if !git.IsBare(ctx, repoRoot) {
+		return repoRoot
+	}
+	out, err := git.Git(ctx, repoRoot, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		return repoRoot
+	}
+	p, err := FindPathByBranch(ctx, repoRoot, strings.TrimSpace(out))
+	if err != nil {
+		util.Verbose("[verbose] 既定ブランチの worktree がないため、%s からコピーします", repoRoot)
+		return repoRoot
 	}
+	return p
+}
 
-	fmt.Printf("\nnode_modules をコピー中...\n")
-	targetNodeModules := filepath.Join(target, "node_modules")
-	util.Verbose("[verbose] コピー元: %s", nodeModules)
-	util.Verbose("[verbose] コピー先: %s", targetNodeModules)
+// rollback removes the worktree at target and, unless it existed before Add,
+// the branch. It runs without the cancelled context of Add and only touches
+// the administrative files of target, leaving other stale worktrees alone.
+func (r *AddResult) rollback(repoRoot, target, branch string, branchExisted bool) {
+	ctx := context.Background()
+	util.Info("中断またはタイムアウトしたため、作成途中の worktree を削除します: %s", target)
 
-	// cp -a でシンボリックリンクや権限を保持してコピー
-	util.Verbose("[verbose] 実行中: cp -a %s %s", nodeModules, targetNodeModules)
-	if err := git.Run("cp", "-a", nodeModules, targetNodeModules); err != nil {
-		return err
+	if _, err := os.Stat(target); err == nil {
+		if _, err := git.Git(ctx, repoRoot, "worktree", "remove", "--force", target); err != nil {
+			// worktree add の途中で止まると git が worktree として認識していないことがある
+			util.Verbose("[verbose] git worktree remove に失敗したため直接削除します: %v", err)
+			if err := os.RemoveAll(target); err != nil {
+				r.warn("%s を削除できませんでした: %v", target, err)
+				return
+			}
+		}
+	}
+	// git worktree prune は関係のない worktree の参照まで消すため、target の管理ディレクトリだけを消す
+	if repo, err := git.OpenRepository(ctx, repoRoot); err == nil {
+		for name, p := range adminPaths(ctx, repoRoot) {
+			if !samePath(p, target, false) {
+				continue
+			}
+			admin := filepath.Join(repo.CommonDir, "worktrees", name)
+			if err := os.RemoveAll(admin); err != nil {
+				r.warn("%s を削除できませんでした: %v", admin, err)
+			}
+		}
+	}
+	if !branchExisted && git.RefExists(ctx, repoRoot, "refs/heads/"+branch) {
+		if _, err := git.Git(ctx, repoRoot, "branch", "-D", branch); err != nil {
+			r.warn("ブランチ %s を削除できませんでした: %v", branch, err)
+			return
+		}
+		util.Verbose("[verbose] 作成したブランチを削除しました: %s", branch)
 	}
+	r.RolledBack = true
+}
 
-	fmt.Printf("node_modules のコピーが完了しました\n")
-	util.Verbose("[verbose] node_modules のコピーが正常に完了しました")
+// upstreamActions returns the commands that set the upstream of a new branch to
+// the same-named branch on remote. With PushUpstream the branch is pushed right away.
+func upstreamActions(repoRoot, target, remote string, opts AddOptions) [][]string {
+	switch {
+	case opts.PushUpstream:
+		return [][]string{{"git", "-C", target, "push", "-u", remote, opts.Branch}}
+	case opts.Track == TrackRemote:
+		return [][]string{
+			{"git", "-C", repoRoot, "config", "branch." + opts.Branch + ".remote", remote},
+			{"git", "-C", repoRoot, "config", "branch." + opts.Branch + ".merge", "refs/heads/" + opts.Branch},
+		}
+	}
 	return nil
 }
 
-// List shows worktree list
-func List(repoRoot string, opts ListOptions) error {
-	util.Verbose("[verbose] worktree の一覧を表示します")
-	if opts.Porcelain {
-		util.Verbose("[verbose] porcelain モードで出力します")
-		return git.Run("git", "-C", repoRoot, "worktree", "list", "--porcelain")
+// describeBase explains how the base ref was chosen
+func describeBase(src git.BaseSource, remote string) string {
+	switch src {
+	case git.BaseExplicit:
+		return "指定された ref"
+	case git.BaseRemoteHead:
+		return remote + "/HEAD"
+	case git.BaseLsRemote:
+		return "git ls-remote --symref で検出"
+	case git.BaseInitDefault:
+		return "init.defaultBranch"
+	}
+	return "既定ブランチの候補（" + strings.Join(git.DefaultBranchCandidates, " / ") + "）から検出"
+}
+
+// warn prints a warning and records it in the result
+func (r *AddResult) warn(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	util.Printf("警告: %s\n", msg)
+	r.Warnings = append(r.Warnings, msg)
+}
+
+// bootstrapResult converts a planned step into a result entry
+func bootstrapResult(st bootstrap.Step) BootstrapResult {
+	return BootstrapResult{Name: st.Name, Strategy: string(st.Strategy), Paths: st.Paths, Command: st.Command}
+}
+
+// runBootstrap prepares dependencies in the new worktree.
+// Failures are reported as warnings since the worktree itself is already usable.
+func runBootstrap(ctx context.Context, res *AddResult, steps []bootstrap.Step, repoRoot, target string) {
+	for _, st := range steps {
+		util.Printf("\nbootstrap: %s\n", st.Describe())
+		br := bootstrapResult(st)
+		if err := bootstrap.Apply(ctx, st, repoRoot, target); err != nil {
+			br.Error = err.Error()
+			res.Bootstrap = append(res.Bootstrap, br)
+			res.warn("bootstrap (%s) に失敗しました: %v", st.Name, err)
+			continue
+		}
+		res.Bootstrap = append(res.Bootstrap, br)
+		util.Printf("bootstrap (%s) が完了しました\n", st.Name)
+	}
+}
+
+// printHookPlan prints the hooks that would run for ev in dry-run mode
+func printHookPlan(ctx context.Context, h hook.Config, ev hook.Event) {
+	cmds := h.Plan(ev)
+	if len(cmds) == 0 {
+		return
+	}
+	log := util.Log(ctx)
+	log.Printf("\n(dry-run) %s hook:\n", ev)
+	for _, c := range cmds {
+		log.Printf("  %s\n", c)
 	}
-	return git.Run("git", "-C", repoRoot, "worktree", "list")
 }
 
 // Prune removes stale worktree references
-func Prune(repoRoot string, opts PruneOptions) error {
+func Prune(ctx context.Context, repoRoot string, opts PruneOptions) (*PruneResult, error) {
 	util.Verbose("[verbose] 削除済み worktree の参照をクリーンアップします")
-	cmd := []string{"git", "-C", repoRoot, "worktree", "prune"}
+	// 削除された worktree を特定できるよう、常に --verbose で実行する
+	cmd := []string{"git", "-C", repoRoot, "worktree", "prune", "--verbose"}
 	if opts.DryRun {
 		cmd = append(cmd, "--dry-run")
 		util.Verbose("[verbose] dry-run モードが有効です")
 	}
+	res := &PruneResult{Repo: repoRoot, DryRun: opts.DryRun, Commands: []CommandResult{}, Pruned: []string{}}
+	paths := adminPaths(ctx, repoRoot)
+
+	util.Verbose("[verbose] 実行中: %s", util.ShellJoin(cmd))
+	out, err := git.Git(ctx, "", cmd[1:]...)
 	if opts.Verbose {
-		cmd = append(cmd, "--verbose")
+		util.Printf("%s", out)
 	}
-	util.Verbose("[verbose] 実行中: %s", util.ShellJoin(cmd))
-	if err := git.Run(cmd[0], cmd[1:]...); err != nil {
-		return err
+	cr := CommandResult{Args: cmd}
+	if err != nil {
+		cr.Error = err.Error()
+		res.Commands = append(res.Commands, cr)
+		return res, err
 	}
+	res.Commands = append(res.Commands, cr)
+	res.Pruned = parsePruned(out, paths)
 	util.Verbose("[verbose] クリーンアップが完了しました")
-	return nil
+
+	if opts.DryRun {
+		printHookPlan(ctx, opts.Hooks, hook.PostPrune)
+		return res, nil
+	}
+	return res, opts.Hooks.Run(ctx, hook.PostPrune, hook.Env{RepoRoot: repoRoot}, repoRoot)
+}
+
+// adminPaths maps the admin directory names under <common>/worktrees to the
+// worktree paths recorded in their gitdir files
+func adminPaths(ctx context.Context, repoRoot string) map[string]string {
+	paths := map[string]string{}
+	repo, err := git.OpenRepository(ctx, repoRoot)
+	if err != nil {
+		return paths
+	}
+	dir := filepath.Join(repo.CommonDir, "worktrees")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return paths
+	}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name(), "gitdir"))
+		if err != nil {
+			continue
+		}
+		// gitdir には <worktree>/.git が書かれている（相対パスなら管理ディレクトリから）
+		gitFile := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(gitFile) {
+			gitFile = filepath.Join(dir, e.Name(), gitFile)
+		}
+		paths[e.Name()] = filepath.Dir(gitFile)
+	}
+	return paths
+}
+
+// parsePruned extracts the pruned worktrees from `git worktree prune --verbose`
+func parsePruned(out string, paths map[string]string) []string {
+	pruned := []string{}
+	for _, ln := range strings.Split(out, "\n") {
+		// "Removing worktrees/<name>: <理由>"
+		rest, ok := strings.CutPrefix(strings.TrimSpace(ln), "Removing ")
+		if !ok {
+			continue
+		}
+		admin, _, _ := strings.Cut(rest, ": ")
+		if p, ok := paths[strings.TrimPrefix(admin, "worktrees/")]; ok {
+			pruned = append(pruned, p)
+		} else {
+			pruned = append(pruned, admin)
+		}
+	}
+	return pruned
 }
 
 // Remove deletes a worktree
-func Remove(repoRoot string, opts RemoveOptions) error {
-	util.Verbose("[verbose] worktree の削除を開始: %s", opts.PathOrBranch)
+func Remove(ctx context.Context, repoRoot string, opts RemoveOptions) (*RemoveResult, error) {
+	log := util.Log(ctx)
+	log.Verbose("[verbose] worktree の削除を開始: %s", opts.PathOrBranch)
 	targetPath := opts.PathOrBranch
+	res := &RemoveResult{Repo: repoRoot, Target: opts.PathOrBranch, DryRun: opts.DryRun, Commands: []CommandResult{}}
 
-	util.Verbose("[verbose] パスの存在を確認中: %s", targetPath)
+	log.Verbose("[verbose] パスの存在を確認中: %s", targetPath)
 	if _, err := os.Stat(targetPath); err != nil {
-		util.Verbose("[verbose] パスが見つかりません。ブランチ名として検索します")
-		p, err2 := FindPathByBranch(repoRoot, opts.PathOrBranch)
+		log.Verbose("[verbose] パスが見つかりません。ブランチ名として検索します")
+		p, err2 := FindPathByBranch(ctx, repoRoot, opts.PathOrBranch)
 		if err2 != nil {
-			return fmt.Errorf("パスでもブランチでも見つかりませんでした: %s", opts.PathOrBranch)
+			return res, fmt.Errorf("パスでもブランチでも見つかりませんでした: %s", opts.PathOrBranch)
 		}
 		targetPath = p
-		util.Verbose("[verbose] ブランチ %s に対応するパスを発見: %s", opts.PathOrBranch, targetPath)
+		log.Verbose("[verbose] ブランチ %s に対応するパスを発見: %s", opts.PathOrBranch, targetPath)
 	} else {
-		util.Verbose("[verbose] パスが存在します: %s", targetPath)
+		log.Verbose("[verbose] パスが存在します: %s", targetPath)
 	}
 
+	res.Path = targetPath
+	res.Branch = branchOf(ctx, repoRoot, targetPath)
+
 	cmd := []string{"git", "-C", repoRoot, "worktree", "remove", targetPath}
 	if opts.Force {
 		cmd = append(cmd, "--force")
-		util.Verbose("[verbose] 強制削除モードが有効です")
+		log.Verbose("[verbose] 強制削除モードが有効です")
+	}
+
+	del, err := planBranchDeletion(ctx, repoRoot, res, opts)
+	if err != nil {
+		return res, err
+	}
+
+	log.Verbose("[verbose] 失われる作業がないか確認中...")
+	res.Safety = checkSafety(ctx, repoRoot, targetPath, res.Branch, opts.Safety)
+	if !res.Safety.Empty() {
+		res.Safety.Print(log, targetPath)
+		switch {
+		case opts.IKnow:
+			log.Info("--i-know が指定されているため削除を続行します")
+		case !opts.DryRun:
+			return res, fmt.Errorf("削除すると失われる作業があります（確認したうえで削除する場合は --i-know を指定してください）: %s", targetPath)
+		}
 	}
 
 	if opts.DryRun {
-		fmt.Println("(dry-run) " + util.ShellJoin(cmd))
-		return nil
+		log.Printf("(dry-run) %s\n", util.ShellJoin(cmd))
+		res.Commands = append(res.Commands, CommandResult{Args: cmd})
+		for _, c := range [][]string{del.local, del.remote} {
+			if c != nil {
+				log.Printf("(dry-run) %s\n", util.ShellJoin(c))
+				res.Commands = append(res.Commands, CommandResult{Args: c})
+			}
+		}
+		printHookPlan(ctx, opts.Hooks, hook.PreRemove)
+		printHookPlan(ctx, opts.Hooks, hook.PostRemove)
+		return res, nil
 	}
 
-	util.Verbose("[verbose] 実行中: %s", util.ShellJoin(cmd))
-	if err := git.Run(cmd[0], cmd[1:]...); err != nil {
-		return err
+	env := hook.Env{RepoRoot: repoRoot, WorktreePath: targetPath, Branch: res.Branch}
+	if err := opts.Hooks.Run(ctx, hook.PreRemove, env, targetPath); err != nil {
+		return res, err
+	}
+
+	cr, err := runCommand(ctx, cmd)
+	res.Commands = append(res.Commands, cr)
+	if err != nil {
+		return res, err
+	}
+	res.Removed = true
+	log.Verbose("[verbose] worktree の削除が完了しました: %s", targetPath)
+	removeEmptyParents(ctx, targetPath, layoutRoot(ctx, repoRoot, opts.Layout))
+
+	if err := del.run(ctx, res, opts.DeleteBranch); err != nil {
+		return res, err
 	}
-	util.Verbose("[verbose] worktree の削除が完了しました: %s", targetPath)
 
+	return res, opts.Hooks.Run(ctx, hook.PostRemove, env, repoRoot)
+}
+
+// branchDeletion holds the commands deleting the local and remote branch; nil means skip
+type branchDeletion struct {
+	local  []string
+	remote []string
+}
+
+// run executes the deletions and records them in res
+func (d branchDeletion) run(ctx context.Context, res *RemoveResult, mode BranchDeletion) error {
+	// 並列に削除するとき、refs の更新がロックで衝突しないように直列化する
+	refMu.Lock()
+	defer refMu.Unlock()
+
+	if d.local != nil {
+		cr, err := runCommand(ctx, d.local)
+		res.Commands = append(res.Commands, cr)
+		if err != nil {
+			if mode == DeleteBranchSafe {
+				return fmt.Errorf("ブランチ %s を削除できませんでした（マージされていない場合は --delete-branch=force）: %w", res.Branch, err)
+			}
+			return err
+		}
+		res.BranchDeleted = true
+	}
+	if d.remote != nil {
+		cr, err := runCommand(ctx, d.remote)
+		res.Commands = append(res.Commands, cr)
+		if err != nil {
+			return err
+		}
+		res.RemoteDeleted = true
+	}
 	return nil
 }
+
+// planBranchDeletion checks that the branch of res can be deleted and
+// returns the git branch and git push --delete commands to run
+func planBranchDeletion(ctx context.Context, repoRoot string, res *RemoveResult, opts RemoveOptions) (branchDeletion, error) {
+	var del branchDeletion
+	if opts.DeleteBranch == DeleteBranchNone && !opts.DeleteRemote {
+		return del, nil
+	}
+	if res.Branch == "" {
+		util.Log(ctx).Info("detached HEAD の worktree のため、ブランチは削除しません")
+		return del, nil
+	}
+
+	if other, err := checkedOutElsewhere(ctx, repoRoot, res.Branch, res.Path); err != nil {
+		return del, err
+	} else if other != "" {
+		return del, fmt.Errorf("ブランチ %s は別の worktree でチェックアウトされています: %s", res.Branch, other)
+	}
+
+	if opts.DeleteBranch != DeleteBranchNone {
+		flag := "-d"
+		if opts.DeleteBranch == DeleteBranchForce {
+			flag = "-D"
+		}
+		del.local = []string{"git", "-C", repoRoot, "branch", flag, res.Branch}
+	}
+
+	if opts.DeleteRemote {
+		remote, name := remoteBranch(ctx, repoRoot, res.Branch)
+		if git.GitOk(ctx, repoRoot, "rev-parse", "--verify", "--quiet", "refs/remotes/"+remote+"/"+name) {
+			del.remote = []string{"git", "-C", repoRoot, "push", remote, "--delete", name}
+		} else {
+			util.Log(ctx).Info("リモートブランチ %s/%s が見つからないため、リモートの削除はスキップします", remote, name)
+		}
+	}
+	return del, nil
+}
@@ -1,8 +1,16 @@
 package worktree
 
 import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/manattan/clove/internal/git"
+	"github.com/manattan/clove/internal/hook"
 )
 
 func TestParseWorktreeList(t *testing.T) {
@@ -91,7 +99,7 @@ func TestFindPathByBranch_Integration(t *testing.T) {
 
 	// This test requires running in a git repository with worktrees
 	// We'll just test that the function doesn't panic
-	_, err := FindPathByBranch("/nonexistent", "main")
+	_, err := FindPathByBranch(context.Background(), "/nonexistent", "main")
 	if err == nil {
 		t.Error("FindPathByBranch should fail for nonexistent repo")
 	}
@@ -99,3 +107,127 @@ func TestFindPathByBranch_Integration(t *testing.T) {
 		t.Logf("FindPathByBranch error (expected): %v", err)
 	}
 }
+
+func TestParsePruned(t *testing.T) {
+	out := "Removing worktrees/gone: gitdir file points to non-existent location\n" +
+		"Removing worktrees/unknown: not a valid directory\n"
+	got := parsePruned(out, map[string]string{"gone": "/tmp/repo-gone"})
+	if want := []string{"/tmp/repo-gone", "worktrees/unknown"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePruned = %v, want %v", got, want)
+	}
+	if got := parsePruned("", nil); len(got) != 0 {
+		t.Errorf("parsePruned(empty) = %v", got)
+	}
+}
+
+func TestPrune_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	repo := initRepo(t)
+	gone := addWorktree(t, repo, "gone")
+	addWorktree(t, repo, "kept")
+	if err := os.RemoveAll(gone); err != nil {
+		t.Fatal(err)
+	}
+
+	// --verbose を指定しなくても削除した worktree のパスを返す
+	for _, dryRun := range []bool{true, false} {
+		res, err := Prune(context.Background(), repo, PruneOptions{DryRun: dryRun})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{gone}; !reflect.DeepEqual(res.Pruned, want) {
+			t.Errorf("Prune(dryRun=%v).Pruned = %v, want %v", dryRun, res.Pruned, want)
+		}
+	}
+	if res, err := Prune(context.Background(), repo, PruneOptions{}); err != nil || len(res.Pruned) != 0 {
+		t.Errorf("second Prune = %+v, %v", res, err)
+	}
+}
+
+func TestAdd_RollbackOnCancel(t *testing.T) {
+	repo := initRepo(t)
+
+	// post-add hook の実行中に中断される
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	opts := AddOptions{
+		Branch:  "feature/x",
+		BaseRef: "main",
+		NoFetch: true,
+		Hooks:   hook.Config{Commands: map[hook.Event]string{hook.PostAdd: "exec sleep 10"}},
+	}
+	res, err := Add(ctx, repo, opts)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Add = %v, want the deadline error", err)
+	}
+	if !res.RolledBack {
+		t.Error("Add should report the rollback")
+	}
+	if _, err := os.Stat(res.Path); !os.IsNotExist(err) {
+		t.Errorf("worktree %s should be removed: %v", res.Path, err)
+	}
+	if git.RefExists(context.Background(), repo, "refs/heads/feature/x") {
+		t.Error("the created branch should be deleted")
+	}
+	worktrees, err := listWorktrees(context.Background(), repo)
+	if err != nil || len(worktrees) != 1 {
+		t.Errorf("worktrees = %v, %v; want only the main one", worktrees, err)
+	}
+
+	// 既存のブランチは中断されても残す
+	if _, err := git.Git(context.Background(), repo, "branch", "keep"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	opts.Branch = "keep"
+	if res, _ := Add(ctx, repo, opts); !res.RolledBack {
+		t.Error("Add should roll back the worktree of an existing branch")
+	}
+	if !git.RefExists(context.Background(), repo, "refs/heads/keep") {
+		t.Error("an existing branch must not be deleted")
+	}
+}
+
+func TestAdd_RollbackOnHookTimeout(t *testing.T) {
+	repo := initRepo(t)
+
+	// 関係のない、prune の対象になる worktree
+	stale := addWorktree(t, repo, "stale")
+	if err := os.RemoveAll(stale); err != nil {
+		t.Fatal(err)
+	}
+
+	// 親の ctx は生きたまま、hook の timeout だけが切れる
+	opts := AddOptions{
+		Branch:  "feature/x",
+		BaseRef: "main",
+		NoFetch: true,
+		Hooks: hook.Config{
+			Commands: map[hook.Event]string{hook.PostAdd: "exec sleep 10"},
+			Timeout:  200 * time.Millisecond,
+		},
+	}
+	res, err := Add(context.Background(), repo, opts)
+	if !git.IsInterrupted(err) {
+		t.Fatalf("Add = %v, want a timeout", err)
+	}
+	if !res.RolledBack {
+		t.Error("Add should roll back after a hook timeout")
+	}
+	if _, err := os.Stat(res.Path); !os.IsNotExist(err) {
+		t.Errorf("worktree %s should be removed: %v", res.Path, err)
+	}
+	if git.RefExists(context.Background(), repo, "refs/heads/feature/x") {
+		t.Error("the created branch should be deleted")
+	}
+
+	// ロールバックは対象の worktree だけを片付ける
+	worktrees, err := listWorktrees(context.Background(), repo)
+	if err != nil || len(worktrees) != 2 || worktrees[1].Path != stale || !worktrees[1].Prunable {
+		t.Errorf("worktrees = %+v, %v; want the stale one kept", worktrees, err)
+	}
+}
@@ -5,11 +5,14 @@ import (
 	"os"
 
 	"github.com/manattan/clove/cmd"
+	"github.com/manattan/clove/internal/output"
 )
 
 func main() {
 	if err := cmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stdout, "エラー: %v\n", err)
+		if !output.IsStructured() {
+			fmt.Fprintf(os.Stdout, "エラー: %v\n", err)
+		}
 		os.Exit(1)
 	}
 }